@@ -0,0 +1,128 @@
+// cmd/standalone/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "job-worker-service/docs" // swagger docs (generated by swag)
+
+	"job-worker-service/internal/config"
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/memory"
+	"job-worker-service/internal/service"
+	httptransport "job-worker-service/internal/transport/http"
+	"job-worker-service/internal/worker"
+)
+
+// standalone: HTTP API + worker pool в одном процессе, без Postgres и Redis
+// (in-memory репозиторий и очередь). Для разработки и CI; всё состояние теряется при рестарте.
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpAddr := config.EnvOr("HTTP_ADDR", ":8080")
+	workersCount := config.EnvIntOr("WORKERS", 4)
+
+	log.Printf("[standalone] config http_addr=%s workers=%d", httpAddr, workersCount)
+
+	// DI
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(config.EnvDurationOr("LEASE_TTL", entity.DefaultLeaseTTL))
+
+	retryPolicies, err := config.RetryPolicies()
+	if err != nil {
+		log.Fatal(err)
+	}
+	timeouts, err := config.JobTimeouts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobSvc := service.NewJobService(repo, queue, nil, retryPolicies, timeouts)
+
+	cancelSvc := service.NewCancelService(repo, queue)
+	retrySvc := service.NewRetryService(repo, queue, nil, queue)
+	deadSvc := service.NewDeadLetterService(repo, queue, retrySvc)
+	eventSvc := service.NewEventService(repo, repo) // события публикует сам memory-репозиторий
+	webhookSvc := service.NewWebhookService(repo, repo, nil, service.WebhookConfig{
+		Secret:               os.Getenv("WEBHOOK_SECRET"),
+		AllowPrivateNetworks: config.EnvBoolOr("WEBHOOK_ALLOW_PRIVATE", false),
+	})
+
+	logSvc := service.NewJobLogService(repo)
+	h := httptransport.NewHandler(jobSvc, deadSvc, cancelSvc, retrySvc, eventSvc, webhookSvc, logSvc)
+	srv := &http.Server{
+		Addr:              httpAddr,
+		Handler:           httptransport.Routes(h),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Printf("standalone listening on %s", httpAddr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("http: %v", err)
+		}
+	}()
+
+	// Reaper + scheduler, как в cmd/worker
+	go runEvery(ctx, config.ReaperInterval(queue.LeaseTTL()), func() {
+		n, err := queue.RequeueStale(ctx, 100)
+		if err != nil {
+			log.Printf("requeue error: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("requeued %d jobs with expired lease", n)
+		}
+	})
+	go runEvery(ctx, 1*time.Second, func() {
+		n, err := queue.PromoteDue(ctx, 100)
+		if err != nil {
+			log.Printf("promote scheduled error: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("promoted %d scheduled jobs", n)
+		}
+	})
+
+	go runEvery(ctx, 1*time.Second, func() {
+		if _, err := webhookSvc.Dispatch(ctx); err != nil {
+			log.Printf("webhook dispatch error: %v", err)
+		}
+	})
+
+	// Handlers: свои типы job регистрируются здесь (handlers.Register / RegisterFunc)
+	handlers := worker.NewRegistry()
+	worker.RegisterBuiltins(handlers)
+
+	processor := worker.NewProcessor(repo, queue, handlers, jobSvc, nil, nil)
+	poolWorkers := worker.NewPool(queue, processor, workersCount, queue.LeaseTTL()/3, queue)
+	poolWorkers.Run(ctx) // до ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+
+	_ = srv.Shutdown(shutdownCtx)
+	log.Println("standalone stopped")
+}
+
+func runEvery(ctx context.Context, d time.Duration, fn func()) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
@@ -15,9 +15,60 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/jobs": {
-            "post": {
-                "description": "Creates job in DB (pending) and enqueues it for background processing.",
+        "/clients/{client_id}/webhook": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get default webhook of client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "client id (X-Client-ID)",
+                        "name": "client_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "must be equal to client_id",
+                        "name": "X-Client-ID",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.webhookSettingsResp"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "url receives webhooks of the client's jobs (created with X-Client-ID) that have no callback_url;\nsecret signs all webhooks of the client (X-Webhook-Signature). Replaces previous settings.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,25 +76,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "jobs"
+                    "webhooks"
                 ],
-                "summary": "Create a new job",
+                "summary": "Set default webhook of client",
                 "parameters": [
                     {
-                        "description": "job payload (priority: 0=low,1=normal,2=high)",
+                        "type": "string",
+                        "description": "client id (X-Client-ID)",
+                        "name": "client_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "must be equal to client_id",
+                        "name": "X-Client-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "webhook settings",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_transport_http.createJobDTO"
+                            "$ref": "#/definitions/internal_transport_http.webhookSettingsDTO"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_transport_http.createJobResp"
+                            "$ref": "#/definitions/internal_transport_http.webhookSettingsResp"
                         }
                     },
                     "400": {
@@ -52,6 +117,57 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_transport_http.apiError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Remove default webhook of client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "client id (X-Client-ID)",
+                        "name": "client_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "must be equal to client_id",
+                        "name": "X-Client-ID",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -61,29 +177,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/jobs/{id}": {
+        "/dead-letters": {
             "get": {
+                "description": "Jobs that exhausted retries or could not be processed at all (newest first).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "jobs"
+                    "dead-letters"
                 ],
-                "summary": "Get job by id",
+                "summary": "List dead-lettered jobs",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "job id (uuid)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "offset (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_transport_http.jobResp"
+                            "$ref": "#/definitions/internal_transport_http.deadLetterListResp"
                         }
                     },
                     "400": {
@@ -92,8 +214,31 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_transport_http.apiError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dead-letters"
+                ],
+                "summary": "Purge dead-letter queue",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.purgeResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/internal_transport_http.apiError"
                         }
@@ -101,19 +246,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/jobs/{id}/result": {
+        "/dead-letters/{id}": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "jobs"
+                    "dead-letters"
                 ],
-                "summary": "Get job result",
+                "summary": "Inspect dead-lettered job",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "job id (uuid)",
+                        "description": "job id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -123,15 +268,40 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/internal_transport_http.deadLetterResp"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/internal_transport_http.apiError"
                         }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "dead-letters"
+                ],
+                "summary": "Remove job from dead-letter queue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
                     },
                     "404": {
                         "description": "Not Found",
@@ -139,58 +309,960 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_transport_http.apiError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/internal_transport_http.apiError"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "internal_transport_http.apiError": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                }
-            }
         },
-        "internal_transport_http.createJobDTO": {
-            "type": "object",
-            "properties": {
-                "input": {
-                    "type": "object",
-                    "additionalProperties": true
-                },
-                "priority": {
-                    "description": "0=low,1=normal,2=high (nil =\u003e default 1)",
-                    "type": "integer"
-                },
-                "type": {
-                    "type": "string"
+        "/dead-letters/{id}/requeue": {
+            "post": {
+                "description": "Resets job (pending, attempts=0) and enqueues it with its original priority.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dead-letters"
+                ],
+                "summary": "Requeue dead-lettered job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.createJobResp"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
                 }
             }
         },
-        "internal_transport_http.createJobResp": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "string"
+        "/events": {
+            "get": {
+                "description": "text/event-stream of status transitions and progress of all jobs, optionally filtered by job type.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Stream events of all jobs (SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job types, e.g. generate_report,convert_video",
+                        "name": "type",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/job-worker-service_internal_entity.JobEvent"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
                 }
             }
         },
-        "internal_transport_http.jobResp": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "error": {
+        "/jobs": {
+            "get": {
+                "description": "Jobs matching all given filters, ordered by (created_at, id); newest first by default.\nstatus, type and tag accept several values (repeated or comma-separated); a job must have all given tags.\nPass next_cursor from the previous page as cursor to get the next one (keep the same filters and order).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "List jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "status filter, e.g. error,dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "job type filter",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "priority (0=low,1=normal,2=high)",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at \u003e= (RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at \u003c (RFC3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "tag filter",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "follow-up jobs of this job (uuid)",
+                        "name": "parent_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "desc (default) or asc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.jobListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates job in DB (pending) and enqueues it for background processing.\nWith run_at (RFC3339) or delay_seconds the job waits in the scheduled set until due.\ntimeout_seconds limits one attempt (default: timeout for the job type); timed-out attempts get error_class=timeout.\nWith Idempotency-Key a repeated request (same key and X-Client-ID, same body) returns the id of the job\ncreated by the first one instead of creating a duplicate; the same key with a different body gets 422.\nWith unique=true (or unique_key) the id of a pending/processing job of the same type with the same input\n(or unique_key) is returned instead of creating another one.\nWith callback_url (or a default webhook of X-Client-ID) a signed webhook is POSTed when the job is done, error or dead.\non_success / on_failure create a follow-up job when this one is done / error or dead;\n{\"$ref\": \"/output/url\"} in its input is replaced with the value from the parent (JSON pointer into id, type, input, output, error).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Create a new job",
+                "parameters": [
+                    {
+                        "description": "job payload (priority: 0=low,1=normal,2=high; retry overrides type policy)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.createJobDTO"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "idempotency key (max 255 chars)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "client scope for Idempotency-Key",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.createJobResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "progress — the last progress reported by the job handler (percent, stage, message),\nsaved at most once per second while the job is processing and reset when a new attempt starts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get job by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.jobResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/cancel": {
+            "post": {
+                "description": "Pending job is removed from the queue, processing job gets its handler context cancelled\non the worker that holds it. In both cases status becomes canceled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Cancel job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.jobResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/clone": {
+            "post": {
+                "description": "Creates a new job with the same type, retry policy, timeout and tags as the source job (any status).\ninput from the body is a JSON merge patch over the source input: keys are replaced, null removes a key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Clone job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "source job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "input patch and priority override",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.cloneJobDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.createJobResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/events": {
+            "get": {
+                "description": "text/event-stream. The first event is the current job state, then status transitions\n(event: status) and handler progress (event: progress). Status event done / error / dead carries\noutput / error; the stream ends after a final status (done, error, dead, canceled).",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Stream job events (SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/job-worker-service_internal_entity.JobEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/history": {
+            "get": {
+                "description": "Every status transition and every new attempt of the job, oldest first, with who made it:\nAPI client, worker, reaper (requeue after an expired lease) or reconciler.\nTwo processing entries in a row mean the attempt was started again without finishing the previous one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get status history of job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.jobHistoryResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/logs": {
+            "get": {
+                "description": "Lines written by the job handler (Reporter.Logf / Warnf / Errorf) and errors of failed attempts,\noldest first. Lines are saved in batches about once per second while the job is processing.\nlevel accepts several values (repeated or comma-separated).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "List execution log of job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "only lines of this attempt",
+                        "name": "attempt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "level filter, e.g. warn,error",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.jobLogListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/result": {
+            "get": {
+                "description": "With wait (e.g. 30s or 30, max 60s; more is 400) the request blocks until the job reaches a final status\n(done, error, dead, canceled) or the wait expires; 409 if the job is still not done.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get job result",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "long-poll timeout: duration (30s) or seconds (30), max 60s",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/retry": {
+            "post": {
+                "description": "Resets error / dead / canceled job (pending, attempts=0, no error and output) and enqueues it\nwith its original priority or with priority from the body. Dead job is removed from the dead-letter queue.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Retry finished job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "priority override",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.retryJobDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.jobResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/webhooks": {
+            "get": {
+                "description": "Every transition of the job to done, error or dead creates a delivery to its callback_url\n(or the default webhook of its client). Failed attempts are retried with backoff.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries of job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id (uuid)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.webhookDeliveryListResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_transport_http.apiError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "internal_transport_http.apiError": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.cloneJobDTO": {
+            "type": "object",
+            "properties": {
+                "input": {
+                    "description": "merge patch поверх input исходной job: ключи заменяются, null удаляет ключ",
+                    "type": "object"
+                },
+                "priority": {
+                    "description": "nil =\u003e как у исходной job",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_transport_http.createJobDTO": {
+            "type": "object",
+            "properties": {
+                "callback_url": {
+                    "description": "webhook о завершении (done/error/dead); пусто =\u003e URL из настроек клиента (X-Client-ID)",
+                    "type": "string"
+                },
+                "delay_seconds": {
+                    "type": "integer"
+                },
+                "input": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "on_failure": {
+                    "$ref": "#/definitions/internal_transport_http.jobTemplateDTO"
+                },
+                "on_success": {
+                    "description": "follow-up job: после done / после error или dead",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_transport_http.jobTemplateDTO"
+                        }
+                    ]
+                },
+                "priority": {
+                    "description": "0=low,1=normal,2=high (nil =\u003e default 1)",
+                    "type": "integer"
+                },
+                "retry": {
+                    "description": "nil =\u003e политика для типа job",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_transport_http.retryDTO"
+                        }
+                    ]
+                },
+                "run_at": {
+                    "description": "отложенный запуск: либо абсолютное время (RFC3339), либо задержка в секундах",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "метки для GET /jobs?tag=...",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_seconds": {
+                    "description": "timeout одной попытки (nil =\u003e timeout для типа job)",
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "unique": {
+                    "description": "пока такая же job (type + input или type + unique_key) pending/processing — вернуть её id",
+                    "type": "boolean"
+                },
+                "unique_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.createJobResp": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.deadLetterListResp": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_transport_http.deadLetterResp"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_transport_http.deadLetterResp": {
+            "type": "object",
+            "properties": {
+                "dead_at": {
+                    "type": "string"
+                },
+                "job": {
+                    "description": "nil, если id не UUID или job нет в БД",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_transport_http.jobResp"
+                        }
+                    ]
+                },
+                "job_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.jobHistoryResp": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_transport_http.jobTransitionResp"
+                    }
+                }
+            }
+        },
+        "internal_transport_http.jobListResp": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_transport_http.jobResp"
+                    }
+                },
+                "next_cursor": {
+                    "description": "пусто на последней странице",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.jobLogListResp": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_transport_http.jobLogResp"
+                    }
+                },
+                "next_cursor": {
+                    "description": "пусто на последней странице",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.jobLogResp": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "attempt": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "level": {
+                    "description": "info | warn | error",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/job-worker-service_internal_entity.LogLevel"
+                        }
+                    ]
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.jobResp": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "callback_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
                     "type": "string"
                 },
+                "error_class": {
+                    "description": "error | timeout",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/job-worker-service_internal_entity.ErrorClass"
+                        }
+                    ]
+                },
                 "id": {
                     "type": "string"
                 },
@@ -198,37 +1270,418 @@ const docTemplate = `{
                     "type": "object",
                     "additionalProperties": true
                 },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "on_failure": {
+                    "$ref": "#/definitions/internal_transport_http.jobTemplateDTO"
+                },
+                "on_success": {
+                    "$ref": "#/definitions/internal_transport_http.jobTemplateDTO"
+                },
                 "output": {
                     "type": "object",
                     "additionalProperties": true
                 },
+                "parent_id": {
+                    "description": "job, после которой создана эта (on_success / on_failure)",
+                    "type": "string"
+                },
                 "priority": {
                     "type": "integer"
                 },
+                "progress": {
+                    "description": "последний прогресс handler'а",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_transport_http.progressResp"
+                        }
+                    ]
+                },
+                "run_at": {
+                    "description": "когда job будет (снова) запущена",
+                    "type": "string"
+                },
                 "status": {
                     "$ref": "#/definitions/job-worker-service_internal_entity.JobStatus"
                 },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_seconds": {
+                    "description": "timeout одной попытки (0 — без ограничения)",
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "unique_key": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.jobTemplateDTO": {
+            "type": "object",
+            "properties": {
+                "callback_url": {
+                    "type": "string"
+                },
+                "delay_seconds": {
+                    "description": "после завершения родителя",
+                    "type": "integer"
+                },
+                "input": {
+                    "type": "object"
+                },
+                "max_attempts": {
+                    "description": "0 =\u003e политика для типа",
+                    "type": "integer"
+                },
+                "on_failure": {
+                    "$ref": "#/definitions/internal_transport_http.jobTemplateDTO"
+                },
+                "on_success": {
+                    "$ref": "#/definitions/internal_transport_http.jobTemplateDTO"
+                },
+                "priority": {
+                    "description": "nil =\u003e priority родителя",
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_seconds": {
+                    "description": "0 =\u003e timeout для типа",
+                    "type": "integer"
+                },
                 "type": {
                     "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.jobTransitionResp": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "api:\u003cclient_id\u003e | worker:\u003chost\u003e-\u003cpid\u003e | reaper | reconciler",
+                    "type": "string"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "attempt": {
+                    "type": "integer"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "from": {
+                    "description": "нет — job создана",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/job-worker-service_internal_entity.JobStatus"
+                        }
+                    ]
+                },
+                "to": {
+                    "$ref": "#/definitions/job-worker-service_internal_entity.JobStatus"
+                }
+            }
+        },
+        "internal_transport_http.progressResp": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "integer"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.purgeResp": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_transport_http.retryDTO": {
+            "type": "object",
+            "properties": {
+                "base_delay_ms": {
+                    "type": "integer"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "max_delay_ms": {
+                    "type": "integer"
+                },
+                "timeout_max_attempts": {
+                    "description": "лимит попыток, если последняя упала по timeout (0 =\u003e max_attempts)",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_transport_http.retryJobDTO": {
+            "type": "object",
+            "properties": {
+                "priority": {
+                    "description": "nil =\u003e исходный priority",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_transport_http.webhookAttemptResp": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "attempt": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "нет — ответа не было (сеть, timeout)",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_transport_http.webhookDeliveryListResp": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_transport_http.webhookDeliveryResp"
+                    }
+                }
+            }
+        },
+        "internal_transport_http.webhookDeliveryResp": {
+            "type": "object",
+            "properties": {
+                "attempt_log": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_transport_http.webhookAttemptResp"
+                    }
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "description": "job.done | job.error | job.dead",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "description": "только pending",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending | delivered | failed",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/job-worker-service_internal_entity.WebhookStatus"
+                        }
+                    ]
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.webhookSettingsDTO": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "description": "секрет подписи; пусто =\u003e общий секрет сервиса",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "webhook для job клиента без callback_url",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_transport_http.webhookSettingsResp": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "string"
+                },
+                "has_secret": {
+                    "description": "сам секрет не возвращается",
+                    "type": "boolean"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "job-worker-service_internal_entity.ErrorClass": {
+            "type": "string",
+            "enum": [
+                "error",
+                "timeout"
+            ],
+            "x-enum-comments": {
+                "ErrorClassError": "handler вернул ошибку",
+                "ErrorClassTimeout": "попытка не уложилась в timeout job"
+            },
+            "x-enum-varnames": [
+                "ErrorClassError",
+                "ErrorClassTimeout"
+            ]
+        },
+        "job-worker-service_internal_entity.JobEvent": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "job_id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/job-worker-service_internal_entity.JobEventKind"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "output": {
+                    "description": "результат — только в status-событии done / error / dead",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "progress": {
+                    "description": "0..100: progress-событие и снимок processing job",
+                    "type": "integer"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/job-worker-service_internal_entity.JobStatus"
+                },
+                "type": {
+                    "type": "string"
                 }
             }
         },
+        "job-worker-service_internal_entity.JobEventKind": {
+            "type": "string",
+            "enum": [
+                "status",
+                "progress"
+            ],
+            "x-enum-comments": {
+                "EventProgress": "handler сообщил прогресс (Reporter.Progress)",
+                "EventStatus": "job перешла в Status"
+            },
+            "x-enum-varnames": [
+                "EventStatus",
+                "EventProgress"
+            ]
+        },
         "job-worker-service_internal_entity.JobStatus": {
             "type": "string",
             "enum": [
                 "pending",
                 "processing",
                 "done",
-                "error"
+                "error",
+                "dead",
+                "canceled"
             ],
+            "x-enum-comments": {
+                "StatusCanceled": "отменена через API (POST /jobs/{id}/cancel)",
+                "StatusDead": "попытки исчерпаны, job лежит в dead-letter очереди"
+            },
             "x-enum-varnames": [
                 "StatusPending",
                 "StatusProcessing",
                 "StatusDone",
-                "StatusError"
+                "StatusError",
+                "StatusDead",
+                "StatusCanceled"
+            ]
+        },
+        "job-worker-service_internal_entity.LogLevel": {
+            "type": "string",
+            "enum": [
+                "info",
+                "warn",
+                "error"
+            ],
+            "x-enum-varnames": [
+                "LogInfo",
+                "LogWarn",
+                "LogError"
+            ]
+        },
+        "job-worker-service_internal_entity.WebhookStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "delivered",
+                "failed"
+            ],
+            "x-enum-comments": {
+                "WebhookDelivered": "получатель ответил 2xx",
+                "WebhookFailed": "попытки исчерпаны",
+                "WebhookPending": "ждёт первой или следующей попытки"
+            },
+            "x-enum-varnames": [
+                "WebhookPending",
+                "WebhookDelivered",
+                "WebhookFailed"
             ]
         }
     }
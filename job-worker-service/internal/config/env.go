@@ -0,0 +1,79 @@
+// Package config — общая конфигурация cmd/app, cmd/worker и cmd/standalone из env.
+package config
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MustEnv returns env var key; если она пустая — процесс завершается.
+func MustEnv(key string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		log.Fatalf("missing env: %s", key)
+	}
+	return v
+}
+
+func EnvOr(key, def string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func EnvIntOr(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+func EnvBoolOr(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func EnvDurationOr(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// ReaperInterval: проверяем lease'ы в 2 раза чаще TTL, но не реже раза в 30s.
+func ReaperInterval(leaseTTL time.Duration) time.Duration {
+	d := leaseTTL / 2
+	if d <= 0 || d > 30*time.Second {
+		d = 30 * time.Second
+	}
+	return d
+}
+
+var dsnPassword = regexp.MustCompile(`://([^:/?#]+):([^@/]+)@`)
+
+// RedactDSN маскирует пароль в DSN для логов: user:pass@ -> user:****@ (DSN без пароля не меняется).
+func RedactDSN(dsn string) string {
+	return dsnPassword.ReplaceAllString(dsn, `://$1:****@`)
+}
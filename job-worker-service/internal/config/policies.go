@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+// RetryPolicies reads RETRY_* env: политика по умолчанию и RETRY_POLICIES="convert_video:5:2s:5m,echo:1".
+func RetryPolicies() (service.RetryPolicies, error) {
+	p := service.RetryPolicies{
+		Default: entity.RetryPolicy{
+			MaxAttempts: EnvIntOr("RETRY_MAX_ATTEMPTS", service.DefaultRetryPolicy.MaxAttempts),
+			BaseDelay:   EnvDurationOr("RETRY_BASE_DELAY", service.DefaultRetryPolicy.BaseDelay),
+			MaxDelay:    EnvDurationOr("RETRY_MAX_DELAY", service.DefaultRetryPolicy.MaxDelay),
+			// 0 = на timeout действует тот же max_attempts
+			TimeoutMaxAttempts: EnvIntOr("RETRY_TIMEOUT_MAX_ATTEMPTS", 0),
+		},
+	}
+	var err error
+	if p.ByType, err = service.ParseRetryPolicies(os.Getenv("RETRY_POLICIES")); err != nil {
+		return service.RetryPolicies{}, fmt.Errorf("retry policies: %w", err)
+	}
+	return p, nil
+}
+
+// JobTimeouts reads JOB_TIMEOUT и JOB_TIMEOUTS="convert_video:10m,echo:5s".
+func JobTimeouts() (service.TimeoutPolicies, error) {
+	t := service.TimeoutPolicies{Default: EnvDurationOr("JOB_TIMEOUT", service.DefaultJobTimeout)}
+	var err error
+	if t.ByType, err = service.ParseTimeouts(os.Getenv("JOB_TIMEOUTS")); err != nil {
+		return service.TimeoutPolicies{}, fmt.Errorf("job timeouts: %w", err)
+	}
+	return t, nil
+}
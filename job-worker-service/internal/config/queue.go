@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/postgresql"
+	"job-worker-service/internal/service"
+)
+
+// NewQueue выбирает реализацию очереди по QUEUE_BACKEND:
+//   - redis (default): lists + processing lists + lease ZSET
+//   - redis-streams: streams + consumer group (PEL, XCLAIM)
+//   - postgres: сама таблица jobs (FOR UPDATE SKIP LOCKED), Redis не нужен
+func NewQueue(ctx context.Context, pool *pgxpool.Pool, redisAddr string) (service.QueueBackend, error) {
+	baseScheduledKey := EnvOr("REDIS_SCHEDULED_KEY", "jobs:scheduled")
+	deadKey := EnvOr("REDIS_DEAD_KEY", "jobs:dead")
+	cancelKey := EnvOr("REDIS_CANCEL_KEY", "jobs:cancel")
+	leaseTTL := EnvDurationOr("LEASE_TTL", entity.DefaultLeaseTTL)
+
+	switch backend := EnvOr("QUEUE_BACKEND", "redis"); backend {
+	case "redis":
+		rdb, err := NewRedis(ctx, redisAddr)
+		if err != nil {
+			return nil, err
+		}
+		baseQueueKey := EnvOr("REDIS_QUEUE_KEY", "jobs:queue")
+		baseProcessingKey := EnvOr("REDIS_PROCESSING_KEY", "jobs:processing")
+
+		return service.NewRedisPriorityQueue(rdb, service.RedisQueueConfig{
+			ProcessingMapKey: EnvOr("REDIS_PROCESSING_MAP_KEY", baseProcessingKey+":map"),
+			DeadKey:          deadKey,
+			CancelKey:        cancelKey,
+			LeaseKey:         EnvOr("REDIS_LEASE_KEY", baseProcessingKey+":leases"),
+			LeaseTTL:         leaseTTL,
+			Low:              service.Lane{QueueKey: baseQueueKey + ":low", ProcessingKey: baseProcessingKey + ":low", ScheduledKey: baseScheduledKey + ":low"},
+			Normal:           service.Lane{QueueKey: baseQueueKey + ":normal", ProcessingKey: baseProcessingKey + ":normal", ScheduledKey: baseScheduledKey + ":normal"},
+			High:             service.Lane{QueueKey: baseQueueKey + ":high", ProcessingKey: baseProcessingKey + ":high", ScheduledKey: baseScheduledKey + ":high"},
+		}), nil
+
+	case "redis-streams":
+		rdb, err := NewRedis(ctx, redisAddr)
+		if err != nil {
+			return nil, err
+		}
+		baseStreamKey := EnvOr("REDIS_STREAM_KEY", "jobs:stream")
+		hostname, _ := os.Hostname()
+
+		return service.NewRedisStreamQueue(rdb, service.RedisStreamQueueConfig{
+			Group:     EnvOr("STREAM_GROUP", "workers"),
+			Consumer:  EnvOr("STREAM_CONSUMER", fmt.Sprintf("%s-%d", hostname, os.Getpid())),
+			DeadKey:   deadKey,
+			CancelKey: cancelKey,
+			LeaseTTL:  leaseTTL,
+			Low:       service.StreamLane{StreamKey: baseStreamKey + ":low", ScheduledKey: baseScheduledKey + ":low"},
+			Normal:    service.StreamLane{StreamKey: baseStreamKey + ":normal", ScheduledKey: baseScheduledKey + ":normal"},
+			High:      service.StreamLane{StreamKey: baseStreamKey + ":high", ScheduledKey: baseScheduledKey + ":high"},
+		}), nil
+
+	case "postgres":
+		return postgresql.NewQueue(pool, postgresql.QueueConfig{LeaseTTL: leaseTTL}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown QUEUE_BACKEND: %s", backend)
+	}
+}
+
+// NewRedis connects to Redis at addr (REDIS_ADDR) and checks the connection.
+func NewRedis(ctx context.Context, addr string) (*redis.Client, error) {
+	if addr == "" {
+		return nil, errors.New("missing env: REDIS_ADDR")
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return rdb, nil
+}
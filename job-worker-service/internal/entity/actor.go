@@ -0,0 +1,24 @@
+package entity
+
+import "context"
+
+// Actor — кто меняет job; репозиторий пишет его в историю переходов (GET /jobs/{id}/history).
+// API: "api" или "api:<X-Client-ID>", worker: "worker:<host>-<pid>".
+const (
+	ActorAPI        = "api"
+	ActorReaper     = "reaper" // RequeueStale: lease worker'а истёк
+	ActorReconciler = "reconciler"
+)
+
+type actorKey struct{}
+
+// WithActor returns ctx in which job changes are attributed to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns actor set by WithActor ("" if none).
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
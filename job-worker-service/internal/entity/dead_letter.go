@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// DeadLetter — запись в dead-letter очереди: job, которую больше не пытаются выполнить.
+// JobID может быть не UUID (poison message из очереди).
+type DeadLetter struct {
+	JobID  string    `json:"job_id"`
+	Reason string    `json:"reason"`
+	DeadAt time.Time `json:"dead_at"`
+}
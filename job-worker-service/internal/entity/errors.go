@@ -0,0 +1,28 @@
+package entity
+
+import "errors"
+
+// Ошибки, которые возвращают репозитории и очереди (postgresql, memory, Redis);
+// service и worker проверяют их через errors.Is, не завися от конкретного backend'а.
+var (
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidTransition — переход запрещён state machine'ой (jobTransitions, AllowedSources):
+	// например, повторная доставка уже done job пытается вернуть её в processing.
+	ErrInvalidTransition = errors.New("invalid job status transition")
+	// ErrStaleAttempt — запись от устаревшей попытки: job уже взята заново (fencing token не совпал).
+	ErrStaleAttempt = errors.New("stale job attempt")
+
+	// ErrLeaseLost — lease job'а истёк и её уже забрал reaper (или job уже ACK'нута).
+	ErrLeaseLost = errors.New("lease lost")
+	// ErrDeadLetterNotFound — job нет в dead-letter очереди.
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+	// ErrIdempotencyKeyExists — job с таким (client_id, Idempotency-Key) уже есть (Create).
+	ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+	// ErrUniqueJobExists — уже есть pending/processing job того же type с тем же unique key (Create и ResetToPending).
+	ErrUniqueJobExists = errors.New("equivalent job is already pending or processing")
+
+	// ErrWebhookSettingsNotFound — у клиента нет webhook-настроек.
+	ErrWebhookSettingsNotFound = errors.New("webhook settings not found")
+)
@@ -14,6 +14,16 @@ const (
 	StatusProcessing JobStatus = "processing"
 	StatusDone       JobStatus = "done"
 	StatusError      JobStatus = "error"
+	StatusDead       JobStatus = "dead"     // попытки исчерпаны, job лежит в dead-letter очереди
+	StatusCanceled   JobStatus = "canceled" // отменена через API (POST /jobs/{id}/cancel)
+)
+
+// ErrorClass — класс ошибки последней неудачной попытки; retry-политика может различать классы.
+type ErrorClass string
+
+const (
+	ErrorClassError   ErrorClass = "error"   // handler вернул ошибку
+	ErrorClassTimeout ErrorClass = "timeout" // попытка не уложилась в timeout job
 )
 
 type Job struct {
@@ -26,4 +36,69 @@ type Job struct {
 	CreatedAt time.Time       `json:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at"`
 	Priority  int             `json:"priority" db:"priority"`
+
+	// Attempts — сколько раз worker уже брал job в работу.
+	Attempts int         `json:"attempts" db:"attempts"`
+	Retry    RetryPolicy `json:"-"`
+
+	// Tags — метки для поиска (GET /jobs?tag=...).
+	Tags []string `json:"tags,omitempty"`
+
+	// ErrorClass — класс ошибки в Error ("" если ошибки нет).
+	ErrorClass ErrorClass `json:"error_class,omitempty"`
+
+	// Timeout — сколько может длиться одна попытка (0 — без ограничения).
+	Timeout time.Duration `json:"-"`
+
+	// RunAt — когда job должна быть запущена (run_at/delay при создании или время следующего ретрая).
+	RunAt *time.Time `json:"run_at,omitempty" db:"run_at"`
+
+	// Idempotency — ключ, с которым job создана (nil — без Idempotency-Key).
+	Idempotency *Idempotency `json:"-"`
+
+	// UniqueKey — пока job pending/processing, другой job того же Type с тем же ключом не будет ("" — без дедупликации).
+	UniqueKey string `json:"unique_key,omitempty"`
+
+	// ClientID — X-Client-ID создателя ("" — общий scope): scope Idempotency-Key и webhook-настроек клиента.
+	ClientID string `json:"client_id,omitempty"`
+	// CallbackURL — куда слать webhook о завершении ("" — URL из настроек клиента, если есть).
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// Progress — последний сохранённый прогресс текущей (или последней) попытки; nil — handler не сообщал.
+	Progress *JobProgress `json:"progress,omitempty"`
+
+	// FenceToken растёт с каждым StartAttempt и никогда не сбрасывается: запись результата
+	// с устаревшим token'ом (worker, чью попытку уже взяли заново) отклоняется.
+	FenceToken int64 `json:"-"`
+
+	// OnSuccess / OnFailure — follow-up job после done / после error или dead (nil — нет).
+	OnSuccess *JobTemplate `json:"-"`
+	OnFailure *JobTemplate `json:"-"`
+	// ParentID — job, по завершении которой создана эта (nil — создана через API).
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+}
+
+// ClampPriority приводит priority к lane очереди: меньше 0 — low (0), больше 2 — high (2).
+func ClampPriority(p int) int {
+	if p < 0 {
+		return 0
+	}
+	if p > 2 {
+		return 2
+	}
+	return p
+}
+
+// JobProgress — прогресс, о котором сообщил handler (Reporter.Progress / Reporter.Stage).
+type JobProgress struct {
+	Percent   int       `json:"percent"`
+	Stage     string    `json:"stage,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Idempotency — Idempotency-Key запроса на создание job в рамках клиента (Job.ClientID).
+type Idempotency struct {
+	Key         string
+	RequestHash string // hash тела запроса: тот же ключ с другим телом — ошибка
 }
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobEventKind string
+
+const (
+	EventStatus   JobEventKind = "status"   // job перешла в Status
+	EventProgress JobEventKind = "progress" // handler сообщил прогресс (Reporter.Progress)
+)
+
+// JobEvent — изменение job для подписчиков (GET /jobs/{id}/events, GET /events).
+type JobEvent struct {
+	Kind     JobEventKind `json:"kind"`
+	JobID    uuid.UUID    `json:"job_id"`
+	Type     string       `json:"type"`
+	Status   JobStatus    `json:"status"`
+	Attempts int          `json:"attempts,omitempty"`
+
+	Progress *int   `json:"progress,omitempty"` // 0..100: progress-событие и снимок processing job
+	Stage    string `json:"stage,omitempty"`
+	Message  string `json:"message,omitempty"`
+
+	// результат — только в status-событии done / error / dead
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  *string         `json:"error,omitempty"`
+
+	At time.Time `json:"at"`
+}
+
+// Final reports whether job will not change anymore (без ручного retry).
+func (s JobStatus) Final() bool {
+	return s == StatusDone || s == StatusError || s == StatusDead || s == StatusCanceled
+}
@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SortOrder — порядок списка job по (created_at, id).
+type SortOrder string
+
+const (
+	SortDesc SortOrder = "desc" // новые первыми (default)
+	SortAsc  SortOrder = "asc"
+)
+
+// JobFilter — фильтр и страница для списка job; нулевые поля не фильтруют.
+type JobFilter struct {
+	Statuses    []JobStatus
+	Types       []string
+	Priority    *int
+	CreatedFrom *time.Time // включительно
+	CreatedTo   *time.Time // не включительно
+	Tags        []string   // job должна иметь все теги
+	ParentID    *uuid.UUID // follow-up job этого родителя
+
+	Order SortOrder
+	After *JobCursor // keyset: только job "после" курсора в порядке Order
+	Limit int
+}
+
+// JobCursor — позиция в списке job: (created_at, id) последней job страницы.
+type JobCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// StaleCursor — позиция в ListStale: (updated_at, id) последней job предыдущей страницы.
+type StaleCursor struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// String encodes cursor as opaque url-safe token.
+func (c JobCursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "_" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseJobCursor(s string) (*JobCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &JobCursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: uid}, nil
+}
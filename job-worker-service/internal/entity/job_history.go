@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobTransition — запись истории job: смена статуса или новая попытка, и кто её сделал.
+type JobTransition struct {
+	ID      int64
+	JobID   uuid.UUID
+	From    JobStatus // "" — job создана
+	To      JobStatus
+	Attempt int    // attempts job после перехода
+	Actor   string // "api:<client_id>", "worker:<host>-<pid>", "reaper", "reconciler"; "" — неизвестно
+	Detail  string // текст ошибки, причина
+	At      time.Time
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogLevel — уровень строки лога job.
+type LogLevel string
+
+const (
+	LogInfo  LogLevel = "info"
+	LogWarn  LogLevel = "warn"
+	LogError LogLevel = "error"
+)
+
+// JobLog — строка лога, которую handler написал через Reporter (или Processor — об ошибке попытки).
+type JobLog struct {
+	ID      int64 // растёт в порядке записи; курсор страницы
+	JobID   uuid.UUID
+	Attempt int
+	Level   LogLevel
+	Message string
+	At      time.Time
+}
+
+// JobLogFilter — фильтр и страница лога job (по возрастанию ID); нулевые поля не фильтруют.
+type JobLogFilter struct {
+	Attempt int
+	Levels  []LogLevel
+
+	After int64 // keyset: только строки с ID > After
+	Limit int
+}
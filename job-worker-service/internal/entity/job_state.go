@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+)
+
+// jobTransitions — state machine job: из какого статуса в какие можно перейти.
+// Единственный источник правды: условия на статус в репозиториях строятся из неё (AllowedSources).
+// processing -> processing — новая попытка после истёкшего lease; done — терминальный.
+var jobTransitions = map[JobStatus][]JobStatus{
+	StatusPending:    {StatusProcessing, StatusError, StatusCanceled},
+	StatusProcessing: {StatusProcessing, StatusPending, StatusDone, StatusError, StatusDead, StatusCanceled},
+	StatusError:      {StatusPending},
+	StatusDead:       {StatusPending},
+	StatusCanceled:   {StatusPending},
+}
+
+// CanTransition reports whether job may move from status from to status to.
+func CanTransition(from, to JobStatus) bool {
+	return slices.Contains(jobTransitions[from], to)
+}
+
+// TransitionSources returns statuses from which job may move to status to.
+func TransitionSources(to JobStatus) []JobStatus {
+	var out []JobStatus
+	for _, from := range []JobStatus{StatusPending, StatusProcessing, StatusDone, StatusError, StatusDead, StatusCanceled} {
+		if CanTransition(from, to) {
+			out = append(out, from)
+		}
+	}
+	return out
+}
+
+// AllowedSources returns statuses from which an operation moves job to status to:
+// from, если он задан (операции вроде SetRetry допускают только часть переходов), иначе TransitionSources(to).
+// Переход из from, которого нет в jobTransitions, — ошибка в коде репозитория: panic при инициализации.
+func AllowedSources(to JobStatus, from ...JobStatus) []JobStatus {
+	if len(from) == 0 {
+		return TransitionSources(to)
+	}
+	for _, f := range from {
+		if !CanTransition(f, to) {
+			panic(fmt.Sprintf("job transition %s -> %s is not in the state machine", f, to))
+		}
+	}
+	return from
+}
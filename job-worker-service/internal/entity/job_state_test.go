@@ -0,0 +1,24 @@
+package entity_test
+
+import (
+	"slices"
+	"testing"
+
+	"job-worker-service/internal/entity"
+)
+
+func TestAllowedSources(t *testing.T) {
+	if got := entity.AllowedSources(entity.StatusDone); !slices.Equal(got, []entity.JobStatus{entity.StatusProcessing}) {
+		t.Fatalf("expected done only from processing, got %v", got)
+	}
+	if got := entity.AllowedSources(entity.StatusPending, entity.StatusError, entity.StatusDead); len(got) != 2 {
+		t.Fatalf("expected requested sources, got %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for transition missing from the state machine")
+		}
+	}()
+	entity.AllowedSources(entity.StatusProcessing, entity.StatusDone)
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobTemplate — follow-up job, которую worker создаёт, когда родитель завершился (Job.OnSuccess / Job.OnFailure).
+// В Input объект {"$ref": "<JSON pointer>"} заменяется значением из родителя:
+// "/output/...", "/input/...", "/error", "/id", "/type".
+type JobTemplate struct {
+	Type     string          `json:"type"`
+	Priority *int            `json:"priority,omitempty"` // nil — priority родителя
+	Input    json.RawMessage `json:"input,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+
+	// MaxAttempts, Timeout: 0 — политика для типа.
+	MaxAttempts int           `json:"max_attempts,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+	// Delay — запуск не раньше, чем через Delay после завершения родителя.
+	Delay time.Duration `json:"delay,omitempty"`
+
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// следующие звенья цепочки
+	OnSuccess *JobTemplate `json:"on_success,omitempty"`
+	OnFailure *JobTemplate `json:"on_failure,omitempty"`
+}
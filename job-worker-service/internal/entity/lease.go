@@ -0,0 +1,6 @@
+package entity
+
+import "time"
+
+// DefaultLeaseTTL — visibility timeout по умолчанию: сколько job может быть "в работе" без heartbeat.
+const DefaultLeaseTTL = 60 * time.Second
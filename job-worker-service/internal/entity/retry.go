@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy описывает, сколько раз и с какой задержкой повторять job.
+// Задержка растёт экспоненциально: BaseDelay * 2^(attempt-1), но не больше MaxDelay,
+// плюс jitter (половина задержки случайная), чтобы ретраи не шли "пачкой".
+// TimeoutMaxAttempts (если > 0) заменяет MaxAttempts, когда последняя попытка упала по timeout:
+// например, 1 — не повторять job, которая не уложилась во время.
+type RetryPolicy struct {
+	MaxAttempts        int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	TimeoutMaxAttempts int
+}
+
+// AttemptsFor returns attempts limit when the last attempt failed with given error class.
+func (p RetryPolicy) AttemptsFor(class ErrorClass) int {
+	if class == ErrorClassTimeout && p.TimeoutMaxAttempts > 0 {
+		return p.TimeoutMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns delay before the next attempt after `attempt` failed attempts (attempt >= 1).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < attempt && i < 32; i++ {
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	// equal jitter: [d/2, d)
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
@@ -0,0 +1,22 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"job-worker-service/internal/entity"
+)
+
+func TestRetryPolicy_BackoffIsCapped(t *testing.T) {
+	p := entity.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := p.Backoff(attempt)
+		if d > p.MaxDelay {
+			t.Fatalf("attempt %d: backoff %s exceeds max %s", attempt, d, p.MaxDelay)
+		}
+	}
+	if d := p.Backoff(4); d < 4*time.Second {
+		t.Fatalf("attempt 4: expected at least 4s (half of 8s), got %s", d)
+	}
+}
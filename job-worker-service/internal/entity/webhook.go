@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookStatus — состояние доставки webhook'а о завершении job.
+type WebhookStatus string
+
+const (
+	WebhookPending   WebhookStatus = "pending"   // ждёт первой или следующей попытки
+	WebhookDelivered WebhookStatus = "delivered" // получатель ответил 2xx
+	WebhookFailed    WebhookStatus = "failed"    // попытки исчерпаны
+)
+
+// WebhookDelivery — доставка webhook'а: создаётся, когда job переходит в done, error или dead.
+type WebhookDelivery struct {
+	ID            int64
+	JobID         uuid.UUID
+	JobStatus     JobStatus // статус, которым завершилась job
+	URL           string
+	Status        WebhookStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+
+	// AttemptLog — попытки по порядку (заполняет только ListWebhookDeliveries).
+	AttemptLog []WebhookAttempt
+}
+
+// WebhookAttempt — одна попытка доставки.
+type WebhookAttempt struct {
+	Attempt    int
+	StatusCode int    // 0 — ответа нет (сетевая ошибка, timeout)
+	Error      string // "" — получатель ответил 2xx
+	Duration   time.Duration
+	At         time.Time
+}
+
+// WebhookSettings — webhook клиента (X-Client-ID) по умолчанию.
+// URL получает job клиента без callback_url; Secret подписывает все webhook'и клиента ("" — общий секрет сервиса).
+type WebhookSettings struct {
+	ClientID  string
+	URL       string
+	Secret    string
+	UpdatedAt time.Time
+}
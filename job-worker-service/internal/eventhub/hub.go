@@ -0,0 +1,123 @@
+// Package eventhub — раздача событий job подписчикам внутри процесса.
+package eventhub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// Buffer — сколько событий может отстать подписчик; дальше он теряет самые старые.
+const Buffer = 64
+
+// Hub раздаёт события job подписчикам процесса (SSE-клиентам, long-poll результата).
+// Медленный подписчик теряет самые старые события, а не тормозит остальных и источник:
+// последнее событие (например, финальный статус job) до него дойдёт.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[chan entity.JobEvent]struct{}
+	jobs   map[uuid.UUID]map[chan entity.JobEvent]struct{}
+	closed bool
+}
+
+func New() *Hub {
+	return &Hub{
+		subs: map[chan entity.JobEvent]struct{}{},
+		jobs: map[uuid.UUID]map[chan entity.JobEvent]struct{}{},
+	}
+}
+
+func (h *Hub) Publish(ev entity.JobEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		send(ch, ev)
+	}
+	for ch := range h.jobs[ev.JobID] {
+		send(ch, ev)
+	}
+}
+
+// send кладёт ev в ch; если буфер полон — выбрасывает самое старое событие.
+// Пишет в ch только Publish под h.mu, читатель только забирает — после выброса место есть.
+func send(ch chan entity.JobEvent, ev entity.JobEvent) {
+	select {
+	case ch <- ev:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- ev
+}
+
+// Subscribe returns events of all jobs published after the call;
+// channel is closed when ctx is done or hub is closed.
+func (h *Hub) Subscribe(ctx context.Context) (<-chan entity.JobEvent, error) {
+	return h.subscribe(ctx, uuid.Nil), nil
+}
+
+// SubscribeJob returns events of job id published after the call; как Subscribe, но без событий других job.
+func (h *Hub) SubscribeJob(ctx context.Context, id uuid.UUID) (<-chan entity.JobEvent, error) {
+	return h.subscribe(ctx, id), nil
+}
+
+// subscribe: id == uuid.Nil — события всех job.
+func (h *Hub) subscribe(ctx context.Context, id uuid.UUID) <-chan entity.JobEvent {
+	ch := make(chan entity.JobEvent, Buffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	subs := h.subs
+	if id != uuid.Nil {
+		if subs = h.jobs[id]; subs == nil {
+			subs = map[chan entity.JobEvent]struct{}{}
+			h.jobs[id] = subs
+		}
+	}
+	subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := subs[ch]; !ok {
+			return // уже закрыт Close
+		}
+		delete(subs, ch)
+		close(ch)
+		if id != uuid.Nil && len(subs) == 0 {
+			delete(h.jobs, id)
+		}
+	}()
+	return ch
+}
+
+// Close closes all subscriptions (источник событий остановлен).
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+	for id, subs := range h.jobs {
+		for ch := range subs {
+			delete(subs, ch)
+			close(ch)
+		}
+		delete(h.jobs, id)
+	}
+}
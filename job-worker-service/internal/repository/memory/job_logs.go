@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// AppendLogs пишет строки лога попытки token; статус не проверяется: лог отменённой попытки сохраняется.
+func (r *JobRepository) AppendLogs(ctx context.Context, jobID uuid.UUID, token int64, logs []entity.JobLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// как FOREIGN KEY job_logs.job_id
+	j, ok := r.jobs[jobID]
+	if !ok {
+		return entity.ErrNotFound
+	}
+	if j.FenceToken != token {
+		return entity.ErrStaleAttempt
+	}
+	for _, l := range logs {
+		l.ID = int64(len(r.logs) + 1)
+		l.JobID = jobID
+		r.logs = append(r.logs, l)
+	}
+	return nil
+}
+
+// ListLogs returns log lines of job matching f ordered by id, at most f.Limit.
+func (r *JobRepository) ListLogs(ctx context.Context, jobID uuid.UUID, f entity.JobLogFilter) ([]entity.JobLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []entity.JobLog
+	for _, l := range r.logs[min(max(f.After, 0), int64(len(r.logs))):] {
+		if len(out) == f.Limit {
+			break
+		}
+		if l.JobID != jobID ||
+			(f.Attempt > 0 && l.Attempt != f.Attempt) ||
+			(len(f.Levels) > 0 && !slices.Contains(f.Levels, l.Level)) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
@@ -0,0 +1,500 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/eventhub"
+)
+
+// JobRepository — in-process реализация service.JobRepository и worker.JobRepo
+// (cmd/standalone и тесты). Семантика та же, что у postgresql.JobRepository,
+// включая entity.ErrNotFound, который проверяет Processor.
+// Заодно реализует service.WebhookRepository (webhooks.go): доставки живут рядом с jobs, как таблицы в одной БД.
+// Лог job (job_logs.go) — так же; история переходов (transition) — как таблица job_events.
+type JobRepository struct {
+	mu   sync.RWMutex
+	jobs map[uuid.UUID]*entity.Job
+
+	// events — смены статуса и прогресс (аналог NOTIFY job_events у postgresql)
+	events *eventhub.Hub
+
+	webhooks        []*entity.WebhookDelivery // по id
+	webhookSettings map[string]entity.WebhookSettings
+
+	logs []entity.JobLog // лог job (job_logs.go), по id
+
+	history []entity.JobTransition // по id (как таблица job_events)
+
+	leases map[uuid.UUID]time.Time // lease попытки (как jobs.lease_until у postgresql)
+}
+
+func NewJobRepository() *JobRepository {
+	return &JobRepository{
+		jobs:            map[uuid.UUID]*entity.Job{},
+		events:          eventhub.New(),
+		webhookSettings: map[string]entity.WebhookSettings{},
+		leases:          map[uuid.UUID]time.Time{},
+	}
+}
+
+// Subscribe implements service.EventSource.
+func (r *JobRepository) Subscribe(ctx context.Context) (<-chan entity.JobEvent, error) {
+	return r.events.Subscribe(ctx)
+}
+
+func (r *JobRepository) Create(ctx context.Context, job *entity.Job) (uuid.UUID, error) {
+	now := time.Now().UTC()
+
+	j := *job
+	j.ID = uuid.New()
+	j.Status = entity.StatusPending
+	j.Attempts = 0
+	j.CreatedAt = now
+	j.UpdatedAt = now
+	if len(j.Input) == 0 {
+		j.Input = json.RawMessage(`{}`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// как уникальный индекс uq_jobs_idempotency_key
+	if k := j.Idempotency; k != nil && r.findByIdempotencyKey(j.ClientID, k.Key) != nil {
+		return uuid.Nil, entity.ErrIdempotencyKeyExists
+	}
+	// как частичный уникальный индекс uq_jobs_unique_key
+	if j.UniqueKey != "" && r.findActiveByUniqueKey(j.Type, j.UniqueKey) != nil {
+		return uuid.Nil, entity.ErrUniqueJobExists
+	}
+	r.jobs[j.ID] = &j
+	r.recordTransition(ctx, &j, "", "")
+	r.events.Publish(statusEvent(&j))
+
+	return j.ID, nil
+}
+
+func (r *JobRepository) GetByIdempotencyKey(ctx context.Context, clientID, key string) (*entity.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	j := r.findByIdempotencyKey(clientID, key)
+	if j == nil {
+		return nil, entity.ErrNotFound
+	}
+	cp := *j
+	return &cp, nil
+}
+
+// findByIdempotencyKey; r.mu must be held.
+func (r *JobRepository) findByIdempotencyKey(clientID, key string) *entity.Job {
+	for _, j := range r.jobs {
+		if k := j.Idempotency; k != nil && j.ClientID == clientID && k.Key == key {
+			return j
+		}
+	}
+	return nil
+}
+
+// GetByID returns a copy: вызывающий код не может поменять job в обход методов репозитория.
+func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	j, ok := r.jobs[id]
+	if !ok {
+		return nil, entity.ErrNotFound
+	}
+	cp := *j
+	return &cp, nil
+}
+
+func (r *JobRepository) GetActiveByUniqueKey(ctx context.Context, typ, key string) (*entity.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	j := r.findActiveByUniqueKey(typ, key)
+	if j == nil {
+		return nil, entity.ErrNotFound
+	}
+	cp := *j
+	return &cp, nil
+}
+
+// findActiveByUniqueKey returns pending/processing job with (typ, key); r.mu must be held.
+func (r *JobRepository) findActiveByUniqueKey(typ, key string) *entity.Job {
+	for _, j := range r.jobs {
+		if j.Type == typ && j.UniqueKey == key && (j.Status == entity.StatusPending || j.Status == entity.StatusProcessing) {
+			return j
+		}
+	}
+	return nil
+}
+
+// ListStale returns jobs in status that were not updated since updatedBefore, ordered by (updated_at, id);
+// after — последняя job предыдущей страницы (nil — с начала).
+func (r *JobRepository) ListStale(ctx context.Context, status entity.JobStatus, updatedBefore time.Time, after *entity.StaleCursor, limit int) ([]*entity.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// less: (updated_at, id) a < b
+	less := func(aAt time.Time, aID uuid.UUID, bAt time.Time, bID uuid.UUID) bool {
+		if c := aAt.Compare(bAt); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(aID[:], bID[:]) < 0
+	}
+
+	var out []*entity.Job
+	for _, j := range r.jobs {
+		if j.Status != status || !j.UpdatedAt.Before(updatedBefore) {
+			continue
+		}
+		if after != nil && !less(after.UpdatedAt, after.ID, j.UpdatedAt, j.ID) {
+			continue
+		}
+		cp := *j
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, k int) bool { return less(out[i].UpdatedAt, out[i].ID, out[k].UpdatedAt, out[k].ID) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// List returns jobs matching f ordered by (created_at, id) in f.Order, at most f.Limit.
+func (r *JobRepository) List(ctx context.Context, f entity.JobFilter) ([]*entity.Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	desc := f.Order != entity.SortAsc
+	// less: a идёт раньше b в порядке сортировки
+	less := func(a, b *entity.Job) bool {
+		c := a.CreatedAt.Compare(b.CreatedAt)
+		if c == 0 {
+			c = bytes.Compare(a.ID[:], b.ID[:])
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	var after *entity.Job
+	if f.After != nil {
+		after = &entity.Job{ID: f.After.ID, CreatedAt: f.After.CreatedAt}
+	}
+
+	var out []*entity.Job
+	for _, j := range r.jobs {
+		if !matchFilter(j, f) || (after != nil && !less(after, j)) {
+			continue
+		}
+		cp := *j
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, k int) bool { return less(out[i], out[k]) })
+	if len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func matchFilter(j *entity.Job, f entity.JobFilter) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, j.Status) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, j.Type) {
+		return false
+	}
+	if f.Priority != nil && j.Priority != *f.Priority {
+		return false
+	}
+	if f.CreatedFrom != nil && j.CreatedAt.Before(*f.CreatedFrom) {
+		return false
+	}
+	if f.CreatedTo != nil && !j.CreatedAt.Before(*f.CreatedTo) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(j.Tags, tag) {
+			return false
+		}
+	}
+	if f.ParentID != nil && (j.ParentID == nil || *j.ParentID != *f.ParentID) {
+		return false
+	}
+	return true
+}
+
+// StartAttempt переводит pending job (или processing, если lease прошлой попытки истёк) в processing,
+// увеличивает счётчик попыток и fencing token, сбрасывает прогресс, ставит lease на lease и возвращает token.
+// Завершённую или отменённую job и processing job с живым lease не трогает (ErrInvalidTransition).
+func (r *JobRepository) StartAttempt(ctx context.Context, id uuid.UUID, lease time.Duration) (int64, error) {
+	var token int64
+	err := r.transition(ctx, id, "", func(j *entity.Job) error {
+		if !slices.Contains(entity.AllowedSources(entity.StatusProcessing), j.Status) {
+			return entity.ErrInvalidTransition
+		}
+		if j.Status == entity.StatusProcessing && time.Now().Before(r.leases[id]) {
+			return entity.ErrInvalidTransition
+		}
+		j.Status = entity.StatusProcessing
+		j.Attempts++
+		j.FenceToken++
+		j.Progress = nil
+		token = j.FenceToken
+		r.leases[id] = time.Now().Add(lease)
+		return nil
+	})
+	return token, err
+}
+
+// ExtendLease продлевает lease текущей попытки (token) на lease от текущего момента.
+func (r *JobRepository) ExtendLease(ctx context.Context, id uuid.UUID, token int64, lease time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, ok := r.jobs[id]
+	if !ok {
+		return entity.ErrNotFound
+	}
+	if err := checkFence(j, token, entity.StatusProcessing); err != nil {
+		return err
+	}
+	r.leases[id] = time.Now().Add(lease)
+	return nil
+}
+
+// ExpireLease снимает lease попытки — как если бы worker перестал его продлевать (для тестов).
+func (r *JobRepository) ExpireLease(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.leases, id)
+}
+
+func (r *JobRepository) SetRetry(ctx context.Context, id uuid.UUID, token int64, class entity.ErrorClass, errText string, runAt time.Time) error {
+	return r.transition(ctx, id, errText, func(j *entity.Job) error {
+		if err := checkFence(j, token, entity.AllowedSources(entity.StatusPending, entity.StatusProcessing)...); err != nil {
+			return err
+		}
+		j.Status = entity.StatusPending
+		j.Error = &errText
+		j.ErrorClass = class
+		j.RunAt = &runAt
+		return nil
+	})
+}
+
+// ReleaseAttempt возвращает прерванную попытку (остановка worker'а) в pending, не засчитывая её.
+func (r *JobRepository) ReleaseAttempt(ctx context.Context, id uuid.UUID, token int64) error {
+	return r.transition(ctx, id, "attempt released", func(j *entity.Job) error {
+		if err := checkFence(j, token, entity.AllowedSources(entity.StatusPending, entity.StatusProcessing)...); err != nil {
+			return err
+		}
+		j.Status = entity.StatusPending
+		j.Attempts = max(j.Attempts-1, 0)
+		j.Progress = nil
+		return nil
+	})
+}
+
+func (r *JobRepository) SetDead(ctx context.Context, id uuid.UUID, token int64, class entity.ErrorClass, errText string) error {
+	return r.transition(ctx, id, errText, func(j *entity.Job) error {
+		if err := checkFence(j, token, entity.AllowedSources(entity.StatusDead)...); err != nil {
+			return err
+		}
+		j.Status = entity.StatusDead
+		j.Error = &errText
+		j.ErrorClass = class
+		return nil
+	})
+}
+
+// ResetToPending сбрасывает упавшую или отменённую job (error/dead/canceled) для повторного запуска "с нуля".
+// entity.ErrInvalidTransition — job в другом статусе; entity.ErrUniqueJobExists — уже есть активная job с тем же unique key.
+func (r *JobRepository) ResetToPending(ctx context.Context, id uuid.UUID, priority int) error {
+	return r.transition(ctx, id, "", func(j *entity.Job) error {
+		if !slices.Contains(entity.AllowedSources(entity.StatusPending, entity.StatusError, entity.StatusDead, entity.StatusCanceled), j.Status) {
+			return entity.ErrInvalidTransition
+		}
+		if j.UniqueKey != "" && r.findActiveByUniqueKey(j.Type, j.UniqueKey) != nil {
+			return entity.ErrUniqueJobExists
+		}
+		j.Status = entity.StatusPending
+		j.Priority = priority
+		j.RunAt = nil
+		j.Attempts = 0
+		j.Output = nil
+		j.Error = nil
+		j.ErrorClass = ""
+		j.Progress = nil
+		return nil
+	})
+}
+
+func (r *JobRepository) SetResultDone(ctx context.Context, id uuid.UUID, token int64, output json.RawMessage) error {
+	if len(output) == 0 {
+		output = json.RawMessage(`{}`)
+	}
+	return r.transition(ctx, id, "", func(j *entity.Job) error {
+		if err := checkFence(j, token, entity.AllowedSources(entity.StatusDone)...); err != nil {
+			return err
+		}
+		j.Status = entity.StatusDone
+		j.Output = output
+		j.Error = nil
+		j.ErrorClass = ""
+		return nil
+	})
+}
+
+// SetResultError фиксирует job как error: после попытки (processing) или потерянную в очереди (pending).
+func (r *JobRepository) SetResultError(ctx context.Context, id uuid.UUID, token int64, errText string) error {
+	return r.transition(ctx, id, errText, func(j *entity.Job) error {
+		if err := checkFence(j, token, entity.AllowedSources(entity.StatusError)...); err != nil {
+			return err
+		}
+		j.Status = entity.StatusError
+		j.Error = &errText
+		j.ErrorClass = entity.ErrorClassError
+		return nil
+	})
+}
+
+// Cancel переводит pending/processing job в canceled и возвращает статус до отмены.
+func (r *JobRepository) Cancel(ctx context.Context, id uuid.UUID) (entity.JobStatus, error) {
+	var prev entity.JobStatus
+	err := r.transition(ctx, id, "", func(j *entity.Job) error {
+		if !slices.Contains(entity.AllowedSources(entity.StatusCanceled), j.Status) {
+			return entity.ErrNotFound
+		}
+		prev = j.Status
+		j.Status = entity.StatusCanceled
+		return nil
+	})
+	return prev, err
+}
+
+// checkFence — как WHERE status IN (from) AND fence_token = token у postgresql.
+func checkFence(j *entity.Job, token int64, from ...entity.JobStatus) error {
+	if j.FenceToken != token {
+		return entity.ErrStaleAttempt
+	}
+	if !slices.Contains(from, j.Status) {
+		return entity.ErrInvalidTransition
+	}
+	return nil
+}
+
+// SetProgress сохраняет прогресс processing job и публикует его подписчикам.
+func (r *JobRepository) SetProgress(ctx context.Context, id uuid.UUID, token int64, p entity.JobProgress) error {
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now().UTC()
+	}
+
+	var ev entity.JobEvent
+	err := r.update(id, func(j *entity.Job) error {
+		if err := checkFence(j, token, entity.StatusProcessing); err != nil {
+			return err
+		}
+		j.Progress = &p
+		ev = statusEvent(j)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ev.Kind = entity.EventProgress
+	ev.Progress = &p.Percent
+	ev.Stage = p.Stage
+	ev.Message = p.Message
+	ev.At = p.UpdatedAt
+	r.events.Publish(ev)
+	return nil
+}
+
+// transition — update, который пишет изменение в историю job (как job_events у postgresql).
+func (r *JobRepository) transition(ctx context.Context, id uuid.UUID, detail string, fn func(j *entity.Job) error) error {
+	return r.update(id, func(j *entity.Job) error {
+		from := j.Status
+		if err := fn(j); err != nil {
+			return err
+		}
+		r.recordTransition(ctx, j, from, detail)
+		return nil
+	})
+}
+
+// recordTransition; r.mu must be held.
+func (r *JobRepository) recordTransition(ctx context.Context, j *entity.Job, from entity.JobStatus, detail string) {
+	r.history = append(r.history, entity.JobTransition{
+		ID:      int64(len(r.history) + 1),
+		JobID:   j.ID,
+		From:    from,
+		To:      j.Status,
+		Attempt: j.Attempts,
+		Actor:   entity.ActorFrom(ctx),
+		Detail:  detail,
+		At:      time.Now().UTC(),
+	})
+}
+
+// ListHistory returns transitions of job, oldest first.
+func (r *JobRepository) ListHistory(ctx context.Context, jobID uuid.UUID) ([]entity.JobTransition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []entity.JobTransition
+	for _, t := range r.history {
+		if t.JobID == jobID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// update applies fn under the lock; fn returns error if the job does not match (как WHERE в SQL):
+// entity.ErrNotFound, entity.ErrInvalidTransition или entity.ErrStaleAttempt.
+// Смена статуса публикуется подписчикам (как trigger jobs_notify_event)
+// и создаёт доставку webhook'а (как trigger jobs_enqueue_webhook).
+func (r *JobRepository) update(id uuid.UUID, fn func(j *entity.Job) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, ok := r.jobs[id]
+	if !ok {
+		return entity.ErrNotFound
+	}
+	prev := j.Status
+	if err := fn(j); err != nil {
+		return err
+	}
+	j.UpdatedAt = time.Now().UTC()
+	if j.Status != prev {
+		r.events.Publish(statusEvent(j))
+		r.enqueueWebhook(j)
+	}
+	return nil
+}
+
+// statusEvent — то же, что payload NOTIFY: без результата (его добавляет service.EventService).
+func statusEvent(j *entity.Job) entity.JobEvent {
+	return entity.JobEvent{
+		Kind:     entity.EventStatus,
+		JobID:    j.ID,
+		Type:     j.Type,
+		Status:   j.Status,
+		Attempts: j.Attempts,
+		At:       j.UpdatedAt,
+	}
+}
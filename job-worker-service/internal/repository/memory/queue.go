@@ -0,0 +1,355 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"job-worker-service/internal/entity"
+)
+
+// ErrQueueEmpty — нечего забирать (аналог redis.Nil у Redis-очередей).
+var ErrQueueEmpty = errors.New("queue is empty")
+
+type scheduledJob struct {
+	priority int
+	at       time.Time
+}
+
+type lease struct {
+	priority int
+	expires  time.Time
+}
+
+// Queue — in-process реализация service.QueueBackend (cmd/standalone и тесты).
+// Lanes: по priority (0 low, 1 normal, 2 high), FIFO внутри lane.
+// Claim: high -> normal -> low + lease; Heartbeat продлевает lease, Ack снимает.
+// Reaper (RequeueStale) возвращает в lane только job с истёкшим lease.
+// Delayed jobs ждут в scheduled, пока PromoteDue не перенесёт их в lane.
+// Состояние живёт только в памяти процесса.
+type Queue struct {
+	mu       sync.Mutex
+	leaseTTL time.Duration
+
+	lanes     [3][]string
+	scheduled map[string]scheduledJob
+	inflight  map[string]lease
+	dead      []entity.DeadLetter // новые первыми
+	canceled  map[string]struct{} // сигналы отмены (SignalCancel)
+
+	// notify будит ClaimBlocking, когда в lane что-то появилось
+	notify chan struct{}
+}
+
+func NewQueue(leaseTTL time.Duration) *Queue {
+	if leaseTTL <= 0 {
+		leaseTTL = entity.DefaultLeaseTTL
+	}
+	return &Queue{
+		leaseTTL:  leaseTTL,
+		scheduled: map[string]scheduledJob{},
+		inflight:  map[string]lease{},
+		canceled:  map[string]struct{}{},
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) LeaseTTL() time.Duration {
+	return q.leaseTTL
+}
+
+func (q *Queue) Enqueue(ctx context.Context, jobID string, priority int) error {
+	q.mu.Lock()
+	q.push(jobID, entity.ClampPriority(priority))
+	q.mu.Unlock()
+	return nil
+}
+
+// push adds job to its lane and wakes a waiting ClaimBlocking; q.mu must be held.
+func (q *Queue) push(jobID string, priority int) {
+	q.lanes[priority] = append(q.lanes[priority], jobID)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	if !at.After(time.Now()) {
+		return q.Enqueue(ctx, jobID, priority)
+	}
+
+	q.mu.Lock()
+	q.scheduled[jobID] = scheduledJob{priority: entity.ClampPriority(priority), at: at}
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *Queue) PromoteDue(ctx context.Context, maxPerLane int64) (int64, error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	due := make([]string, 0)
+	for id, s := range q.scheduled {
+		if !s.at.After(now) {
+			due = append(due, id)
+		}
+	}
+	// раньше запланированные — раньше в очереди
+	sort.Slice(due, func(i, j int) bool { return q.scheduled[due[i]].at.Before(q.scheduled[due[j]].at) })
+
+	var moved int64
+	var perLane [3]int64
+	for _, id := range due {
+		p := q.scheduled[id].priority
+		if perLane[p] >= maxPerLane {
+			continue
+		}
+		delete(q.scheduled, id)
+		q.push(id, p)
+		perLane[p]++
+		moved++
+	}
+	return moved, nil
+}
+
+func (q *Queue) ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error) {
+	// if timeout <= 0, loop forever (like a worker daemon)
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		id, err := q.Claim(ctx)
+		if !errors.Is(err, ErrQueueEmpty) {
+			return id, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-deadline:
+			return "", ErrQueueEmpty
+		case <-q.notify:
+		}
+	}
+}
+
+// Claim takes one job (high->normal->low) and records its lease; ErrQueueEmpty if all lanes are empty.
+func (q *Queue) Claim(ctx context.Context) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for p := 2; p >= 0; p-- {
+		if len(q.lanes[p]) == 0 {
+			continue
+		}
+		id := q.lanes[p][0]
+		q.lanes[p] = q.lanes[p][1:]
+		q.inflight[id] = lease{priority: p, expires: time.Now().Add(q.leaseTTL)}
+		return id, nil
+	}
+	return "", ErrQueueEmpty
+}
+
+func (q *Queue) Heartbeat(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	l, ok := q.inflight[jobID]
+	if !ok {
+		return entity.ErrLeaseLost
+	}
+	l.expires = time.Now().Add(q.leaseTTL)
+	q.inflight[jobID] = l
+	return nil
+}
+
+func (q *Queue) Ack(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	delete(q.inflight, jobID)
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *Queue) RequeueStale(ctx context.Context, limit int64) (int64, error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var moved int64
+	for id, l := range q.inflight {
+		if moved >= limit {
+			break
+		}
+		if l.expires.After(now) {
+			continue
+		}
+		delete(q.inflight, id)
+		q.push(id, l.priority)
+		moved++
+	}
+	return moved, nil
+}
+
+// DeadLetter кладёт job в dead-letter очередь (повторный вызов не дублирует запись).
+func (q *Queue) DeadLetter(ctx context.Context, jobID string, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.removeDead(jobID)
+	dl := entity.DeadLetter{JobID: jobID, Reason: reason, DeadAt: time.Now().UTC()}
+	q.dead = append([]entity.DeadLetter{dl}, q.dead...)
+	return nil
+}
+
+func (q *Queue) ListDead(ctx context.Context, offset, limit int64) ([]entity.DeadLetter, int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	total := int64(len(q.dead))
+	if limit <= 0 || offset >= total {
+		return []entity.DeadLetter{}, total, nil
+	}
+	end := min(offset+limit, total)
+
+	out := make([]entity.DeadLetter, end-offset)
+	copy(out, q.dead[offset:end])
+	return out, total, nil
+}
+
+func (q *Queue) GetDead(ctx context.Context, jobID string) (*entity.DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, dl := range q.dead {
+		if dl.JobID == jobID {
+			return &dl, nil
+		}
+	}
+	return nil, entity.ErrDeadLetterNotFound
+}
+
+func (q *Queue) RemoveDead(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.removeDead(jobID) {
+		return entity.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (q *Queue) PurgeDead(ctx context.Context) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := int64(len(q.dead))
+	q.dead = nil
+	return n, nil
+}
+
+// removeDead; q.mu must be held.
+func (q *Queue) removeDead(jobID string) bool {
+	for i, dl := range q.dead {
+		if dl.JobID == jobID {
+			q.dead = append(q.dead[:i], q.dead[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Remove убирает ещё не взятую job из lane и scheduled.
+func (q *Queue) Remove(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.scheduled, jobID)
+	for p, lane := range q.lanes {
+		q.lanes[p] = slices.DeleteFunc(lane, func(id string) bool { return id == jobID })
+	}
+	return nil
+}
+
+func (q *Queue) SignalCancel(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	q.canceled[jobID] = struct{}{}
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *Queue) ClearCancel(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	delete(q.canceled, jobID)
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *Queue) CancelRequested(ctx context.Context, jobIDs []string) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var out []string
+	for _, id := range jobIDs {
+		if _, ok := q.canceled[id]; ok {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// Tracked reports which of jobIDs the queue knows about (queued, scheduled or claimed).
+func (q *Queue) Tracked(ctx context.Context, jobIDs []string) (map[string]bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make(map[string]bool, len(jobIDs))
+	for _, id := range jobIDs {
+		_, scheduled := q.scheduled[id]
+		_, claimed := q.inflight[id]
+		out[id] = scheduled || claimed || q.queued(id)
+	}
+	return out, nil
+}
+
+// queued; q.mu must be held.
+func (q *Queue) queued(jobID string) bool {
+	for _, lane := range q.lanes {
+		for _, id := range lane {
+			if id == jobID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Pending returns job ids waiting in the lane of given priority (для тестов и отладки).
+func (q *Queue) Pending(priority int) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lane := q.lanes[entity.ClampPriority(priority)]
+	out := make([]string, len(lane))
+	copy(out, lane)
+	return out
+}
+
+// Scheduled returns when a delayed job is due (для тестов и отладки).
+func (q *Queue) Scheduled(jobID string) (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s, ok := q.scheduled[jobID]
+	return s.at, ok
+}
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// enqueueWebhook — то же, что trigger jobs_enqueue_webhook: доставка на callback_url job
+// или URL клиента, когда job переходит в done / error / dead; r.mu must be held.
+func (r *JobRepository) enqueueWebhook(j *entity.Job) {
+	if j.Status != entity.StatusDone && j.Status != entity.StatusError && j.Status != entity.StatusDead {
+		return
+	}
+	target := j.CallbackURL
+	if target == "" && j.ClientID != "" {
+		target = r.webhookSettings[j.ClientID].URL
+	}
+	if target == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+	r.webhooks = append(r.webhooks, &entity.WebhookDelivery{
+		ID:            int64(len(r.webhooks) + 1),
+		JobID:         j.ID,
+		JobStatus:     j.Status,
+		URL:           target,
+		Status:        entity.WebhookPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+func (r *JobRepository) ClaimDueWebhooks(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var due []*entity.WebhookDelivery
+	for _, d := range r.webhooks {
+		if d.Status == entity.WebhookPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b *entity.WebhookDelivery) int { return a.NextAttemptAt.Compare(b.NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	out := make([]entity.WebhookDelivery, len(due))
+	for i, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		out[i] = *d
+		out[i].AttemptLog = nil
+	}
+	return out, nil
+}
+
+func (r *JobRepository) RecordWebhookAttempt(ctx context.Context, deliveryID int64, a entity.WebhookAttempt, status entity.WebhookStatus, next time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.webhook(deliveryID)
+	if d == nil {
+		return entity.ErrNotFound
+	}
+	d.AttemptLog = append(d.AttemptLog, a)
+	d.Status = status
+	d.Attempts = a.Attempt
+	d.LastError = a.Error
+	d.NextAttemptAt = a.At
+	d.DeliveredAt = nil
+	switch status {
+	case entity.WebhookPending:
+		d.NextAttemptAt = next
+	case entity.WebhookDelivered:
+		at := a.At
+		d.DeliveredAt = &at
+	}
+	return nil
+}
+
+// webhook; r.mu must be held.
+func (r *JobRepository) webhook(id int64) *entity.WebhookDelivery {
+	if id < 1 || id > int64(len(r.webhooks)) {
+		return nil
+	}
+	return r.webhooks[id-1]
+}
+
+func (r *JobRepository) ListWebhookDeliveries(ctx context.Context, jobID uuid.UUID) ([]entity.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []entity.WebhookDelivery
+	for _, d := range r.webhooks {
+		if d.JobID == jobID {
+			cp := *d
+			cp.AttemptLog = slices.Clone(d.AttemptLog)
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func (r *JobRepository) GetWebhookSettings(ctx context.Context, clientID string) (*entity.WebhookSettings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.webhookSettings[clientID]
+	if !ok {
+		return nil, entity.ErrWebhookSettingsNotFound
+	}
+	return &s, nil
+}
+
+func (r *JobRepository) PutWebhookSettings(ctx context.Context, s entity.WebhookSettings) error {
+	r.mu.Lock()
+	r.webhookSettings[s.ClientID] = s
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *JobRepository) DeleteWebhookSettings(ctx context.Context, clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhookSettings[clientID]; !ok {
+		return entity.ErrWebhookSettingsNotFound
+	}
+	delete(r.webhookSettings, clientID)
+	return nil
+}
@@ -0,0 +1,96 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"job-worker-service/internal/entity"
+)
+
+// DeadLetters — dead-letter очередь в таблице dead_letters (для Postgres-бэкенда очереди).
+// job_id — text, а не uuid: туда же попадают "битые" id, которых нет в jobs.
+type DeadLetters struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeadLetters(pool *pgxpool.Pool) *DeadLetters {
+	return &DeadLetters{pool: pool}
+}
+
+// DeadLetter кладёт job в dead-letter очередь (повторный вызов обновляет reason/dead_at, без дублей).
+func (d *DeadLetters) DeadLetter(ctx context.Context, jobID string, reason string) error {
+	const q = `
+INSERT INTO dead_letters (job_id, reason, dead_at)
+VALUES ($1, $2, now())
+ON CONFLICT (job_id) DO UPDATE SET reason = EXCLUDED.reason, dead_at = EXCLUDED.dead_at;
+`
+	_, err := conn(ctx, d.pool).Exec(ctx, q, jobID, reason)
+	return err
+}
+
+func (d *DeadLetters) ListDead(ctx context.Context, offset, limit int64) ([]entity.DeadLetter, int64, error) {
+	db := conn(ctx, d.pool)
+
+	var total int64
+	if err := db.QueryRow(ctx, `SELECT count(*) FROM dead_letters;`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 || offset >= total {
+		return []entity.DeadLetter{}, total, nil
+	}
+
+	const q = `
+SELECT job_id, reason, dead_at
+FROM dead_letters
+ORDER BY dead_at DESC, job_id
+OFFSET $1 LIMIT $2;
+`
+	rows, err := db.Query(ctx, q, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.DeadLetter, error) {
+		var dl entity.DeadLetter
+		err := row.Scan(&dl.JobID, &dl.Reason, &dl.DeadAt)
+		return dl, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (d *DeadLetters) GetDead(ctx context.Context, jobID string) (*entity.DeadLetter, error) {
+	const q = `SELECT job_id, reason, dead_at FROM dead_letters WHERE job_id = $1;`
+
+	var dl entity.DeadLetter
+	if err := conn(ctx, d.pool).QueryRow(ctx, q, jobID).Scan(&dl.JobID, &dl.Reason, &dl.DeadAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, entity.ErrDeadLetterNotFound
+		}
+		return nil, err
+	}
+	return &dl, nil
+}
+
+func (d *DeadLetters) RemoveDead(ctx context.Context, jobID string) error {
+	tag, err := conn(ctx, d.pool).Exec(ctx, `DELETE FROM dead_letters WHERE job_id = $1;`, jobID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (d *DeadLetters) PurgeDead(ctx context.Context) (int64, error) {
+	tag, err := conn(ctx, d.pool).Exec(ctx, `DELETE FROM dead_letters;`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,67 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/eventhub"
+)
+
+// eventsChannel — канал NOTIFY: trigger jobs_notify_event (смена статуса) и JobRepository.SetProgress.
+const eventsChannel = "job_events"
+
+// Events — service.EventSource поверх LISTEN/NOTIFY: одно соединение на процесс,
+// события раздаются подписчикам через eventhub.Hub.
+// NOTIFY пишет сама БД, поэтому события видны при любом QUEUE_BACKEND.
+type Events struct {
+	pool *pgxpool.Pool
+	hub  *eventhub.Hub
+}
+
+func NewEvents(pool *pgxpool.Pool) *Events {
+	return &Events{pool: pool, hub: eventhub.New()}
+}
+
+func (e *Events) Subscribe(ctx context.Context) (<-chan entity.JobEvent, error) {
+	return e.hub.Subscribe(ctx)
+}
+
+// Listen держит отдельное соединение с LISTEN job_events и раздаёт события,
+// пока не отменён ctx или не оборвалось соединение (тогда возвращает ошибку — вызывающий переподключается).
+// События, пришедшие между обрывом и переподключением, теряются.
+func (e *Events) Listen(ctx context.Context) error {
+	c, err := e.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// соединение с LISTEN не возвращаем в pool
+	conn := c.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var ev entity.JobEvent
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			log.Printf("[events] bad payload=%q error=%v", n.Payload, err)
+			continue
+		}
+		e.hub.Publish(ev)
+	}
+}
+
+// Close закрывает подписки (SSE-потоки завершаются); вызывается, когда Listen больше не запускается.
+func (e *Events) Close() {
+	e.hub.Close()
+}
@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"job-worker-service/internal/entity"
+)
+
+// transition меняет одну job и пишет переход в историю (job_events) одной командой;
+// возвращает статус до изменения и fencing token после.
+// set — SET для jobs, cond — условие на текущую строку (old.status), параметры set/cond начинаются с $4:
+// $1 — id, $2 — actor (entity.ActorFrom), $3 — detail.
+// entity.ErrNotFound — job нет или cond не выполнено.
+func (r *JobRepository) transition(ctx context.Context, id uuid.UUID, set, cond, detail string, args ...any) (entity.JobStatus, int64, error) {
+	q := `
+WITH old AS (
+    SELECT id, status FROM jobs WHERE id = $1 FOR UPDATE
+), upd AS (
+    UPDATE jobs j
+    SET ` + set + `
+    FROM old
+    WHERE j.id = old.id AND ` + cond + `
+    RETURNING j.id, old.status AS from_status, j.status, j.attempts, j.fence_token
+), ev AS (
+    INSERT INTO job_events (job_id, from_status, to_status, attempt, actor, detail)
+    SELECT id, from_status, status, attempts, NULLIF($2, ''), NULLIF($3, '') FROM upd
+)
+SELECT from_status, fence_token FROM upd;
+`
+	var (
+		prev  string
+		token int64
+	)
+	err := r.db(ctx).QueryRow(ctx, q, append([]any{id, entity.ActorFrom(ctx), detail}, args...)...).Scan(&prev, &token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", 0, entity.ErrNotFound
+		}
+		return "", 0, err
+	}
+	return entity.JobStatus(prev), token, nil
+}
+
+// Условия на статус до перехода (old.status) для transition — из state machine (entity.AllowedSources),
+// а не перечислены в каждом методе.
+var (
+	retryFrom  = statusIn(entity.AllowedSources(entity.StatusPending, entity.StatusProcessing))
+	resetFrom  = statusIn(entity.AllowedSources(entity.StatusPending, entity.StatusError, entity.StatusDead, entity.StatusCanceled))
+	deadFrom   = statusIn(entity.AllowedSources(entity.StatusDead))
+	doneFrom   = statusIn(entity.AllowedSources(entity.StatusDone))
+	errorFrom  = statusIn(entity.AllowedSources(entity.StatusError))
+	cancelFrom = statusIn(entity.AllowedSources(entity.StatusCanceled))
+	startFrom  = startAttemptFrom()
+)
+
+// statusIn returns "old.status IN (...)" for statuses (константы entity, не пользовательский ввод).
+func statusIn(statuses []entity.JobStatus) string {
+	quoted := make([]string, len(statuses))
+	for i, st := range statuses {
+		quoted[i] = "'" + string(st) + "'"
+	}
+	return "old.status IN (" + strings.Join(quoted, ",") + ")"
+}
+
+// startAttemptFrom — условие StartAttempt: processing -> processing (новая попытка) только после истёкшего lease.
+func startAttemptFrom() string {
+	var fresh []entity.JobStatus
+	reentry := false
+	for _, st := range entity.AllowedSources(entity.StatusProcessing) {
+		if st == entity.StatusProcessing {
+			reentry = true
+			continue
+		}
+		fresh = append(fresh, st)
+	}
+	cond := statusIn(fresh)
+	if reentry {
+		cond += ` OR (old.status = 'processing' AND j.lease_until < now())`
+	}
+	return "(" + cond + ")"
+}
+
+// fenceErr уточняет entity.ErrNotFound записи worker'а: entity.ErrStaleAttempt — token не nil и не совпал
+// (job взята новой попыткой), entity.ErrInvalidTransition — статус job не позволяет переход.
+// entity.ErrNotFound остаётся, только если job нет.
+func (r *JobRepository) fenceErr(ctx context.Context, id uuid.UUID, token *int64, err error) error {
+	if !errors.Is(err, entity.ErrNotFound) {
+		return err
+	}
+	var current int64
+	if err := r.db(ctx).QueryRow(ctx, `SELECT fence_token FROM jobs WHERE id = $1;`, id).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ErrNotFound
+		}
+		return err
+	}
+	if token != nil && current != *token {
+		return entity.ErrStaleAttempt
+	}
+	return entity.ErrInvalidTransition
+}
+
+// ListHistory returns transitions of job, oldest first.
+func (r *JobRepository) ListHistory(ctx context.Context, jobID uuid.UUID) ([]entity.JobTransition, error) {
+	const q = `
+SELECT id, job_id, from_status, to_status, attempt, actor, detail, created_at
+FROM job_events
+WHERE job_id = $1
+ORDER BY id;
+`
+	rows, err := r.db(ctx).Query(ctx, q, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.JobTransition, error) {
+		var (
+			t             entity.JobTransition
+			from          *string
+			to            string
+			actor, detail *string
+		)
+		if err := row.Scan(&t.ID, &t.JobID, &from, &to, &t.Attempt, &actor, &detail, &t.At); err != nil {
+			return t, err
+		}
+		t.To = entity.JobStatus(to)
+		if from != nil {
+			t.From = entity.JobStatus(*from)
+		}
+		if actor != nil {
+			t.Actor = *actor
+		}
+		if detail != nil {
+			t.Detail = *detail
+		}
+		return t, nil
+	})
+}
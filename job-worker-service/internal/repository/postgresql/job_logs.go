@@ -0,0 +1,87 @@
+package postgresql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"job-worker-service/internal/entity"
+)
+
+// AppendLogs пишет строки лога попытки token одной командой (migrations/015_job_logs.sql).
+// Fencing только по token, не по статусу: лог отменённой попытки сохраняется.
+func (r *JobRepository) AppendLogs(ctx context.Context, jobID uuid.UUID, token int64, logs []entity.JobLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	var (
+		attempts = make([]int, len(logs))
+		levels   = make([]string, len(logs))
+		messages = make([]string, len(logs))
+		ats      = make([]time.Time, len(logs))
+	)
+	for i, l := range logs {
+		attempts[i], levels[i], messages[i], ats[i] = l.Attempt, string(l.Level), l.Message, l.At
+	}
+
+	const q = `
+INSERT INTO job_logs (job_id, attempt, level, message, created_at)
+SELECT $1, a.attempt, a.level, a.message, a.created_at
+FROM unnest($2::int[], $3::text[], $4::text[], $5::timestamptz[]) AS a(attempt, level, message, created_at)
+WHERE EXISTS (SELECT 1 FROM jobs WHERE id = $1 AND fence_token = $6)
+ORDER BY a.created_at;
+`
+	tag, err := r.db(ctx).Exec(ctx, q, jobID, attempts, levels, messages, ats, token)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return r.fenceErr(ctx, jobID, &token, entity.ErrNotFound)
+	}
+	return nil
+}
+
+// ListLogs returns log lines of job matching f ordered by id, at most f.Limit.
+func (r *JobRepository) ListLogs(ctx context.Context, jobID uuid.UUID, f entity.JobLogFilter) ([]entity.JobLog, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"job_id = " + arg(jobID)}
+	if f.Attempt > 0 {
+		where = append(where, "attempt = "+arg(f.Attempt))
+	}
+	if len(f.Levels) > 0 {
+		levels := make([]string, len(f.Levels))
+		for i, l := range f.Levels {
+			levels[i] = string(l)
+		}
+		where = append(where, "level = ANY("+arg(levels)+")")
+	}
+	if f.After > 0 {
+		where = append(where, "id > "+arg(f.After))
+	}
+
+	q := `SELECT id, job_id, attempt, level, message, created_at FROM job_logs WHERE ` +
+		strings.Join(where, " AND ") + ` ORDER BY id LIMIT ` + arg(f.Limit) + `;`
+
+	rows, err := r.db(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.JobLog, error) {
+		var (
+			l     entity.JobLog
+			level string
+		)
+		err := row.Scan(&l.ID, &l.JobID, &l.Attempt, &level, &l.Message, &l.At)
+		l.Level = entity.LogLevel(level)
+		return l, err
+	})
+}
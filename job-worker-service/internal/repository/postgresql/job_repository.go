@@ -4,16 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"job-worker-service/internal/entity"
 )
 
-var ErrNotFound = errors.New("not found")
+// uniqueViolation — SQLSTATE unique_violation.
+const uniqueViolation = "23505"
 
 type JobRepository struct {
 	pool *pgxpool.Pool
@@ -23,30 +27,117 @@ func NewJobRepository(pool *pgxpool.Pool) *JobRepository {
 	return &JobRepository{pool: pool}
 }
 
-func (r *JobRepository) Create(ctx context.Context, typ string, priority int, input json.RawMessage) (uuid.UUID, error) {
+// db — текущая транзакция из ctx (TxManager.WithinTx) или pool.
+func (r *JobRepository) db(ctx context.Context) dbtx {
+	return conn(ctx, r.pool)
+}
+
+func (r *JobRepository) Create(ctx context.Context, job *entity.Job) (uuid.UUID, error) {
+	input := job.Input
 	if len(input) == 0 {
 		input = json.RawMessage(`{}`)
 	}
+	tags := job.Tags
+	if tags == nil {
+		tags = []string{} // колонка NOT NULL
+	}
+	var (
+		idempotencyKey *string
+		requestHash    *string
+		uniqueKey      *string
+		callbackURL    *string
+	)
+	if job.Idempotency != nil {
+		idempotencyKey = &job.Idempotency.Key
+		requestHash = &job.Idempotency.RequestHash
+	}
+	if job.UniqueKey != "" {
+		uniqueKey = &job.UniqueKey
+	}
+	if job.CallbackURL != "" {
+		callbackURL = &job.CallbackURL
+	}
+	onSuccess, err := marshalTemplate(job.OnSuccess)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	onFailure, err := marshalTemplate(job.OnFailure)
+	if err != nil {
+		return uuid.Nil, err
+	}
 
+	// созданная job — первая запись истории (job_events)
 	const q = `
-INSERT INTO jobs (type, status, priority, input)
-VALUES ($1, 'pending', $2, $3)
-RETURNING id;
+WITH ins AS (
+    INSERT INTO jobs (type, status, priority, input, max_attempts, backoff_base_ms, backoff_max_ms, run_at,
+                      timeout_ms, timeout_max_attempts, tags, client_id, idempotency_key, request_hash,
+                      unique_key, callback_url, on_success, on_failure, parent_id)
+    VALUES ($1, 'pending', $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $17, $18, $19)
+    RETURNING id, status, attempts
+), ev AS (
+    INSERT INTO job_events (job_id, to_status, attempt, actor)
+    SELECT id, status, attempts, NULLIF($16, '') FROM ins
+)
+SELECT id FROM ins;
 `
 	var id uuid.UUID
-	if err := r.pool.QueryRow(ctx, q, typ, priority, input).Scan(&id); err != nil {
-		return uuid.Nil, err
+	if err := r.db(ctx).QueryRow(ctx, q,
+		job.Type,
+		job.Priority,
+		input,
+		job.Retry.MaxAttempts,
+		job.Retry.BaseDelay.Milliseconds(),
+		job.Retry.MaxDelay.Milliseconds(),
+		job.RunAt,
+		job.Timeout.Milliseconds(),
+		job.Retry.TimeoutMaxAttempts,
+		tags,
+		job.ClientID,
+		idempotencyKey,
+		requestHash,
+		uniqueKey,
+		callbackURL,
+		entity.ActorFrom(ctx),
+		onSuccess,
+		onFailure,
+		job.ParentID,
+	).Scan(&id); err != nil {
+		return uuid.Nil, uniqueErr(err)
 	}
 	return id, nil
 }
 
-func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
-	const q = `
-SELECT id, type, status, priority, input, output, error, created_at, updated_at
-FROM jobs
-WHERE id = $1;
-`
+// marshalTemplate: nil => NULL.
+func marshalTemplate(t *entity.JobTemplate) ([]byte, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
 
+// uniqueErr maps violations of jobs unique indexes to service errors.
+func uniqueErr(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "uq_jobs_idempotency_key":
+		return entity.ErrIdempotencyKeyExists
+	case "uq_jobs_unique_key":
+		return entity.ErrUniqueJobExists
+	}
+	return err
+}
+
+// jobColumns — колонки для scanJob (в том же порядке).
+const jobColumns = `id, type, status, priority, input, output, error, created_at, updated_at,
+       attempts, max_attempts, backoff_base_ms, backoff_max_ms, run_at,
+       timeout_ms, timeout_max_attempts, error_class, tags,
+       client_id, idempotency_key, request_hash, unique_key, callback_url, progress,
+       fence_token, on_success, on_failure, parent_id`
+
+func scanJob(row pgx.Row) (*entity.Job, error) {
 	var (
 		job         entity.Job
 		statusText  string
@@ -55,9 +146,20 @@ WHERE id = $1;
 		errText     *string
 		createdAt   time.Time
 		updatedAt   time.Time
+		baseMs      int64
+		maxMs       int64
+		timeoutMs   int64
+		errClass    *string
+		idemKey     *string
+		requestHash *string
+		uniqueKey   *string
+		callbackURL *string
+		progress    []byte
+		onSuccess   []byte
+		onFailure   []byte
 	)
 
-	if err := r.pool.QueryRow(ctx, q, id).Scan(
+	if err := row.Scan(
 		&job.ID,
 		&job.Type,
 		&statusText,
@@ -67,12 +169,47 @@ WHERE id = $1;
 		&errText,     // NULL => nil
 		&createdAt,
 		&updatedAt,
+		&job.Attempts,
+		&job.Retry.MaxAttempts,
+		&baseMs,
+		&maxMs,
+		&job.RunAt, // NULL => nil
+		&timeoutMs,
+		&job.Retry.TimeoutMaxAttempts,
+		&errClass, // NULL => nil
+		&job.Tags,
+		&job.ClientID,
+		&idemKey,     // NULL => nil
+		&requestHash, // NULL => nil
+		&uniqueKey,   // NULL => nil
+		&callbackURL, // NULL => nil
+		&progress,    // NULL => nil
+		&job.FenceToken,
+		&onSuccess,    // NULL => nil
+		&onFailure,    // NULL => nil
+		&job.ParentID, // NULL => nil
 	); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
-		}
 		return nil, err
 	}
+	if progress != nil {
+		job.Progress = &entity.JobProgress{}
+		if err := json.Unmarshal(progress, job.Progress); err != nil {
+			return nil, err
+		}
+	}
+
+	if onSuccess != nil {
+		job.OnSuccess = &entity.JobTemplate{}
+		if err := json.Unmarshal(onSuccess, job.OnSuccess); err != nil {
+			return nil, err
+		}
+	}
+	if onFailure != nil {
+		job.OnFailure = &entity.JobTemplate{}
+		if err := json.Unmarshal(onFailure, job.OnFailure); err != nil {
+			return nil, err
+		}
+	}
 
 	job.Status = entity.JobStatus(statusText)
 	job.Input = json.RawMessage(inputBytes)
@@ -82,50 +219,299 @@ WHERE id = $1;
 		job.Output = nil
 	}
 	job.Error = errText
+	if errClass != nil {
+		job.ErrorClass = entity.ErrorClass(*errClass)
+	}
 	job.CreatedAt = createdAt
 	job.UpdatedAt = updatedAt
+	job.Retry.BaseDelay = time.Duration(baseMs) * time.Millisecond
+	job.Retry.MaxDelay = time.Duration(maxMs) * time.Millisecond
+	job.Timeout = time.Duration(timeoutMs) * time.Millisecond
+	if uniqueKey != nil {
+		job.UniqueKey = *uniqueKey
+	}
+	if callbackURL != nil {
+		job.CallbackURL = *callbackURL
+	}
+	if idemKey != nil {
+		job.Idempotency = &entity.Idempotency{Key: *idemKey}
+		if requestHash != nil {
+			job.Idempotency.RequestHash = *requestHash
+		}
+	}
 
 	return &job, nil
 }
 
-func (r *JobRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entity.JobStatus) error {
-	const q = `UPDATE jobs SET status=$2 WHERE id=$1;`
+func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	q := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1;`
 
-	tag, err := r.pool.Exec(ctx, q, id, string(status))
+	job, err := scanJob(r.db(ctx).QueryRow(ctx, q, id))
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+	return job, nil
+}
+
+// GetByIdempotencyKey returns job created with Idempotency-Key key by clientID.
+func (r *JobRepository) GetByIdempotencyKey(ctx context.Context, clientID, key string) (*entity.Job, error) {
+	q := `SELECT ` + jobColumns + ` FROM jobs WHERE client_id = $1 AND idempotency_key = $2;`
+
+	job, err := scanJob(r.db(ctx).QueryRow(ctx, q, clientID, key))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
 	}
-	return nil
+	return job, nil
 }
 
-func (r *JobRepository) SetResultDone(ctx context.Context, id uuid.UUID, output json.RawMessage) error {
-	if len(output) == 0 {
-		output = json.RawMessage(`{}`)
+// GetActiveByUniqueKey returns pending/processing job of type typ with unique key key.
+func (r *JobRepository) GetActiveByUniqueKey(ctx context.Context, typ, key string) (*entity.Job, error) {
+	q := `
+SELECT ` + jobColumns + `
+FROM jobs
+WHERE type = $1 AND unique_key = $2 AND status IN ('pending', 'processing');
+`
+	job, err := scanJob(r.db(ctx).QueryRow(ctx, q, typ, key))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, entity.ErrNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListStale returns jobs in status that were not updated since updatedBefore, ordered by (updated_at, id).
+// Keyset: after — последняя job предыдущей страницы (nil — с начала).
+// Job, ещё ожидающие публикации в outbox, не считаются "зависшими".
+func (r *JobRepository) ListStale(ctx context.Context, status entity.JobStatus, updatedBefore time.Time, after *entity.StaleCursor, limit int) ([]*entity.Job, error) {
+	args := []any{string(status), updatedBefore, limit}
+	keyset := ""
+	if after != nil {
+		keyset = `AND (updated_at, id) > ($4, $5)`
+		args = append(args, after.UpdatedAt, after.ID)
+	}
+	q := `
+SELECT ` + jobColumns + `
+FROM jobs
+WHERE status = $1
+  AND updated_at < $2
+  ` + keyset + `
+  AND NOT EXISTS (SELECT 1 FROM job_outbox o WHERE o.job_id = jobs.id)
+ORDER BY updated_at, id
+LIMIT $3;
+`
+	rows, err := r.db(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// List returns jobs matching f ordered by (created_at, id) in f.Order, at most f.Limit.
+// Keyset pagination: f.After — (created_at, id) последней job предыдущей страницы.
+func (r *JobRepository) List(ctx context.Context, f entity.JobFilter) ([]*entity.Job, error) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, st := range f.Statuses {
+			statuses[i] = string(st)
+		}
+		where = append(where, "status = ANY("+arg(statuses)+")")
+	}
+	if len(f.Types) > 0 {
+		where = append(where, "type = ANY("+arg(f.Types)+")")
+	}
+	if f.Priority != nil {
+		where = append(where, "priority = "+arg(*f.Priority))
+	}
+	if f.CreatedFrom != nil {
+		where = append(where, "created_at >= "+arg(*f.CreatedFrom))
+	}
+	if f.CreatedTo != nil {
+		where = append(where, "created_at < "+arg(*f.CreatedTo))
+	}
+	if len(f.Tags) > 0 {
+		where = append(where, "tags @> "+arg(f.Tags)) // GIN idx_jobs_tags
+	}
+	if f.ParentID != nil {
+		where = append(where, "parent_id = "+arg(*f.ParentID))
+	}
+
+	cmp, dir := "<", "DESC"
+	if f.Order == entity.SortAsc {
+		cmp, dir = ">", "ASC"
+	}
+	if f.After != nil {
+		where = append(where, "(created_at, id) "+cmp+" ("+arg(f.After.CreatedAt)+", "+arg(f.After.ID)+")")
+	}
+
+	q := `SELECT ` + jobColumns + ` FROM jobs`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += ` ORDER BY created_at ` + dir + `, id ` + dir + ` LIMIT ` + arg(f.Limit) + `;`
+
+	rows, err := r.db(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+func scanJobs(rows pgx.Rows) ([]*entity.Job, error) {
+	defer rows.Close()
+
+	var out []*entity.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, job)
+	}
+	return out, rows.Err()
+}
+
+// SetProgress сохраняет прогресс processing job и публикует его подписчикам (NOTIFY job_events).
+// ErrStaleAttempt / ErrInvalidTransition — попытку уже взяли заново или job уже не processing.
+func (r *JobRepository) SetProgress(ctx context.Context, id uuid.UUID, token int64, p entity.JobProgress) error {
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now().UTC()
+	}
+	progress, err := json.Marshal(p)
+	if err != nil {
+		return err
 	}
-	const q = `UPDATE jobs SET status='done', output=$2, error=NULL WHERE id=$1;`
 
-	tag, err := r.pool.Exec(ctx, q, id, output)
+	const q = `
+WITH upd AS (
+    UPDATE jobs SET progress = $2
+    WHERE id = $1 AND status = 'processing' AND fence_token = $7
+    RETURNING id, type, status, attempts
+)
+SELECT pg_notify('job_events', json_build_object(
+    'kind', 'progress',
+    'job_id', id,
+    'type', type,
+    'status', status,
+    'attempts', attempts,
+    'progress', $3::int,
+    'stage', left($4::text, 200),
+    'message', left($5::text, 1000), -- payload NOTIFY ограничен 8000 байт
+    'at', $6::timestamptz
+)::text)
+FROM upd;
+`
+	tag, err := r.db(ctx).Exec(ctx, q, id, progress, p.Percent, p.Stage, p.Message, p.UpdatedAt, token)
 	if err != nil {
 		return err
 	}
 	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+		return r.fenceErr(ctx, id, &token, entity.ErrNotFound)
 	}
 	return nil
 }
 
-func (r *JobRepository) SetResultError(ctx context.Context, id uuid.UUID, errText string) error {
-	const q = `UPDATE jobs SET status='error', error=$2 WHERE id=$1;`
+// StartAttempt переводит pending (или processing — lease прошлой попытки истёк) job в processing,
+// увеличивает счётчик попыток, сбрасывает прогресс, ставит lease_until на lease и возвращает новый fencing token.
+// Завершённую или отменённую job не трогает (ErrInvalidTransition): повторная доставка не вернёт done в processing.
+// Processing job с живым lease — тоже ErrInvalidTransition: её выполняет другой worker. Lease в БД пишется
+// при любом backend'е очереди (у Redis-очередей свой lease в Redis), продлевает его ExtendLease.
+func (r *JobRepository) StartAttempt(ctx context.Context, id uuid.UUID, lease time.Duration) (int64, error) {
+	const set = `status='processing', attempts=attempts+1, fence_token=j.fence_token+1, progress=NULL,
+        lease_until=now() + make_interval(secs => $4)`
+
+	_, token, err := r.transition(ctx, id, set, startFrom, "", lease.Seconds())
+	return token, r.fenceErr(ctx, id, nil, err)
+}
 
-	tag, err := r.pool.Exec(ctx, q, id, errText)
+// ExtendLease продлевает lease попытки token на lease от текущего момента.
+// ErrStaleAttempt / ErrInvalidTransition — попытку уже взяли заново или job уже не processing.
+func (r *JobRepository) ExtendLease(ctx context.Context, id uuid.UUID, token int64, lease time.Duration) error {
+	const q = `
+UPDATE jobs SET lease_until = now() + make_interval(secs => $3)
+WHERE id = $1 AND status = 'processing' AND fence_token = $2;
+`
+	tag, err := r.db(ctx).Exec(ctx, q, id, token, lease.Seconds())
 	if err != nil {
 		return err
 	}
 	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+		return r.fenceErr(ctx, id, &token, entity.ErrNotFound)
 	}
 	return nil
 }
+
+// SetRetry возвращает job в pending после неудачной попытки, сохраняя текст и класс последней ошибки
+// и время следующей попытки.
+func (r *JobRepository) SetRetry(ctx context.Context, id uuid.UUID, token int64, class entity.ErrorClass, errText string, runAt time.Time) error {
+	_, _, err := r.transition(ctx, id, `status='pending', error=$3, error_class=$5, run_at=$6`,
+		retryFrom+` AND j.fence_token = $4`, errText, token, string(class), runAt,
+	)
+	return r.fenceErr(ctx, id, &token, err)
+}
+
+// ReleaseAttempt возвращает прерванную попытку (остановка worker'а) в pending, не засчитывая её.
+func (r *JobRepository) ReleaseAttempt(ctx context.Context, id uuid.UUID, token int64) error {
+	_, _, err := r.transition(ctx, id, `status='pending', attempts=GREATEST(attempts-1, 0), progress=NULL`,
+		retryFrom+` AND j.fence_token = $4`, "attempt released", token,
+	)
+	return r.fenceErr(ctx, id, &token, err)
+}
+
+// SetDead фиксирует job как dead (попытки исчерпаны).
+func (r *JobRepository) SetDead(ctx context.Context, id uuid.UUID, token int64, class entity.ErrorClass, errText string) error {
+	_, _, err := r.transition(ctx, id, `status='dead', error=$3, error_class=$5`,
+		deadFrom+` AND j.fence_token = $4`, errText, token, string(class),
+	)
+	return r.fenceErr(ctx, id, &token, err)
+}
+
+// ResetToPending сбрасывает упавшую или отменённую job для повторного запуска "с нуля" с priority.
+// entity.ErrInvalidTransition — job в другом статусе; entity.ErrUniqueJobExists — уже есть активная job с тем же unique_key.
+func (r *JobRepository) ResetToPending(ctx context.Context, id uuid.UUID, priority int) error {
+	const set = `status='pending', attempts=0, output=NULL, error=NULL, error_class=NULL, run_at=NULL, progress=NULL, priority=$4`
+
+	_, _, err := r.transition(ctx, id, set, resetFrom, "", priority)
+	return r.fenceErr(ctx, id, nil, uniqueErr(err))
+}
+
+func (r *JobRepository) SetResultDone(ctx context.Context, id uuid.UUID, token int64, output json.RawMessage) error {
+	if len(output) == 0 {
+		output = json.RawMessage(`{}`)
+	}
+	_, _, err := r.transition(ctx, id, `status='done', output=$5, error=NULL, error_class=NULL`,
+		doneFrom+` AND j.fence_token = $4`, "", token, output,
+	)
+	return r.fenceErr(ctx, id, &token, err)
+}
+
+// SetResultError фиксирует job как error: после попытки (processing) или потерянную в очереди (pending).
+func (r *JobRepository) SetResultError(ctx context.Context, id uuid.UUID, token int64, errText string) error {
+	_, _, err := r.transition(ctx, id, `status='error', error=$3, error_class='error'`,
+		errorFrom+` AND j.fence_token = $4`, errText, token,
+	)
+	return r.fenceErr(ctx, id, &token, err)
+}
+
+// Cancel переводит pending/processing job в canceled и возвращает статус до отмены.
+// entity.ErrNotFound — job нет или она уже в терминальном статусе.
+func (r *JobRepository) Cancel(ctx context.Context, id uuid.UUID) (entity.JobStatus, error) {
+	from, _, err := r.transition(ctx, id, `status='canceled'`, cancelFrom, "")
+	return from, err
+}
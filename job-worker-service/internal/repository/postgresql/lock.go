@@ -0,0 +1,44 @@
+package postgresql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLock — pg_try_advisory_lock(key) на отдельном соединении пула: пока lock взят,
+// его не получит ни один другой процесс (например, проход reconciler'а среди реплик worker'а).
+// Реализует service.PassLock.
+type AdvisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+}
+
+func NewAdvisoryLock(pool *pgxpool.Pool, key int64) *AdvisoryLock {
+	return &AdvisoryLock{pool: pool, key: key}
+}
+
+// TryLock не ждёт: ok=false — lock держит другой процесс. unlock снимает lock и возвращает соединение в пул.
+func (l *AdvisoryLock) TryLock(ctx context.Context) (unlock func(), ok bool, err error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1);`, l.key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	return func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1);`, l.key); err != nil {
+			// соединение с невыясненным lock в пул не возвращаем: закрытие сессии снимет lock
+			_ = conn.Hijack().Close(context.WithoutCancel(ctx))
+			return
+		}
+		conn.Release()
+	}, true, nil
+}
@@ -0,0 +1,178 @@
+package postgresql
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"job-worker-service/internal/entity"
+)
+
+// outboxBackoff — задержка повторной публикации, если очередь недоступна.
+var outboxBackoff = entity.RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+
+// Outbox — transactional outbox перед Redis-очередью.
+// Реализует service.JobQueue: Enqueue/EnqueueAt пишут строку job_outbox через conn(ctx),
+// т.е. внутри TxManager.WithinTx — в одной транзакции с insert в jobs (или со сменой статуса: retry, release).
+// Relay публикует строки в настоящую очередь и удаляет их.
+type Outbox struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutbox(pool *pgxpool.Pool) *Outbox {
+	return &Outbox{pool: pool}
+}
+
+func (o *Outbox) Enqueue(ctx context.Context, jobID string, priority int) error {
+	return o.add(ctx, jobID, priority, nil)
+}
+
+func (o *Outbox) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	return o.add(ctx, jobID, priority, &at)
+}
+
+func (o *Outbox) add(ctx context.Context, jobID string, priority int, runAt *time.Time) error {
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO job_outbox (job_id, priority, run_at) VALUES ($1, $2, $3);`
+
+	_, err = conn(ctx, o.pool).Exec(ctx, q, id, priority, runAt)
+	return err
+}
+
+// Publisher — настоящая очередь, в которую Relay публикует строки outbox (реализация: service.RedisQueue / RedisStreamQueue).
+type Publisher interface {
+	Enqueue(ctx context.Context, jobID string, priority int) error
+	EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error
+}
+
+type outboxEntry struct {
+	id       int64
+	jobID    uuid.UUID
+	priority int
+	runAt    *time.Time
+	attempts int
+}
+
+// Relay публикует до limit готовых строк outbox в queue; возвращает число опубликованных.
+// Строки сначала забираются (claim) отдельной короткой транзакцией: next_attempt_at сдвигается на outboxClaimTTL,
+// поэтому другие relay (реплики app и worker'а) их не видят, а блокировки не держатся, пока идёт публикация в Redis.
+// Опубликованная строка удаляется, неудачная — откладывается по backoff.
+// Если relay упал между claim и удалением, строку опубликует повторно следующий relay после outboxClaimTTL —
+// дубль в очереди возможен только так.
+func (o *Outbox) Relay(ctx context.Context, queue Publisher, limit int) (int, error) {
+	entries, err := o.claim(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, e := range entries {
+		if pubErr := publish(ctx, queue, e); pubErr != nil {
+			delay := outboxBackoff.Backoff(e.attempts + 1)
+			log.Printf("[outbox] job_id=%s publish error=%v attempt=%d retry_in_ms=%d",
+				e.jobID.String(), pubErr, e.attempts+1, delay.Milliseconds(),
+			)
+
+			const upd = `
+UPDATE job_outbox
+SET attempts = attempts + 1, last_error = $2, next_attempt_at = now() + make_interval(secs => $3)
+WHERE id = $1;
+`
+			if _, err := o.pool.Exec(ctx, upd, e.id, pubErr.Error(), delay.Seconds()); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if _, err := o.pool.Exec(ctx, `DELETE FROM job_outbox WHERE id = $1;`, e.id); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// RelayBatch — сколько строк outbox Run публикует за один Relay.
+const RelayBatch = 100
+
+// Run публикует outbox в queue каждые interval, пока не отменён ctx. Полный batch — сразу следующий, без ожидания тика.
+// Relay запускают и app, и worker (оба пишут в outbox): claim разводит их по строкам.
+func (o *Outbox) Run(ctx context.Context, queue Publisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := o.Relay(ctx, queue, RelayBatch)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("outbox relay error: %v", err)
+					}
+					break
+				}
+				if n < RelayBatch {
+					break
+				}
+			}
+		}
+	}
+}
+
+// outboxClaimTTL — на сколько claim скрывает строки от других relay (lease на время публикации batch'а).
+const outboxClaimTTL = 30 * time.Second
+
+// claim забирает до limit готовых строк в порядке id и сразу commit'ит: строки "взяты" до now() + outboxClaimTTL.
+func (o *Outbox) claim(ctx context.Context, limit int) ([]outboxEntry, error) {
+	const q = `
+WITH ready AS (
+    SELECT id
+    FROM job_outbox
+    WHERE next_attempt_at <= now()
+    ORDER BY id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE job_outbox o
+SET next_attempt_at = now() + make_interval(secs => $2)
+FROM ready
+WHERE o.id = ready.id
+RETURNING o.id, o.job_id, o.priority, o.run_at, o.attempts;
+`
+	rows, err := o.pool.Query(ctx, q, limit, outboxClaimTTL.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (outboxEntry, error) {
+		var e outboxEntry
+		err := row.Scan(&e.id, &e.jobID, &e.priority, &e.runAt, &e.attempts)
+		return e, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// RETURNING порядок не гарантирует
+	slices.SortFunc(entries, func(a, b outboxEntry) int { return cmp.Compare(a.id, b.id) })
+	return entries, nil
+}
+
+func publish(ctx context.Context, queue Publisher, e outboxEntry) error {
+	if e.runAt != nil {
+		// EnqueueAt сам ставит сразу, если время уже наступило
+		return queue.EnqueueAt(ctx, e.jobID.String(), e.priority, *e.runAt)
+	}
+	return queue.Enqueue(ctx, e.jobID.String(), e.priority)
+}
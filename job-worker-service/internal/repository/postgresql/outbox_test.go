@@ -0,0 +1,144 @@
+package postgresql_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"job-worker-service/internal/repository/postgresql"
+)
+
+// publisherStub — очередь для Relay; onPublish вызывается внутри публикации.
+type publisherStub struct {
+	published []string
+	err       error
+	onPublish func(jobID string)
+}
+
+func (p *publisherStub) Enqueue(ctx context.Context, jobID string, priority int) error {
+	return p.publish(jobID)
+}
+
+func (p *publisherStub) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	return p.publish(jobID)
+}
+
+func (p *publisherStub) publish(jobID string) error {
+	if p.onPublish != nil {
+		p.onPublish(jobID)
+	}
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, jobID)
+	return nil
+}
+
+func outboxRows(t *testing.T, pool *pgxpool.Pool, jobID uuid.UUID) (rows, attempts int) {
+	t.Helper()
+	const q = `SELECT count(*), coalesce(max(attempts), 0) FROM job_outbox WHERE job_id = $1;`
+	if err := pool.QueryRow(context.Background(), q, jobID).Scan(&rows, &attempts); err != nil {
+		t.Fatalf("outbox rows: %v", err)
+	}
+	return rows, attempts
+}
+
+func TestOutbox_EnqueueIsPartOfTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo, pool := newTestRepo(t)
+	id := createJob(t, repo, pool)
+	outbox := postgresql.NewOutbox(pool)
+
+	rollback := errors.New("rollback")
+	err := postgresql.NewTxManager(pool).WithinTx(ctx, func(ctx context.Context) error {
+		if err := outbox.Enqueue(ctx, id.String(), 1); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback, got %v", err)
+	}
+	if n, _ := outboxRows(t, pool, id); n != 0 {
+		t.Fatalf("expected no outbox row after rollback, got %d", n)
+	}
+
+	if err := outbox.EnqueueAt(ctx, id.String(), 1, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("enqueue at: %v", err)
+	}
+	if n, _ := outboxRows(t, pool, id); n != 1 {
+		t.Fatalf("expected one outbox row, got %d", n)
+	}
+}
+
+func TestOutbox_RelayPublishesOutsideLockAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	repo, pool := newTestRepo(t)
+	id := createJob(t, repo, pool)
+	outbox := postgresql.NewOutbox(pool)
+
+	if err := outbox.Enqueue(ctx, id.String(), 1); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	var nested []string
+	pub := &publisherStub{onPublish: func(jobID string) {
+		if jobID != id.String() {
+			return
+		}
+		// claim уже закоммичен: строка не заблокирована, но другой relay её не видит
+		const q = `SELECT 1 FROM job_outbox WHERE job_id = $1 FOR UPDATE NOWAIT;`
+		if _, err := pool.Exec(ctx, q, id); err != nil {
+			t.Errorf("expected claimed row unlocked during publish, got %v", err)
+		}
+		other := &publisherStub{}
+		if _, err := outbox.Relay(ctx, other, 100); err != nil {
+			t.Errorf("nested relay: %v", err)
+		}
+		nested = append(nested, other.published...)
+	}}
+
+	if _, err := outbox.Relay(ctx, pub, 100); err != nil {
+		t.Fatalf("relay: %v", err)
+	}
+	if !slices.Contains(pub.published, id.String()) {
+		t.Fatalf("expected job published, got %v", pub.published)
+	}
+	if slices.Contains(nested, id.String()) {
+		t.Fatalf("expected claimed row hidden from another relay, got %v", nested)
+	}
+	if n, _ := outboxRows(t, pool, id); n != 0 {
+		t.Fatalf("expected outbox row deleted after publish, got %d", n)
+	}
+}
+
+func TestOutbox_FailedPublishBacksOff(t *testing.T) {
+	ctx := context.Background()
+	repo, pool := newTestRepo(t)
+	id := createJob(t, repo, pool)
+	outbox := postgresql.NewOutbox(pool)
+
+	if err := outbox.Enqueue(ctx, id.String(), 1); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := outbox.Relay(ctx, &publisherStub{err: errors.New("redis down")}, 100); err != nil {
+		t.Fatalf("relay: %v", err)
+	}
+	if n, attempts := outboxRows(t, pool, id); n != 1 || attempts != 1 {
+		t.Fatalf("expected row kept with attempts=1, got rows=%d attempts=%d", n, attempts)
+	}
+
+	// следующая публикация — только после backoff
+	pub := &publisherStub{}
+	if _, err := outbox.Relay(ctx, pub, 100); err != nil {
+		t.Fatalf("relay: %v", err)
+	}
+	if slices.Contains(pub.published, id.String()) {
+		t.Fatalf("expected row delayed by backoff, got %v", pub.published)
+	}
+}
@@ -0,0 +1,291 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"job-worker-service/internal/entity"
+)
+
+// claimPollInterval — как часто ClaimBlocking опрашивает таблицу, если pending job нет.
+const claimPollInterval = 500 * time.Millisecond
+
+type QueueConfig struct {
+	LeaseTTL time.Duration
+}
+
+// Queue implements service.Queue on the jobs table itself (QUEUE_BACKEND=postgres, без Redis).
+// Очередь: строки jobs со status='pending', lease_until IS NULL и наступившим run_at.
+// Claim:   SELECT ... ORDER BY priority DESC, created_at FOR UPDATE SKIP LOCKED (idx_jobs_priority_created_at)
+// и lease_until/lease_token; статус меняет Processor (StartAttempt), как и для Redis.
+// Lease:   lease_until, продлевается Heartbeat только владельцем lease_token.
+// Ack:     снимает lease.
+// Reaper:  снимает истёкшие lease и возвращает processing -> pending.
+// Отложенные job ждут в той же таблице (run_at), поэтому PromoteDue ничего не делает.
+// Отмена: статус canceled в самой строке — claim её не видит, worker'ы узнают об отмене по статусу.
+// Enqueue пишет через conn(ctx): внутри TxManager.WithinTx создание job и enqueue — одна транзакция.
+type Queue struct {
+	*DeadLetters
+
+	pool     *pgxpool.Pool
+	leaseTTL time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]uuid.UUID // job_id -> lease_token этого процесса
+}
+
+func NewQueue(pool *pgxpool.Pool, cfg QueueConfig) *Queue {
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = entity.DefaultLeaseTTL
+	}
+	return &Queue{
+		DeadLetters: NewDeadLetters(pool),
+		pool:        pool,
+		leaseTTL:    cfg.LeaseTTL,
+		inflight:    map[string]uuid.UUID{},
+	}
+}
+
+func (q *Queue) LeaseTTL() time.Duration {
+	return q.leaseTTL
+}
+
+// Enqueue делает pending job доступной для claim (сама строка jobs и есть элемент очереди).
+// priority уже хранится в jobs.priority.
+func (q *Queue) Enqueue(ctx context.Context, jobID string, priority int) error {
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		return err
+	}
+
+	const sql = `UPDATE jobs SET lease_until = NULL, lease_token = NULL WHERE id = $1 AND status = 'pending';`
+
+	tag, err := conn(ctx, q.pool).Exec(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+func (q *Queue) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		return err
+	}
+
+	const sql = `UPDATE jobs SET run_at = $2, lease_until = NULL, lease_token = NULL WHERE id = $1 AND status = 'pending';`
+
+	tag, err := conn(ctx, q.pool).Exec(ctx, sql, id, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrNotFound
+	}
+	return nil
+}
+
+// PromoteDue — no-op: claim сам проверяет run_at <= now().
+func (q *Queue) PromoteDue(ctx context.Context, maxPerLane int64) (int64, error) {
+	return 0, nil
+}
+
+func (q *Queue) ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error) {
+	// if timeout <= 0, loop forever (like a worker daemon)
+	forever := timeout <= 0
+	deadline := time.Now().Add(timeout)
+
+	for {
+		id, err := q.Claim(ctx)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+
+		wait := claimPollInterval
+		if !forever {
+			remain := time.Until(deadline)
+			if remain <= 0 {
+				return "", pgx.ErrNoRows
+			}
+			if remain < wait {
+				wait = remain
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Claim leases the next due pending job (high -> low, FIFO within priority); pgx.ErrNoRows if none.
+// SKIP LOCKED: конкурентные worker'ы не ждут друг друга и не получают одну и ту же строку.
+func (q *Queue) Claim(ctx context.Context) (string, error) {
+	const sql = `
+WITH next AS (
+    SELECT id
+    FROM jobs
+    WHERE status = 'pending'
+      AND lease_until IS NULL
+      AND (run_at IS NULL OR run_at <= now())
+    ORDER BY priority DESC, created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE jobs j
+SET lease_until = now() + make_interval(secs => $1), lease_token = $2
+FROM next
+WHERE j.id = next.id
+RETURNING j.id;
+`
+	token := uuid.New()
+
+	var id uuid.UUID
+	if err := conn(ctx, q.pool).QueryRow(ctx, sql, q.leaseTTL.Seconds(), token).Scan(&id); err != nil {
+		return "", err
+	}
+
+	q.mu.Lock()
+	q.inflight[id.String()] = token
+	q.mu.Unlock()
+
+	return id.String(), nil
+}
+
+func (q *Queue) token(jobID string) (uuid.UUID, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.inflight[jobID]
+	return t, ok
+}
+
+func (q *Queue) Heartbeat(ctx context.Context, jobID string) error {
+	token, ok := q.token(jobID)
+	if !ok {
+		return entity.ErrLeaseLost
+	}
+
+	const sql = `
+UPDATE jobs SET lease_until = now() + make_interval(secs => $3)
+WHERE id = $1 AND lease_token = $2;
+`
+	tag, err := conn(ctx, q.pool).Exec(ctx, sql, jobID, token, q.leaseTTL.Seconds())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *Queue) Ack(ctx context.Context, jobID string) error {
+	token, ok := q.token(jobID)
+	if !ok {
+		return nil
+	}
+
+	const sql = `UPDATE jobs SET lease_until = NULL, lease_token = NULL WHERE id = $1 AND lease_token = $2;`
+
+	if _, err := conn(ctx, q.pool).Exec(ctx, sql, jobID, token); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	delete(q.inflight, jobID)
+	q.mu.Unlock()
+	return nil
+}
+
+// RequeueStale снимает истёкшие lease (worker упал/завис); job в processing снова становится pending
+// с новым fencing token'ом (переход пишется в историю job с actor "reaper").
+func (q *Queue) RequeueStale(ctx context.Context, limit int64) (int64, error) {
+	const sql = `
+WITH stale AS (
+    SELECT id, status
+    FROM jobs
+    WHERE lease_until < now()
+    ORDER BY lease_until
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+), upd AS (
+    UPDATE jobs j
+    SET lease_until = NULL,
+        lease_token = NULL,
+        status = CASE WHEN stale.status = 'processing' THEN 'pending' ELSE stale.status END,
+        -- результат зависшей попытки больше не запишется (ErrStaleAttempt)
+        fence_token = CASE WHEN stale.status = 'processing' THEN j.fence_token + 1 ELSE j.fence_token END
+    FROM stale
+    WHERE j.id = stale.id
+    RETURNING j.id, stale.status AS from_status, j.status, j.attempts
+), ev AS (
+    INSERT INTO job_events (job_id, from_status, to_status, attempt, actor, detail)
+    SELECT id, from_status, status, attempts, $2, 'lease expired' FROM upd WHERE from_status = 'processing'
+)
+SELECT count(*) FROM upd;
+`
+	var n int64
+	if err := conn(ctx, q.pool).QueryRow(ctx, sql, limit, entity.ActorReaper).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Remove ничего не делает: claim берёт только status='pending', отменённая job уже не в очереди.
+func (q *Queue) Remove(ctx context.Context, jobID string) error {
+	return nil
+}
+
+// SignalCancel ничего не делает: сигнал — сам статус canceled (CancelRequested читает его).
+func (q *Queue) SignalCancel(ctx context.Context, jobID string) error {
+	return nil
+}
+
+// ClearCancel ничего не делает: сигнал пропадает вместе со статусом canceled.
+func (q *Queue) ClearCancel(ctx context.Context, jobID string) error {
+	return nil
+}
+
+// CancelRequested returns ids from jobIDs whose status is canceled.
+func (q *Queue) CancelRequested(ctx context.Context, jobIDs []string) ([]string, error) {
+	ids := make([]uuid.UUID, 0, len(jobIDs))
+	for _, s := range jobIDs {
+		if id, err := uuid.Parse(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	const sql = `SELECT id FROM jobs WHERE id = ANY($1) AND status = 'canceled';`
+
+	rows, err := conn(ctx, q.pool).Query(ctx, sql, ids)
+	if err != nil {
+		return nil, err
+	}
+	canceled, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, len(canceled))
+	for i, id := range canceled {
+		out[i] = id.String()
+	}
+	return out, nil
+}
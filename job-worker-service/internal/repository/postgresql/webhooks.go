@@ -0,0 +1,224 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"job-worker-service/internal/entity"
+)
+
+// WebhookRepository — реализация service.WebhookRepository.
+// Строки webhook_deliveries создаёт trigger jobs_enqueue_webhook (migrations/013_webhooks.sql).
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+const deliveryColumns = `id, job_id, job_status, url, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+func scanDelivery(row pgx.CollectableRow) (entity.WebhookDelivery, error) {
+	var (
+		d         entity.WebhookDelivery
+		jobStatus string
+		status    string
+		lastError *string
+	)
+	if err := row.Scan(
+		&d.ID,
+		&d.JobID,
+		&jobStatus,
+		&d.URL,
+		&status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&lastError, // NULL => nil
+		&d.CreatedAt,
+		&d.DeliveredAt, // NULL => nil
+	); err != nil {
+		return d, err
+	}
+	d.JobStatus = entity.JobStatus(jobStatus)
+	d.Status = entity.WebhookStatus(status)
+	if lastError != nil {
+		d.LastError = *lastError
+	}
+	return d, nil
+}
+
+// ClaimDueWebhooks: FOR UPDATE SKIP LOCKED + сдвиг next_attempt_at на lease одной командой,
+// без транзакции на время HTTP-запросов.
+func (r *WebhookRepository) ClaimDueWebhooks(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	const q = `
+UPDATE webhook_deliveries d
+SET next_attempt_at = now() + make_interval(secs => $2)
+FROM (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at, id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+) due
+WHERE d.id = due.id
+RETURNING d.id, d.job_id, d.job_status, d.url, d.status, d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.delivered_at;
+`
+	rows, err := r.pool.Query(ctx, q, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanDelivery)
+}
+
+func (r *WebhookRepository) RecordWebhookAttempt(ctx context.Context, deliveryID int64, a entity.WebhookAttempt, status entity.WebhookStatus, next time.Time) error {
+	var (
+		statusCode  *int
+		errText     *string
+		nextAt      = a.At
+		deliveredAt *time.Time
+	)
+	if a.StatusCode != 0 {
+		statusCode = &a.StatusCode
+	}
+	if a.Error != "" {
+		errText = &a.Error
+	}
+	switch status {
+	case entity.WebhookPending:
+		nextAt = next
+	case entity.WebhookDelivered:
+		deliveredAt = &a.At
+	}
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		const ins = `
+INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+`
+		if _, err := tx.Exec(ctx, ins, deliveryID, a.Attempt, statusCode, errText, a.Duration.Milliseconds(), a.At); err != nil {
+			return err
+		}
+
+		const upd = `
+UPDATE webhook_deliveries
+SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6
+WHERE id = $1;
+`
+		tag, err := tx.Exec(ctx, upd, deliveryID, string(status), a.Attempt, nextAt, errText, deliveredAt)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return entity.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ListWebhookDeliveries returns deliveries of job (oldest first) with their attempts.
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, jobID uuid.UUID) ([]entity.WebhookDelivery, error) {
+	q := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE job_id = $1 ORDER BY id;`
+
+	rows, err := r.pool.Query(ctx, q, jobID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := pgx.CollectRows(rows, scanDelivery)
+	if err != nil || len(deliveries) == 0 {
+		return deliveries, err
+	}
+
+	ids := make([]int64, len(deliveries))
+	byID := make(map[int64]*entity.WebhookDelivery, len(deliveries))
+	for i := range deliveries {
+		ids[i] = deliveries[i].ID
+		byID[deliveries[i].ID] = &deliveries[i]
+	}
+
+	const qa = `
+SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+FROM webhook_attempts
+WHERE delivery_id = ANY($1)
+ORDER BY delivery_id, attempt;
+`
+	rows, err = r.pool.Query(ctx, qa, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			deliveryID int64
+			a          entity.WebhookAttempt
+			statusCode *int
+			errText    *string
+			durationMs int64
+		)
+		if err := rows.Scan(&deliveryID, &a.Attempt, &statusCode, &errText, &durationMs, &a.At); err != nil {
+			return nil, err
+		}
+		if statusCode != nil {
+			a.StatusCode = *statusCode
+		}
+		if errText != nil {
+			a.Error = *errText
+		}
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+
+		d := byID[deliveryID]
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) GetWebhookSettings(ctx context.Context, clientID string) (*entity.WebhookSettings, error) {
+	const q = `SELECT client_id, url, secret, updated_at FROM webhook_settings WHERE client_id = $1;`
+
+	var (
+		s           entity.WebhookSettings
+		url, secret *string
+	)
+	if err := r.pool.QueryRow(ctx, q, clientID).Scan(&s.ClientID, &url, &secret, &s.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, entity.ErrWebhookSettingsNotFound
+		}
+		return nil, err
+	}
+	if url != nil {
+		s.URL = *url
+	}
+	if secret != nil {
+		s.Secret = *secret
+	}
+	return &s, nil
+}
+
+func (r *WebhookRepository) PutWebhookSettings(ctx context.Context, s entity.WebhookSettings) error {
+	const q = `
+INSERT INTO webhook_settings (client_id, url, secret, updated_at)
+VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+ON CONFLICT (client_id) DO UPDATE
+SET url = EXCLUDED.url, secret = EXCLUDED.secret, updated_at = EXCLUDED.updated_at;
+`
+	_, err := r.pool.Exec(ctx, q, s.ClientID, s.URL, s.Secret, s.UpdatedAt)
+	return err
+}
+
+func (r *WebhookRepository) DeleteWebhookSettings(ctx context.Context, clientID string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_settings WHERE client_id = $1;`, clientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrWebhookSettingsNotFound
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	// ErrNotCancelable — job уже завершена (done / error / dead / canceled).
+	ErrNotCancelable = errors.New("job is already finished")
+)
+
+// Порт репозитория для отмены (реализация: postgresql.JobRepository)
+type CancelRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+	// Cancel переводит pending/processing job в canceled и возвращает статус до отмены;
+	// entity.ErrNotFound, если job нет или она уже завершена.
+	Cancel(ctx context.Context, id uuid.UUID) (entity.JobStatus, error)
+}
+
+// CancelQueue — порт очереди для отмены (реализация: QueueBackend, не outbox).
+type CancelQueue interface {
+	Remove(ctx context.Context, jobID string) error
+	SignalCancel(ctx context.Context, jobID string) error
+}
+
+type CancelService struct {
+	repo  CancelRepository
+	queue CancelQueue
+}
+
+func NewCancelService(repo CancelRepository, queue CancelQueue) *CancelService {
+	return &CancelService{repo: repo, queue: queue}
+}
+
+// Cancel отменяет job:
+//   - pending: статус canceled + удаление из очереди;
+//   - processing: статус canceled + сигнал worker'у, он отменит ctx handler'а.
+//
+// Статус в БД меняется первым и он терминальный: даже если очередь недоступна, worker не начнёт
+// отменённую job (StartAttempt её пропускает) и не перезапишет canceled результатом попытки.
+func (s *CancelService) Cancel(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cancelable(job.Status) {
+		return nil, ErrNotCancelable
+	}
+
+	prev, err := s.repo.Cancel(ctx, id)
+	if err != nil {
+		// job могла завершиться между GetByID и Cancel
+		if cur, getErr := s.repo.GetByID(ctx, id); getErr == nil && !cancelable(cur.Status) {
+			return nil, ErrNotCancelable
+		}
+		return nil, err
+	}
+
+	jobID := id.String()
+	switch prev {
+	case entity.StatusPending:
+		if err := s.queue.Remove(ctx, jobID); err != nil {
+			// не критично: worker пропустит canceled job, если всё же возьмёт её
+			log.Printf("[cancel] job_id=%s remove from queue error=%v", jobID, err)
+		}
+	case entity.StatusProcessing:
+		if err := s.queue.SignalCancel(ctx, jobID); err != nil {
+			// handler доработает впустую, но его результат не перезапишет canceled
+			log.Printf("[cancel] job_id=%s signal worker error=%v", jobID, err)
+		}
+	}
+	log.Printf("[cancel] job_id=%s status=canceled prev_status=%s", jobID, prev)
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func cancelable(st entity.JobStatus) bool {
+	return st == entity.StatusPending || st == entity.StatusProcessing
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cancelSignalTTL — сколько живёт сигнал отмены: worker увидит его за ~секунду,
+// TTL нужен только чтобы ключи не копились.
+const cancelSignalTTL = 24 * time.Hour
+
+// redisCancelSignals — сигналы отмены в Redis, общие для всех Redis-бэкендов:
+// ключ prefix:<job_id>, worker'ы опрашивают их MGET'ом по своим выполняемым job.
+type redisCancelSignals struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func newRedisCancelSignals(rdb *redis.Client, prefix string) redisCancelSignals {
+	if prefix == "" {
+		prefix = "jobs:cancel"
+	}
+	return redisCancelSignals{rdb: rdb, prefix: prefix}
+}
+
+func (c redisCancelSignals) key(jobID string) string {
+	return c.prefix + ":" + jobID
+}
+
+func (c redisCancelSignals) SignalCancel(ctx context.Context, jobID string) error {
+	return c.rdb.Set(ctx, c.key(jobID), 1, cancelSignalTTL).Err()
+}
+
+func (c redisCancelSignals) ClearCancel(ctx context.Context, jobID string) error {
+	return c.rdb.Del(ctx, c.key(jobID)).Err()
+}
+
+// CancelRequested returns ids from jobIDs that have a cancel signal.
+func (c redisCancelSignals) CancelRequested(ctx context.Context, jobIDs []string) ([]string, error) {
+	if len(jobIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(jobIDs))
+	for i, id := range jobIDs {
+		keys[i] = c.key(id)
+	}
+	vals, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for i, v := range vals {
+		if v != nil {
+			out = append(out, jobIDs[i])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// ErrNotRequeueable — запись из dead-letter нельзя вернуть в очередь
+// (id не UUID или job нет в БД / она не в терминальном статусе).
+var ErrNotRequeueable = errors.New("dead letter can not be requeued")
+
+// Порт репозитория для разбора dead-letter (реализация: postgresql.JobRepository)
+type DeadLetterRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+}
+
+// DeadJob — запись dead-letter + job из БД (nil, если id не UUID или job не найдена).
+type DeadJob struct {
+	entity.DeadLetter
+	Job *entity.Job
+}
+
+type DeadLetterService struct {
+	repo  DeadLetterRepository
+	dead  DeadLetters
+	retry *RetryService
+}
+
+// NewDeadLetterService: requeue — это ручной повтор (retry): reset + enqueue в одной транзакции через outbox.
+func NewDeadLetterService(repo DeadLetterRepository, dead DeadLetters, retry *RetryService) *DeadLetterService {
+	return &DeadLetterService{repo: repo, dead: dead, retry: retry}
+}
+
+func (s *DeadLetterService) List(ctx context.Context, offset, limit int64) ([]DeadJob, int64, error) {
+	letters, total, err := s.dead.ListDead(ctx, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]DeadJob, 0, len(letters))
+	for _, dl := range letters {
+		out = append(out, DeadJob{DeadLetter: dl, Job: s.lookupJob(ctx, dl.JobID)})
+	}
+	return out, total, nil
+}
+
+func (s *DeadLetterService) Get(ctx context.Context, jobID string) (*DeadJob, error) {
+	dl, err := s.dead.GetDead(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &DeadJob{DeadLetter: *dl, Job: s.lookupJob(ctx, jobID)}, nil
+}
+
+// Requeue сбрасывает job (pending, attempts=0) и ставит её обратно в очередь с исходным priority —
+// так же, как RetryService.Retry.
+func (s *DeadLetterService) Requeue(ctx context.Context, jobID string) error {
+	if _, err := s.dead.GetDead(ctx, jobID); err != nil {
+		return err
+	}
+
+	job := s.lookupJob(ctx, jobID)
+	if job == nil || (job.Status != entity.StatusDead && job.Status != entity.StatusError) {
+		return ErrNotRequeueable
+	}
+
+	_, err := s.retry.Retry(ctx, job.ID, nil)
+	if errors.Is(err, ErrJobNotFound) || errors.Is(err, ErrNotRetryable) {
+		return ErrNotRequeueable
+	}
+	if err != nil {
+		return err
+	}
+	// dead job Retry уже убрал из dead-letter; error job мог попасть туда только вручную
+	if err := s.dead.RemoveDead(ctx, jobID); err != nil && !errors.Is(err, entity.ErrDeadLetterNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Purge удаляет запись из dead-letter очереди (job в БД остаётся со статусом dead).
+func (s *DeadLetterService) Purge(ctx context.Context, jobID string) error {
+	return s.dead.RemoveDead(ctx, jobID)
+}
+
+func (s *DeadLetterService) PurgeAll(ctx context.Context) (int64, error) {
+	return s.dead.PurgeDead(ctx)
+}
+
+func (s *DeadLetterService) lookupJob(ctx context.Context, jobID string) *entity.Job {
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		return nil
+	}
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return job
+}
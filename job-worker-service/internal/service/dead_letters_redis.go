@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"job-worker-service/internal/entity"
+)
+
+// redisDeadLetters — dead-letter очередь в Redis, общая для всех Redis-бэкендов:
+// list key (job_id, новые первыми) + hash key:info (job_id -> reason/dead_at json).
+type redisDeadLetters struct {
+	rdb *redis.Client
+	key string
+}
+
+func (d redisDeadLetters) infoKey() string {
+	return d.key + ":info"
+}
+
+// DeadLetter кладёт job в dead-letter очередь (повторный вызов не дублирует запись).
+func (d redisDeadLetters) DeadLetter(ctx context.Context, jobID string, reason string) error {
+	info, err := json.Marshal(entity.DeadLetter{JobID: jobID, Reason: reason, DeadAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.LRem(ctx, d.key, 0, jobID)
+		p.LPush(ctx, d.key, jobID)
+		p.HSet(ctx, d.infoKey(), jobID, info)
+		return nil
+	})
+	return err
+}
+
+func (d redisDeadLetters) ListDead(ctx context.Context, offset, limit int64) ([]entity.DeadLetter, int64, error) {
+	total, err := d.rdb.LLen(ctx, d.key).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 || offset >= total {
+		return []entity.DeadLetter{}, total, nil
+	}
+
+	ids, err := d.rdb.LRange(ctx, d.key, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []entity.DeadLetter{}, total, nil
+	}
+
+	infos, err := d.rdb.HMGet(ctx, d.infoKey(), ids...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]entity.DeadLetter, 0, len(ids))
+	for i, id := range ids {
+		out = append(out, decodeDeadLetter(id, infos[i]))
+	}
+	return out, total, nil
+}
+
+func (d redisDeadLetters) GetDead(ctx context.Context, jobID string) (*entity.DeadLetter, error) {
+	info, err := d.rdb.HGet(ctx, d.infoKey(), jobID).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, entity.ErrDeadLetterNotFound
+		}
+		return nil, err
+	}
+	dl := decodeDeadLetter(jobID, info)
+	return &dl, nil
+}
+
+func (d redisDeadLetters) RemoveDead(ctx context.Context, jobID string) error {
+	var removed *redis.IntCmd
+	_, err := d.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		removed = p.LRem(ctx, d.key, 0, jobID)
+		p.HDel(ctx, d.infoKey(), jobID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if removed.Val() == 0 {
+		return entity.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (d redisDeadLetters) PurgeDead(ctx context.Context) (int64, error) {
+	var total *redis.IntCmd
+	_, err := d.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		total = p.LLen(ctx, d.key)
+		p.Del(ctx, d.key, d.infoKey())
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total.Val(), nil
+}
+
+// decodeDeadLetter tolerates missing/broken info (e.g. id pushed to dead list by hand).
+func decodeDeadLetter(jobID string, raw any) entity.DeadLetter {
+	dl := entity.DeadLetter{JobID: jobID}
+	if s, ok := raw.(string); ok {
+		_ = json.Unmarshal([]byte(s), &dl)
+		dl.JobID = jobID
+	}
+	return dl
+}
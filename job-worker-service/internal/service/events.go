@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/eventhub"
+)
+
+// EventSource — поток событий job (реализации: postgresql.Events — LISTEN/NOTIFY, memory.JobRepository).
+type EventSource interface {
+	Subscribe(ctx context.Context) (<-chan entity.JobEvent, error)
+}
+
+// Порт репозитория для событий (реализация: postgresql.JobRepository)
+type EventRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+}
+
+// EventFilter: JobID == uuid.Nil — все job; Types пусто — все типы.
+type EventFilter struct {
+	JobID uuid.UUID
+	Types []string
+}
+
+// EventService держит одну подписку на source на процесс и раздаёт события своим подписчикам.
+// Status-события done / error / dead дополняются результатом из БД (в NOTIFY он не помещается:
+// payload ограничен 8000 байт) — в горутине подписчика, а не в раздаче: медленная БД задерживает
+// только подписчиков этого события. Результат читается один раз на событие для всех подписчиков.
+type EventService struct {
+	repo   EventRepository
+	source EventSource
+
+	hub     *eventhub.Hub
+	mu      sync.Mutex
+	started bool
+
+	resultsMu sync.Mutex
+	results   map[resultKey]*loadedResult
+}
+
+// resultKey — final-событие job: повторная попытка после retry — другое событие.
+type resultKey struct {
+	jobID    uuid.UUID
+	status   entity.JobStatus
+	attempts int
+}
+
+type loadedResult struct {
+	once   sync.Once
+	output json.RawMessage
+	err    *string
+}
+
+// resultTTL — сколько загруженный результат события доступен подписчикам, которые до него ещё не дочитали.
+const resultTTL = time.Minute
+
+func NewEventService(repo EventRepository, source EventSource) *EventService {
+	return &EventService{repo: repo, source: source, hub: eventhub.New(), results: make(map[resultKey]*loadedResult)}
+}
+
+// subscribeSource подписывается на source при первом обращении; подписка живёт, пока source её не закроет.
+func (s *EventService) subscribeSource() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+
+	in, err := s.source.Subscribe(context.Background())
+	if err != nil {
+		return err
+	}
+	s.started = true
+
+	go func() {
+		defer s.hub.Close()
+		for ev := range in {
+			s.hub.Publish(ev)
+		}
+	}()
+	return nil
+}
+
+// Stream returns events matching f until ctx is done or the source stops.
+// Подписка на одну job (f.JobID) получает только её события, а не весь поток.
+func (s *EventService) Stream(ctx context.Context, f EventFilter) (<-chan entity.JobEvent, error) {
+	if err := s.subscribeSource(); err != nil {
+		return nil, err
+	}
+	var in <-chan entity.JobEvent
+	var err error
+	if f.JobID != uuid.Nil {
+		in, err = s.hub.SubscribeJob(ctx, f.JobID)
+	} else {
+		in, err = s.hub.Subscribe(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan entity.JobEvent, eventhub.Buffer)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+				continue
+			}
+			if ev.Kind == entity.EventStatus && ev.Status.Final() && ev.Status != entity.StatusCanceled {
+				s.withResult(ctx, &ev)
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Snapshot — текущее состояние job в виде status-события (первое событие GET /jobs/{id}/events).
+func (s *EventService) Snapshot(ctx context.Context, id uuid.UUID) (entity.JobEvent, error) {
+	job, err := s.getJob(ctx, id)
+	if err != nil {
+		return entity.JobEvent{}, err
+	}
+	return StatusEvent(job), nil
+}
+
+// WaitFinal returns job once it reaches a final status or wait expires (тогда — текущее состояние из БД).
+// ErrJobNotFound — job нет; ctx.Err() — вызывающий ушёл раньше (клиент отключился).
+func (s *EventService) WaitFinal(ctx context.Context, id uuid.UUID, wait time.Duration) (*entity.Job, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	// подписка до чтения job: переход между ними не потеряется
+	events, err := s.Stream(waitCtx, EventFilter{JobID: id})
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.getJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for !job.Status.Final() {
+		ev, ok := <-events
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// !ok: wait истёк или источник остановлен
+		if !ok || (ev.Kind == entity.EventStatus && ev.Status.Final()) {
+			return s.getJob(ctx, id)
+		}
+	}
+	return job, nil
+}
+
+func (s *EventService) getJob(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+// withResult дополняет final-событие результатом; подписчики одного события ждут одно чтение из БД.
+func (s *EventService) withResult(ctx context.Context, ev *entity.JobEvent) {
+	if ev.Output != nil || ev.Error != nil {
+		return
+	}
+
+	key := resultKey{jobID: ev.JobID, status: ev.Status, attempts: ev.Attempts}
+	s.resultsMu.Lock()
+	r, ok := s.results[key]
+	if !ok {
+		r = &loadedResult{}
+		s.results[key] = r
+		time.AfterFunc(resultTTL, func() {
+			s.resultsMu.Lock()
+			delete(s.results, key)
+			s.resultsMu.Unlock()
+		})
+	}
+	s.resultsMu.Unlock()
+
+	r.once.Do(func() {
+		// ушедший подписчик не должен оставить без результата остальных
+		job, err := s.repo.GetByID(context.WithoutCancel(ctx), ev.JobID)
+		if err != nil || job.Status != ev.Status {
+			return
+		}
+		r.output, r.err = resultOf(job)
+	})
+	ev.Output, ev.Error = r.output, r.err
+}
+
+// StatusEvent builds status event from job's current state.
+func StatusEvent(job *entity.Job) entity.JobEvent {
+	ev := entity.JobEvent{
+		Kind:     entity.EventStatus,
+		JobID:    job.ID,
+		Type:     job.Type,
+		Status:   job.Status,
+		Attempts: job.Attempts,
+		At:       job.UpdatedAt,
+	}
+	if job.Status.Final() {
+		ev.Output, ev.Error = resultOf(job)
+	}
+	if p := job.Progress; p != nil && job.Status == entity.StatusProcessing {
+		ev.Progress = &p.Percent
+		ev.Stage = p.Stage
+		ev.Message = p.Message
+	}
+	return ev
+}
+
+func resultOf(job *entity.Job) (json.RawMessage, *string) {
+	switch job.Status {
+	case entity.StatusDone:
+		return job.Output, nil
+	case entity.StatusError, entity.StatusDead:
+		return nil, job.Error
+	}
+	return nil, nil
+}
@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/memory"
+	"job-worker-service/internal/service"
+)
+
+// countingRepo считает чтения job (дополнение final-события результатом).
+type countingRepo struct {
+	*memory.JobRepository
+	gets atomic.Int32
+}
+
+func (r *countingRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	r.gets.Add(1)
+	return r.JobRepository.GetByID(ctx, id)
+}
+
+func TestEventService_ResultLoadedOncePerFinalEvent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repo := memory.NewJobRepository()
+	counting := &countingRepo{JobRepository: repo}
+	svc := service.NewEventService(counting, repo)
+
+	id, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	token, _ := repo.StartAttempt(ctx, id, time.Minute)
+
+	var streams []<-chan entity.JobEvent
+	for range 3 {
+		ch, err := svc.Stream(ctx, service.EventFilter{JobID: id})
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		streams = append(streams, ch)
+	}
+
+	_ = repo.SetResultDone(ctx, id, token, json.RawMessage(`{"ok":true}`))
+
+	for i, ch := range streams {
+		ev := <-ch
+		if ev.Status != entity.StatusDone || string(ev.Output) != `{"ok":true}` {
+			t.Fatalf("stream %d: expected done with output, got %+v", i, ev)
+		}
+	}
+	if n := counting.gets.Load(); n != 1 {
+		t.Fatalf("expected result to be loaded once, got %d reads", n)
+	}
+}
+
+// slowRepo — чтение job blocked ждёт release (медленная БД).
+type slowRepo struct {
+	*memory.JobRepository
+	blocked uuid.UUID
+	release chan struct{}
+}
+
+func (r *slowRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	if id == r.blocked {
+		<-r.release
+	}
+	return r.JobRepository.GetByID(ctx, id)
+}
+
+func TestEventService_SlowResultLoadDoesNotBlockOtherJobs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repo := memory.NewJobRepository()
+	slow, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	fast, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	slowRepo := &slowRepo{JobRepository: repo, blocked: slow, release: make(chan struct{})}
+	svc := service.NewEventService(slowRepo, repo)
+
+	slowEvents, _ := svc.Stream(ctx, service.EventFilter{JobID: slow})
+	fastEvents, _ := svc.Stream(ctx, service.EventFilter{JobID: fast})
+
+	slowToken, _ := repo.StartAttempt(ctx, slow, time.Minute)
+	fastToken, _ := repo.StartAttempt(ctx, fast, time.Minute)
+	_ = repo.SetResultDone(ctx, slow, slowToken, json.RawMessage(`{"job":"slow"}`))
+	_ = repo.SetResultDone(ctx, fast, fastToken, json.RawMessage(`{"job":"fast"}`))
+
+	// результат slow ещё грузится, а событие fast уже дошло
+	deadline := time.After(time.Second)
+	for done := false; !done; {
+		select {
+		case ev := <-fastEvents:
+			if done = ev.Status == entity.StatusDone; done && string(ev.Output) != `{"job":"fast"}` {
+				t.Fatalf("expected fast done with output, got %+v", ev)
+			}
+		case <-deadline:
+			t.Fatal("event of another job blocked by slow result load")
+		}
+	}
+
+	close(slowRepo.release)
+	for ev := range slowEvents {
+		if ev.Status == entity.StatusDone {
+			if string(ev.Output) != `{"job":"slow"}` {
+				t.Fatalf("expected slow done with output, got %+v", ev)
+			}
+			return
+		}
+	}
+	t.Fatal("slow stream closed without final event")
+}
+
+func TestEventService_Snapshot_NotFoundOnlyForMissingJob(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+
+	if _, err := service.NewEventService(repo, repo).Snapshot(ctx, uuid.New()); !errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+	_, err := service.NewEventService(brokenRepo{repo}, repo).Snapshot(ctx, uuid.New())
+	if err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected repository error, got %v", err)
+	}
+}
+
+func TestEventService_JobStreamKeepsFinalEventWhenSlow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repo := memory.NewJobRepository()
+	svc := service.NewEventService(repo, repo)
+
+	id, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	other, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	token, _ := repo.StartAttempt(ctx, id, time.Minute)
+	otherToken, _ := repo.StartAttempt(ctx, other, time.Minute)
+
+	events, err := svc.Stream(ctx, service.EventFilter{JobID: id})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	// подписчик не читает: прогресса больше, чем влезает в буфер
+	for i := range 200 {
+		_ = repo.SetProgress(ctx, id, token, entity.JobProgress{Percent: i % 100})
+		_ = repo.SetProgress(ctx, other, otherToken, entity.JobProgress{Percent: i % 100})
+	}
+	_ = repo.SetResultDone(ctx, id, token, json.RawMessage(`{}`))
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.JobID != id {
+				t.Fatalf("expected only events of %s, got %+v", id, ev)
+			}
+			if ev.Kind == entity.EventStatus && ev.Status == entity.StatusDone {
+				return
+			}
+		case <-deadline:
+			t.Fatal("final event was lost")
+		}
+	}
+}
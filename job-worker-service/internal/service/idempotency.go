@@ -0,0 +1,58 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+const maxIdempotencyKeyLen = 255
+
+// ErrIdempotencyKeyReused — ключ уже использован для запроса с другим телом (HTTP 422).
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+// requestHash — hash параметров CreateJobRequest, влияющих на job (input нормализуется: порядок ключей не важен).
+func requestHash(req CreateJobRequest) (string, error) {
+	var input any
+	if err := unmarshalNumber(req.Input, &input); err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(struct {
+		Type        string
+		Priority    int
+		Input       any
+		Retry       entity.RetryPolicy
+		Timeout     time.Duration
+		RunAt       *time.Time
+		Delay       *time.Duration
+		Tags        []string
+		Unique      bool
+		UniqueKey   string
+		CallbackURL string
+		// omitempty: hash запросов без follow-up job тот же, что до их появления
+		OnSuccess *entity.JobTemplate `json:",omitempty"`
+		OnFailure *entity.JobTemplate `json:",omitempty"`
+	}{req.Type, req.Priority, input, req.Retry, req.Timeout, req.RunAt, req.Delay, req.Tags, req.Unique, req.UniqueKey, req.CallbackURL,
+		req.OnSuccess, req.OnFailure})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replay — ответ на повтор запроса: id уже созданной job, если тело то же.
+func replay(job *entity.Job, hash string) (uuid.UUID, error) {
+	if job.Idempotency == nil || job.Idempotency.RequestHash != hash {
+		return uuid.Nil, ErrIdempotencyKeyReused
+	}
+	return job.ID, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// Порт лога job (реализации: postgresql.JobRepository, memory.JobRepository).
+// Строки пишет worker.Processor (worker.JobRepo.AppendLogs).
+type JobLogRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+	ListLogs(ctx context.Context, jobID uuid.UUID, f entity.JobLogFilter) ([]entity.JobLog, error)
+}
+
+const (
+	DefaultLogLimit = 100
+	MaxLogLimit     = 1000
+)
+
+// JobLogPage — страница лога job; Next == 0 на последней странице.
+type JobLogPage struct {
+	Logs []entity.JobLog
+	Next int64 // ID последней строки страницы — JobLogFilter.After следующей
+}
+
+// JobLogService отдаёт лог выполнения job для API.
+type JobLogService struct {
+	repo JobLogRepository
+}
+
+func NewJobLogService(repo JobLogRepository) *JobLogService {
+	return &JobLogService{repo: repo}
+}
+
+// Logs returns one page of job log lines matching f, oldest first (default DefaultLogLimit).
+func (s *JobLogService) Logs(ctx context.Context, jobID uuid.UUID, f entity.JobLogFilter) (JobLogPage, error) {
+	for _, l := range f.Levels {
+		switch l {
+		case entity.LogInfo, entity.LogWarn, entity.LogError:
+		default:
+			return JobLogPage{}, fmt.Errorf("%w: unknown level %q", ErrInvalidFilter, l)
+		}
+	}
+	if f.Attempt < 0 {
+		return JobLogPage{}, fmt.Errorf("%w: attempt must be positive", ErrInvalidFilter)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultLogLimit
+	}
+	f.Limit = min(f.Limit, MaxLogLimit)
+
+	_, err := s.repo.GetByID(ctx, jobID)
+	if errors.Is(err, entity.ErrNotFound) {
+		return JobLogPage{}, ErrJobNotFound
+	}
+	if err != nil {
+		return JobLogPage{}, err
+	}
+
+	// +1 строка: есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+	logs, err := s.repo.ListLogs(ctx, jobID, f)
+	if err != nil {
+		return JobLogPage{}, err
+	}
+
+	page := JobLogPage{Logs: logs}
+	if len(logs) > limit {
+		page.Logs = logs[:limit]
+		page.Next = page.Logs[limit-1].ID
+	}
+	return page, nil
+}
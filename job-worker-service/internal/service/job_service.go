@@ -1,9 +1,13 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -12,29 +16,80 @@ import (
 
 // Порт репозитория (реализация: postgresql.JobRepository)
 type JobRepository interface {
-	Create(ctx context.Context, typ string, priority int, input json.RawMessage) (uuid.UUID, error)
+	Create(ctx context.Context, job *entity.Job) (uuid.UUID, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+	List(ctx context.Context, f entity.JobFilter) ([]*entity.Job, error)
+	// Create возвращает entity.ErrIdempotencyKeyExists, если job с тем же job.Idempotency уже есть,
+	// и entity.ErrUniqueJobExists, если есть активная job с тем же (Type, UniqueKey).
+	GetByIdempotencyKey(ctx context.Context, clientID, key string) (*entity.Job, error)
+	GetActiveByUniqueKey(ctx context.Context, typ, key string) (*entity.Job, error)
+	// ListHistory — переходы job, старые первыми (их пишут сами методы репозитория).
+	ListHistory(ctx context.Context, jobID uuid.UUID) ([]entity.JobTransition, error)
 }
 
 // Маленький порт очереди только для добавления задач в очередь.
 // (Не называем Queue, чтобы не конфликтовать с queue_service.go)
 type JobQueue interface {
 	Enqueue(ctx context.Context, jobID string, priority int) error
+	EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error
+}
+
+// Transactor выполняет fn в одной транзакции БД (реализация: postgresql.TxManager).
+// Нужен, когда enqueue пишет в ту же БД (Postgres-очередь или outbox): тогда создание job и enqueue атомарны.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type JobService struct {
-	repo  JobRepository
-	queue JobQueue
+	repo     JobRepository
+	queue    JobQueue
+	tx       Transactor
+	retry    RetryPolicies
+	timeouts TimeoutPolicies
 }
 
-func NewJobService(repo JobRepository, queue JobQueue) *JobService {
-	return &JobService{repo: repo, queue: queue}
+// NewJobService: tx может быть nil — тогда Create и Enqueue выполняются по отдельности
+// (in-memory очередь). Redis-очередь без outbox в транзакцию ставить нельзя: worker может взять id раньше commit'а.
+func NewJobService(repo JobRepository, queue JobQueue, tx Transactor, retry RetryPolicies, timeouts TimeoutPolicies) *JobService {
+	return &JobService{repo: repo, queue: queue, tx: tx, retry: retry, timeouts: timeouts}
 }
 
 type CreateJobRequest struct {
 	Type     string
 	Priority int
 	Input    json.RawMessage
+
+	// Retry — переопределение политики ретраев для конкретной job.
+	// Нулевые поля берутся из политики для типа.
+	Retry entity.RetryPolicy
+
+	// Timeout одной попытки; 0 — timeout для типа.
+	Timeout time.Duration
+
+	Tags []string
+
+	// Отложенный запуск: RunAt (абсолютное время) или Delay (от текущего момента), не оба сразу.
+	RunAt *time.Time
+	Delay *time.Duration
+
+	// IdempotencyKey (заголовок Idempotency-Key) в рамках ClientID: повтор с тем же ключом
+	// и тем же телом возвращает id уже созданной job, с другим телом — ErrIdempotencyKeyReused.
+	ClientID       string
+	IdempotencyKey string
+
+	// Unique: пока есть pending/processing job того же Type с тем же input (после канонизации),
+	// возвращается её id, новая job не создаётся. UniqueKey — то же, но по ключу клиента вместо input.
+	Unique    bool
+	UniqueKey string
+
+	// CallbackURL — webhook о завершении job (done / error / dead), см. WebhookService.
+	CallbackURL string
+
+	// OnSuccess / OnFailure — follow-up job после done / после error или dead, см. CreateFollowUp.
+	OnSuccess *entity.JobTemplate
+	OnFailure *entity.JobTemplate
+	// ParentID — родитель follow-up job (заполняет CreateFollowUp).
+	ParentID *uuid.UUID
 }
 
 func (s *JobService) CreateJob(ctx context.Context, req CreateJobRequest) (uuid.UUID, error) {
@@ -44,24 +99,310 @@ func (s *JobService) CreateJob(ctx context.Context, req CreateJobRequest) (uuid.
 	if len(req.Input) == 0 {
 		req.Input = json.RawMessage(`{}`)
 	}
+	if req.Retry.MaxAttempts < 0 || req.Retry.BaseDelay < 0 || req.Retry.MaxDelay < 0 || req.Retry.TimeoutMaxAttempts < 0 {
+		return uuid.Nil, errors.New("retry settings must not be negative")
+	}
+	if req.Timeout < 0 {
+		return uuid.Nil, errors.New("timeout_seconds must be positive")
+	}
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = s.timeouts.For(req.Type)
+	}
+
+	for _, tag := range req.Tags {
+		if tag == "" {
+			return uuid.Nil, errors.New("tags must not be empty")
+		}
+	}
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLen {
+		return uuid.Nil, fmt.Errorf("idempotency key is longer than %d", maxIdempotencyKeyLen)
+	}
+	if len(req.UniqueKey) > maxUniqueKeyLen {
+		return uuid.Nil, fmt.Errorf("unique_key is longer than %d", maxUniqueKeyLen)
+	}
+	if req.CallbackURL != "" {
+		if err := validateWebhookURL(req.CallbackURL); err != nil {
+			return uuid.Nil, fmt.Errorf("callback_url: %w", err)
+		}
+	}
+	if err := validateFollowUps(req.OnSuccess, req.OnFailure, 1); err != nil {
+		return uuid.Nil, err
+	}
+
+	var idem *entity.Idempotency
+	if req.IdempotencyKey != "" {
+		hash, err := requestHash(req)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if existing, err := s.repo.GetByIdempotencyKey(ctx, req.ClientID, req.IdempotencyKey); err == nil {
+			return replay(existing, hash)
+		}
+		idem = &entity.Idempotency{Key: req.IdempotencyKey, RequestHash: hash}
+	}
+
+	runAt, err := resolveRunAt(req.RunAt, req.Delay)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	uniqueKey := req.UniqueKey
+	if uniqueKey == "" && req.Unique {
+		if uniqueKey, err = inputFingerprint(req.Input); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if uniqueKey != "" {
+		if existing, err := s.repo.GetActiveByUniqueKey(ctx, req.Type, uniqueKey); err == nil {
+			return existing.ID, nil
+		}
+	}
 
 	priority := req.Priority
 	if priority < 0 || priority > 2 {
 		priority = 1 // normal
 	}
 
-	id, err := s.repo.Create(ctx, req.Type, priority, req.Input)
+	job := &entity.Job{
+		Type:     req.Type,
+		Status:   entity.StatusPending,
+		Priority: priority,
+		Input:    req.Input,
+		Retry:    mergeRetryPolicy(s.retry.For(req.Type), req.Retry),
+		RunAt:    runAt,
+		Timeout:  timeout,
+		Tags:     slices.Compact(slices.Sorted(slices.Values(req.Tags))),
+
+		Idempotency: idem,
+		UniqueKey:   uniqueKey,
+
+		ClientID:    req.ClientID,
+		CallbackURL: req.CallbackURL,
+
+		OnSuccess: req.OnSuccess,
+		OnFailure: req.OnFailure,
+		ParentID:  req.ParentID,
+	}
+
+	var id uuid.UUID
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.repo.Create(ctx, job)
+		if err != nil {
+			return err
+		}
+
+		if runAt != nil {
+			return s.queue.EnqueueAt(ctx, id.String(), priority, *runAt)
+		}
+		return s.queue.Enqueue(ctx, id.String(), priority)
+	})
+	if errors.Is(err, entity.ErrIdempotencyKeyExists) {
+		// параллельный запрос с тем же ключом успел создать job раньше
+		existing, getErr := s.repo.GetByIdempotencyKey(ctx, req.ClientID, idem.Key)
+		if getErr != nil {
+			return uuid.Nil, getErr
+		}
+		return replay(existing, idem.RequestHash)
+	}
+	if errors.Is(err, entity.ErrUniqueJobExists) {
+		// такую же job успели создать параллельно (двойной клик)
+		if existing, getErr := s.repo.GetActiveByUniqueKey(ctx, req.Type, uniqueKey); getErr == nil {
+			return existing.ID, nil
+		}
+		return uuid.Nil, err
+	}
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	if err := s.queue.Enqueue(ctx, id.String(), priority); err != nil {
+	return id, nil
+}
+
+// ErrInvalidInputPatch — input patch CloneJob не JSON (HTTP 400).
+var ErrInvalidInputPatch = errors.New("invalid input patch")
+
+type CloneJobRequest struct {
+	// InputPatch — JSON merge patch (RFC 7386) поверх input исходной job:
+	// ключи заменяются, null удаляет ключ. Пусто — input как есть.
+	InputPatch json.RawMessage
+	// Priority; nil — как у исходной job.
+	Priority *int
+}
+
+// CloneJob создаёт новую job из существующей (любого статуса): тот же type, retry-политика,
+// timeout, tags и follow-up job, input — исходный с наложенным InputPatch.
+func (s *JobService) CloneJob(ctx context.Context, id uuid.UUID, req CloneJobRequest) (uuid.UUID, error) {
+	if req.Priority != nil && (*req.Priority < 0 || *req.Priority > 2) {
+		return uuid.Nil, ErrInvalidPriority
+	}
+	src, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return uuid.Nil, ErrJobNotFound
+	}
+	if err != nil {
 		return uuid.Nil, err
 	}
 
-	return id, nil
+	input := src.Input
+	if len(req.InputPatch) > 0 {
+		if input, err = mergePatch(src.Input, req.InputPatch); err != nil {
+			return uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidInputPatch, err)
+		}
+	}
+	priority := src.Priority
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+
+	return s.CreateJob(ctx, CreateJobRequest{
+		Type:     src.Type,
+		Priority: priority,
+		Input:    input,
+		Retry:    src.Retry,
+		Timeout:  src.Timeout,
+		Tags:     src.Tags,
+
+		OnSuccess: src.OnSuccess,
+		OnFailure: src.OnFailure,
+	})
+}
+
+// mergePatch applies JSON merge patch (RFC 7386) to doc.
+func mergePatch(doc, patch json.RawMessage) (json.RawMessage, error) {
+	var d, p any
+	if len(doc) > 0 {
+		if err := unmarshalNumber(doc, &d); err != nil {
+			return nil, err
+		}
+	}
+	if err := unmarshalNumber(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(d, p))
+}
+
+func mergeValue(doc, patch any) any {
+	po, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	do, ok := doc.(map[string]any)
+	if !ok {
+		do = map[string]any{}
+	}
+	for k, v := range po {
+		if v == nil {
+			delete(do, k)
+			continue
+		}
+		do[k] = mergeValue(do[k], v)
+	}
+	return do
+}
+
+// unmarshalNumber — json.Unmarshal без потери точности больших чисел (json.Number вместо float64).
+func unmarshalNumber(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func (s *JobService) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTx(ctx, fn)
+}
+
+// resolveRunAt returns nil for immediate run (no run_at/delay or time already passed).
+func resolveRunAt(runAt *time.Time, delay *time.Duration) (*time.Time, error) {
+	if runAt != nil && delay != nil {
+		return nil, errors.New("run_at and delay_seconds are mutually exclusive")
+	}
+	if delay != nil {
+		if *delay < 0 {
+			return nil, errors.New("delay_seconds must not be negative")
+		}
+		at := time.Now().Add(*delay)
+		runAt = &at
+	}
+	if runAt == nil || !runAt.After(time.Now()) {
+		return nil, nil
+	}
+	at := runAt.UTC()
+	return &at, nil
 }
 
 func (s *JobService) GetJob(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
-	return s.repo.GetByID(ctx, id)
+	job, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+// History returns status transitions and attempts of job, oldest first.
+func (s *JobService) History(ctx context.Context, id uuid.UUID) ([]entity.JobTransition, error) {
+	if _, err := s.GetJob(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListHistory(ctx, id)
+}
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// ErrInvalidFilter — некорректный фильтр списка job (HTTP 400).
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// JobPage — страница списка job; Next == nil на последней странице.
+type JobPage struct {
+	Jobs []*entity.Job
+	Next *entity.JobCursor
+}
+
+// ListJobs returns one page of jobs matching f (default: newest first, DefaultListLimit).
+func (s *JobService) ListJobs(ctx context.Context, f entity.JobFilter) (JobPage, error) {
+	for _, st := range f.Statuses {
+		switch st {
+		case entity.StatusPending, entity.StatusProcessing, entity.StatusDone,
+			entity.StatusError, entity.StatusDead, entity.StatusCanceled:
+		default:
+			return JobPage{}, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, st)
+		}
+	}
+	switch f.Order {
+	case "":
+		f.Order = entity.SortDesc
+	case entity.SortAsc, entity.SortDesc:
+	default:
+		return JobPage{}, fmt.Errorf("%w: order must be asc or desc", ErrInvalidFilter)
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+		return JobPage{}, fmt.Errorf("%w: created_from must be before created_to", ErrInvalidFilter)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultListLimit
+	}
+	f.Limit = min(f.Limit, MaxListLimit)
+
+	// +1 строка: есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+	jobs, err := s.repo.List(ctx, f)
+	if err != nil {
+		return JobPage{}, err
+	}
+
+	page := JobPage{Jobs: jobs}
+	if len(jobs) > limit {
+		page.Jobs = jobs[:limit]
+		last := page.Jobs[limit-1]
+		page.Next = &entity.JobCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return page, nil
 }
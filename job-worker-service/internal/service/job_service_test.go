@@ -10,73 +10,56 @@ import (
 	"github.com/google/uuid"
 
 	"job-worker-service/internal/entity"
-	"job-worker-service/internal/repository/postgresql"
+	"job-worker-service/internal/repository/memory"
 	"job-worker-service/internal/service"
 )
 
-type fakeRepo struct {
-	createCalled int
-	lastType     string
-	lastInput    json.RawMessage
-	lastPriority int
-
-	createID  uuid.UUID
-	createErr error
+// failingQueue — in-memory очередь, у которой Enqueue всегда падает.
+type failingQueue struct {
+	*memory.Queue
+	calls int
 }
 
-func (r *fakeRepo) Create(ctx context.Context, typ string, priority int, input json.RawMessage) (uuid.UUID, error) {
-
-	r.createCalled++
-	r.lastType = typ
-	r.lastPriority = priority
-	r.lastInput = input
-	if r.createErr != nil {
-		return uuid.Nil, r.createErr
-	}
-	return r.createID, nil
+func (q *failingQueue) Enqueue(ctx context.Context, jobID string, priority int) error {
+	q.calls++
+	return errors.New("queue down")
 }
 
-func (r *fakeRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
-	return nil, postgresql.ErrNotFound
-}
-func (r *fakeRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status entity.JobStatus) error {
-	return nil
-}
-func (r *fakeRepo) SetResultDone(ctx context.Context, id uuid.UUID, output json.RawMessage) error {
-	return nil
-}
-func (r *fakeRepo) SetResultError(ctx context.Context, id uuid.UUID, errText string) error {
-	return nil
+// fakeTx: транзакция "откатывается", если fn вернула ошибку.
+type fakeTx struct {
+	calls      int
+	rolledBack int
 }
 
-type fakeQueue struct {
-	enqueuedIDs        []string
-	enqueuedPriorities []int
-	enqueueErr         error
+func (tx *fakeTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx.calls++
+	if err := fn(ctx); err != nil {
+		tx.rolledBack++
+		return err
+	}
+	return nil
 }
 
-func (q *fakeQueue) Enqueue(ctx context.Context, jobID string, priority int) error {
-	q.enqueuedIDs = append(q.enqueuedIDs, jobID)
-	q.enqueuedPriorities = append(q.enqueuedPriorities, priority)
-	return q.enqueueErr
+func newTestJobService(retry service.RetryPolicies) (*service.JobService, *memory.JobRepository, *memory.Queue) {
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	return service.NewJobService(repo, queue, nil, retry, service.TimeoutPolicies{}), repo, queue
 }
 
-// Остальные методы интерфейса Queue нам в этих тестах не нужны, но они должны существовать
-func (q *fakeQueue) ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error) {
-	return "", errors.New("not implemented")
+func mustGetJob(t *testing.T, repo *memory.JobRepository, id uuid.UUID) *entity.Job {
+	t.Helper()
+	j, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get job %s: %v", id, err)
+	}
+	return j
 }
-func (q *fakeQueue) Ack(ctx context.Context, jobID string) error                { return nil }
-func (q *fakeQueue) RequeueStale(ctx context.Context, max int64) (int64, error) { return 0, nil }
 
 func TestJobService_CreateJob_PriorityPropagates(t *testing.T) {
 	ctx := context.Background()
-	id := uuid.MustParse("66666666-6666-6666-6666-666666666666")
-
-	repo := &fakeRepo{createID: id}
-	queue := &fakeQueue{}
-	svc := service.NewJobService(repo, queue)
+	svc, repo, queue := newTestJobService(service.RetryPolicies{})
 
-	_, err := svc.CreateJob(ctx, service.CreateJobRequest{
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{
 		Type:     "echo",
 		Priority: 2,
 		Input:    json.RawMessage(`{"x":1}`),
@@ -85,23 +68,19 @@ func TestJobService_CreateJob_PriorityPropagates(t *testing.T) {
 		t.Fatalf("expected nil error, got %v", err)
 	}
 
-	if repo.lastPriority != 2 {
-		t.Fatalf("expected repo priority=2, got %d", repo.lastPriority)
+	if got := mustGetJob(t, repo, id).Priority; got != 2 {
+		t.Fatalf("expected repo priority=2, got %d", got)
 	}
-	if len(queue.enqueuedPriorities) != 1 || queue.enqueuedPriorities[0] != 2 {
-		t.Fatalf("expected enqueue priority=2, got %#v", queue.enqueuedPriorities)
+	if pending := queue.Pending(2); len(pending) != 1 || pending[0] != id.String() {
+		t.Fatalf("expected job in high lane, got %#v", pending)
 	}
 }
 
 func TestJobService_CreateJob_PriorityClampedToNormal(t *testing.T) {
 	ctx := context.Background()
-	id := uuid.MustParse("77777777-7777-7777-7777-777777777777")
+	svc, repo, queue := newTestJobService(service.RetryPolicies{})
 
-	repo := &fakeRepo{createID: id}
-	queue := &fakeQueue{}
-	svc := service.NewJobService(repo, queue)
-
-	_, err := svc.CreateJob(ctx, service.CreateJobRequest{
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{
 		Type:     "echo",
 		Priority: 999, // invalid
 		Input:    json.RawMessage(`{"x":1}`),
@@ -110,10 +89,405 @@ func TestJobService_CreateJob_PriorityClampedToNormal(t *testing.T) {
 		t.Fatalf("expected nil error, got %v", err)
 	}
 
-	if repo.lastPriority != 1 {
-		t.Fatalf("expected repo priority=1 (clamped), got %d", repo.lastPriority)
+	if got := mustGetJob(t, repo, id).Priority; got != 1 {
+		t.Fatalf("expected repo priority=1 (clamped), got %d", got)
+	}
+	if pending := queue.Pending(1); len(pending) != 1 || pending[0] != id.String() {
+		t.Fatalf("expected job in normal lane (clamped), got %#v", pending)
+	}
+}
+
+func TestJobService_CreateJob_RetryPolicyByTypeAndOverride(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newTestJobService(service.RetryPolicies{
+		ByType: map[string]entity.RetryPolicy{
+			"convert_video": {MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute},
+		},
+	})
+
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "convert_video"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	want := entity.RetryPolicy{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute}
+	if got := mustGetJob(t, repo, id).Retry; got != want {
+		t.Fatalf("expected type policy %+v, got %+v", want, got)
+	}
+
+	// переопределение на уровне job: только max_attempts, задержки — из политики типа
+	id, err = svc.CreateJob(ctx, service.CreateJobRequest{
+		Type:  "convert_video",
+		Retry: entity.RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	want.MaxAttempts = 1
+	if got := mustGetJob(t, repo, id).Retry; got != want {
+		t.Fatalf("expected overridden policy %+v, got %+v", want, got)
+	}
+
+	// неизвестный тип => DefaultRetryPolicy
+	id, err = svc.CreateJob(ctx, service.CreateJobRequest{Type: "echo"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got := mustGetJob(t, repo, id).Retry; got != service.DefaultRetryPolicy {
+		t.Fatalf("expected default policy %+v, got %+v", service.DefaultRetryPolicy, got)
+	}
+}
+
+func TestJobService_CreateJob_TimeoutByTypeAndOverride(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	svc := service.NewJobService(repo, memory.NewQueue(time.Minute), nil, service.RetryPolicies{}, service.TimeoutPolicies{
+		ByType: map[string]time.Duration{"convert_video": 10 * time.Minute},
+	})
+
+	for _, tc := range []struct {
+		req  service.CreateJobRequest
+		want time.Duration
+	}{
+		{service.CreateJobRequest{Type: "convert_video"}, 10 * time.Minute},
+		{service.CreateJobRequest{Type: "convert_video", Timeout: 5 * time.Second}, 5 * time.Second},
+		{service.CreateJobRequest{Type: "echo"}, service.DefaultJobTimeout},
+	} {
+		id, err := svc.CreateJob(ctx, tc.req)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if got := mustGetJob(t, repo, id).Timeout; got != tc.want {
+			t.Fatalf("%s (timeout=%s): expected %s, got %s", tc.req.Type, tc.req.Timeout, tc.want, got)
+		}
+	}
+
+	if _, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "echo", Timeout: -time.Second}); err == nil {
+		t.Fatalf("expected error for negative timeout")
+	}
+}
+
+func TestJobService_CreateJob_CreateAndEnqueueInOneTx(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	queue := &failingQueue{Queue: memory.NewQueue(time.Minute)}
+	tx := &fakeTx{}
+	svc := service.NewJobService(repo, queue, tx, service.RetryPolicies{}, service.TimeoutPolicies{})
+
+	if _, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "echo"}); err == nil {
+		t.Fatalf("expected enqueue error")
+	}
+	if tx.calls != 1 || tx.rolledBack != 1 {
+		t.Fatalf("expected create+enqueue in one rolled back tx, got calls=%d rolledBack=%d", tx.calls, tx.rolledBack)
+	}
+	if queue.calls != 1 {
+		t.Fatalf("expected enqueue inside tx, got %d calls", queue.calls)
+	}
+}
+
+func TestJobService_CreateJob_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	svc, _, queue := newTestJobService(service.RetryPolicies{})
+
+	req := service.CreateJobRequest{
+		Type:           "generate_report",
+		Priority:       1,
+		Input:          json.RawMessage(`{"report":"sales","month":3}`),
+		ClientID:       "gateway",
+		IdempotencyKey: "k-1",
+	}
+
+	// параллельные повторы (gateway ретраит по timeout'у) — одна job
+	const n = 8
+	ids := make(chan uuid.UUID, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			id, err := svc.CreateJob(ctx, req)
+			ids <- id
+			errs <- err
+		}()
+	}
+	first := uuid.Nil
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		id := <-ids
+		if first == uuid.Nil {
+			first = id
+		}
+		if id != first {
+			t.Fatalf("expected same job id, got %s and %s", first, id)
+		}
+	}
+	if pending := queue.Pending(1); len(pending) != 1 {
+		t.Fatalf("expected one enqueued job, got %v", pending)
+	}
+
+	// порядок ключей в input не важен
+	same := req
+	same.Input = json.RawMessage(`{"month":3,"report":"sales"}`)
+	if id, err := svc.CreateJob(ctx, same); err != nil || id != first {
+		t.Fatalf("expected replay of %s, got %s (err=%v)", first, id, err)
+	}
+
+	other := req
+	other.Input = json.RawMessage(`{"report":"sales","month":4}`)
+	if _, err := svc.CreateJob(ctx, other); !errors.Is(err, service.ErrIdempotencyKeyReused) {
+		t.Fatalf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// ключ действует в рамках клиента
+	otherClient := req
+	otherClient.ClientID = "batch"
+	if id, err := svc.CreateJob(ctx, otherClient); err != nil || id == first {
+		t.Fatalf("expected new job for another client, got %s (err=%v)", id, err)
+	}
+}
+
+func TestJobService_CreateJob_UniqueDeduplicatesActiveJobs(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, queue := newTestJobService(service.RetryPolicies{})
+
+	create := func(typ, input, key string) uuid.UUID {
+		t.Helper()
+		id, err := svc.CreateJob(ctx, service.CreateJobRequest{
+			Type:      typ,
+			Priority:  1,
+			Input:     json.RawMessage(input),
+			Unique:    key == "",
+			UniqueKey: key,
+		})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return id
+	}
+
+	// двойной клик: тот же type и input (порядок ключей не важен) — та же job
+	first := create("generate_report", `{"report":"sales","month":3}`, "")
+	if id := create("generate_report", `{ "month": 3, "report": "sales" }`, ""); id != first {
+		t.Fatalf("expected duplicate to return %s, got %s", first, id)
+	}
+	if pending := queue.Pending(1); len(pending) != 1 {
+		t.Fatalf("expected one enqueued job, got %v", pending)
+	}
+
+	// другой input / другой type — отдельные job
+	if id := create("generate_report", `{"report":"sales","month":4}`, ""); id == first {
+		t.Fatal("expected new job for different input")
+	}
+	if id := create("echo", `{"report":"sales","month":3}`, ""); id == first {
+		t.Fatal("expected new job for different type")
+	}
+
+	// unique_key: input не сравнивается
+	keyed := create("generate_report", `{"v":1}`, "sales-q1")
+	if id := create("generate_report", `{"v":2}`, "sales-q1"); id != keyed {
+		t.Fatalf("expected unique_key duplicate to return %s, got %s", keyed, id)
+	}
+
+	// processing — всё ещё активна
+	token, _ := repo.StartAttempt(ctx, first, time.Minute)
+	if id := create("generate_report", `{"report":"sales","month":3}`, ""); id != first {
+		t.Fatalf("expected processing job %s, got %s", first, id)
+	}
+
+	// завершилась — можно снова; ручной повтор старой job теперь конфликтует с новой
+	_ = repo.SetResultError(ctx, first, token, "boom")
+	again := create("generate_report", `{"report":"sales","month":3}`, "")
+	if again == first {
+		t.Fatal("expected new job after the previous one finished")
+	}
+	retrySvc := service.NewRetryService(repo, queue, nil, queue)
+	if _, err := retrySvc.Retry(ctx, first, nil); !errors.Is(err, entity.ErrUniqueJobExists) {
+		t.Fatalf("expected ErrUniqueJobExists, got %v", err)
+	}
+}
+
+func TestParseRetryPolicies(t *testing.T) {
+	got, err := service.ParseRetryPolicies("convert_video:5:2s:5m, echo:1")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got["convert_video"] != (entity.RetryPolicy{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute}) {
+		t.Fatalf("unexpected convert_video policy: %+v", got["convert_video"])
+	}
+	if got["echo"] != (entity.RetryPolicy{MaxAttempts: 1}) {
+		t.Fatalf("unexpected echo policy: %+v", got["echo"])
+	}
+
+	if _, err := service.ParseRetryPolicies("echo:zero"); err == nil {
+		t.Fatalf("expected error for invalid max_attempts")
+	}
+
+	got, err = service.ParseRetryPolicies("convert_video:5:2s:5m:1")
+	if err != nil || got["convert_video"].TimeoutMaxAttempts != 1 || got["convert_video"].AttemptsFor(entity.ErrorClassTimeout) != 1 {
+		t.Fatalf("expected timeout_max_attempts=1, got %+v (err=%v)", got["convert_video"], err)
+	}
+}
+
+func TestParseTimeouts(t *testing.T) {
+	got, err := service.ParseTimeouts("convert_video:10m, echo:5s")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if got["convert_video"] != 10*time.Minute || got["echo"] != 5*time.Second {
+		t.Fatalf("unexpected timeouts: %v", got)
+	}
+
+	for _, spec := range []string{"echo", "echo:soon", "echo:0s", ":5s"} {
+		if _, err := service.ParseTimeouts(spec); err == nil {
+			t.Fatalf("%q: expected error", spec)
+		}
+	}
+}
+
+// brokenRepo — memory-репозиторий, у которого чтение job падает (БД недоступна).
+type brokenRepo struct {
+	*memory.JobRepository
+}
+
+func (r brokenRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
+	return nil, errors.New("db down")
+}
+
+func TestCancelService_Cancel_NotFoundOnlyForMissingJob(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	if _, err := service.NewCancelService(repo, queue).Cancel(ctx, uuid.New()); !errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for missing job, got %v", err)
+	}
+
+	_, err := service.NewCancelService(brokenRepo{repo}, queue).Cancel(ctx, uuid.New())
+	if err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected repository error, got %v", err)
+	}
+}
+
+func TestJobService_History_NotFoundOnlyForMissingJob(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	if _, err := svc.History(ctx, uuid.New()); !errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for missing job, got %v", err)
+	}
+
+	svc = service.NewJobService(brokenRepo{repo}, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	if _, err := svc.History(ctx, uuid.New()); err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected repository error, got %v", err)
+	}
+}
+
+func TestJobLogService_Logs_NotFoundOnlyForMissingJob(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+
+	if _, err := service.NewJobLogService(repo).Logs(ctx, uuid.New(), entity.JobLogFilter{}); !errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for missing job, got %v", err)
+	}
+
+	_, err := service.NewJobLogService(brokenRepo{repo}).Logs(ctx, uuid.New(), entity.JobLogFilter{})
+	if err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected repository error, got %v", err)
+	}
+}
+
+func TestJobService_CreateFollowUp_PassesRepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	if _, err := svc.CreateFollowUp(ctx, uuid.New()); !errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for missing parent, got %v", err)
+	}
+
+	svc = service.NewJobService(brokenRepo{repo}, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	if _, err := svc.CreateFollowUp(ctx, uuid.New()); err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected repository error, got %v", err)
+	}
+}
+
+// raceRepo — job ещё canceled при GetByID, но к ResetToPending её уже перезапустили.
+type raceRepo struct {
+	*memory.JobRepository
+}
+
+func (r raceRepo) ResetToPending(ctx context.Context, id uuid.UUID, priority int) error {
+	return entity.ErrInvalidTransition
+}
+
+func TestRetryService_Retry_ConflictAndInvalidPriority(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	id, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	if _, err := repo.Cancel(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := service.NewRetryService(raceRepo{repo}, queue, nil, queue)
+	if _, err := svc.Retry(ctx, id, nil); !errors.Is(err, service.ErrNotRetryable) {
+		t.Fatalf("expected ErrNotRetryable, got %v", err)
+	}
+	bad := 3
+	if _, err := svc.Retry(ctx, id, &bad); !errors.Is(err, service.ErrInvalidPriority) {
+		t.Fatalf("expected ErrInvalidPriority, got %v", err)
+	}
+	if _, err := service.NewRetryService(brokenRepo{repo}, queue, nil, queue).Retry(ctx, id, nil); err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected internal error, got %v", err)
+	}
+}
+
+// outboxQueue — JobQueue, который только запоминает enqueue (как outbox до relay).
+type outboxQueue struct {
+	enqueued []string
+}
+
+func (q *outboxQueue) Enqueue(ctx context.Context, jobID string, priority int) error {
+	q.enqueued = append(q.enqueued, jobID)
+	return nil
+}
+
+func (q *outboxQueue) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	q.enqueued = append(q.enqueued, jobID)
+	return nil
+}
+
+func TestDeadLetterService_RequeueGoesThroughOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	id, _ := repo.Create(ctx, &entity.Job{Type: "echo", Priority: 2})
+	token, _ := repo.StartAttempt(ctx, id, time.Minute)
+	_ = repo.SetDead(ctx, id, token, entity.ErrorClassError, "boom")
+	_ = queue.DeadLetter(ctx, id.String(), "boom")
+
+	outbox := &outboxQueue{}
+	tx := &fakeTx{}
+	svc := service.NewDeadLetterService(repo, queue, service.NewRetryService(repo, outbox, tx, queue))
+
+	if err := svc.Requeue(ctx, id.String()); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if tx.calls != 1 || len(outbox.enqueued) != 1 || outbox.enqueued[0] != id.String() {
+		t.Fatalf("expected reset+enqueue through outbox in one tx, got calls=%d enqueued=%v", tx.calls, outbox.enqueued)
+	}
+	if len(queue.Pending(2)) != 0 {
+		t.Fatalf("expected no direct enqueue into queue, got %v", queue.Pending(2))
+	}
+	if _, err := queue.GetDead(ctx, id.String()); !errors.Is(err, entity.ErrDeadLetterNotFound) {
+		t.Fatalf("expected dead letter removed, got %v", err)
 	}
-	if len(queue.enqueuedPriorities) != 1 || queue.enqueuedPriorities[0] != 1 {
-		t.Fatalf("expected enqueue priority=1 (clamped), got %#v", queue.enqueuedPriorities)
+	if got := mustGetJob(t, repo, id).Status; got != entity.StatusPending {
+		t.Fatalf("expected status=pending, got %s", got)
 	}
 }
@@ -0,0 +1,222 @@
+package service
+
+import "github.com/redis/go-redis/v9"
+
+// Lua-скрипты RedisPriorityQueue. Каждый выполняется на сервере атомарно,
+// поэтому processing-листы, processing map и lease'ы не могут "разъехаться",
+// даже если worker умрёт посреди операции.
+//
+// Все ключи одной очереди должны жить на одном Redis (в кластере — один hash slot, например {jobs}:...).
+
+// claimScript забирает job из первой непустой lane (в порядке KEYS) за один round trip.
+// KEYS: map, leases, q1, p1, q2, p2, ... (пары queue/processing в порядке приоритета)
+// ARGV: lease expiry (unix ms)
+// Returns job id or nil.
+var claimScript = redis.NewScript(`
+local mapKey, leaseKey = KEYS[1], KEYS[2]
+for i = 3, #KEYS, 2 do
+	local id = redis.call('RPOPLPUSH', KEYS[i], KEYS[i+1])
+	if id then
+		redis.call('HSET', mapKey, id, KEYS[i+1])
+		redis.call('ZADD', leaseKey, ARGV[1], id)
+		return id
+	end
+end
+return false
+`)
+
+// heartbeatScript продлевает lease, только если job всё ещё числится в processing.
+// KEYS: map, leases
+// ARGV: job id, lease expiry (unix ms)
+// Returns 1 if extended, 0 if lease lost.
+var heartbeatScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if not redis.call('ZSCORE', KEYS[2], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`)
+
+// ackScript удаляет job из processing-листа, processing map и lease'ов.
+// Если mapping нет (старые job / ручное вмешательство) — чистит все processing-листы.
+// KEYS: map, leases, p1, p2, ...
+// ARGV: job id
+// Returns number of removed processing entries.
+var ackScript = redis.NewScript(`
+local mapKey, leaseKey, id = KEYS[1], KEYS[2], ARGV[1]
+local removed = 0
+local pk = redis.call('HGET', mapKey, id)
+if pk then
+	removed = redis.call('LREM', pk, 1, id)
+else
+	for i = 3, #KEYS do
+		removed = removed + redis.call('LREM', KEYS[i], 1, id)
+	end
+end
+redis.call('HDEL', mapKey, id)
+redis.call('ZREM', leaseKey, id)
+return removed
+`)
+
+// reapScript возвращает в очередь job'ы с истёкшим lease.
+// KEYS: map, leases, q1, p1, q2, p2, ...
+// ARGV: now (unix ms), limit
+// Returns number of requeued jobs.
+var reapScript = redis.NewScript(`
+local mapKey, leaseKey = KEYS[1], KEYS[2]
+local ids = redis.call('ZRANGEBYSCORE', leaseKey, '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local moved = 0
+for _, id in ipairs(ids) do
+	redis.call('ZREM', leaseKey, id)
+	local pk = redis.call('HGET', mapKey, id)
+	for i = 3, #KEYS, 2 do
+		if (not pk) or KEYS[i+1] == pk then
+			if redis.call('LREM', KEYS[i+1], 1, id) > 0 then
+				redis.call('LPUSH', KEYS[i], id)
+				moved = moved + 1
+				break
+			end
+		end
+	end
+	redis.call('HDEL', mapKey, id)
+end
+return moved
+`)
+
+// promoteScript переносит "созревшие" job из scheduled set в очередь lane.
+// KEYS: scheduled, queue
+// ARGV: now (unix ms), limit
+// Returns number of promoted jobs.
+var promoteScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('LPUSH', KEYS[2], id)
+end
+return #ids
+`)
+
+// streamEnqueueScript добавляет job в stream и запоминает id записи в индексе lane (job_id -> entry id).
+// KEYS: stream, index
+// ARGV: job id
+var streamEnqueueScript = redis.NewScript(`
+local entry = redis.call('XADD', KEYS[1], '*', 'job_id', ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], entry)
+return entry
+`)
+
+// streamPromoteScript — promoteScript для stream-бэкенда: "созревшие" job из scheduled set -> XADD (+ индекс).
+// KEYS: scheduled, stream, index
+// ARGV: now (unix ms), limit
+var streamPromoteScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local entry = redis.call('XADD', KEYS[2], '*', 'job_id', id)
+	redis.call('HSET', KEYS[3], id, entry)
+end
+return #ids
+`)
+
+// streamAckScript: XACK + XDEL записи; индекс чистится, только если указывает на неё
+// (job могла уже попасть в stream заново, например ретрай без задержки).
+// KEYS: stream, index
+// ARGV: group, entry id, job id
+var streamAckScript = redis.NewScript(`
+redis.call('XACK', KEYS[1], ARGV[1], ARGV[2])
+redis.call('XDEL', KEYS[1], ARGV[2])
+if redis.call('HGET', KEYS[2], ARGV[3]) == ARGV[2] then
+	redis.call('HDEL', KEYS[2], ARGV[3])
+end
+return 1
+`)
+
+// streamRemoveScript удаляет запись job из stream по индексу и job из scheduled set.
+// KEYS: stream, index, scheduled
+// ARGV: job id
+var streamRemoveScript = redis.NewScript(`
+local entry = redis.call('HGET', KEYS[2], ARGV[1])
+if entry then
+	redis.call('XDEL', KEYS[1], entry)
+	redis.call('HDEL', KEYS[2], ARGV[1])
+end
+redis.call('ZREM', KEYS[3], ARGV[1])
+return 1
+`)
+
+// streamHeartbeatScript сбрасывает idle time записи, только если она всё ещё в PEL этого consumer'а
+// (иначе её уже забрал reaper — XCLAIM вернул бы её нам обратно).
+// KEYS: stream
+// ARGV: group, entry id, consumer
+// Returns 1 if extended, 0 if lease lost.
+var streamHeartbeatScript = redis.NewScript(`
+local p = redis.call('XPENDING', KEYS[1], ARGV[1], ARGV[2], ARGV[2], 1, ARGV[3])
+if #p == 0 then
+	return 0
+end
+redis.call('XCLAIM', KEYS[1], ARGV[1], ARGV[3], 0, ARGV[2], 'JUSTID')
+return 1
+`)
+
+// streamReapScript передаёт записи, которые висят в PEL дольше visibility timeout, consumer'у-держателю (XCLAIM JUSTID):
+// запись остаётся той же, счётчик доставок не сбрасывается. Забирает их у держателя streamReclaimScript в Claim.
+// KEYS: stream
+// ARGV: group, holder consumer, min idle (ms), limit
+// Returns number of requeued jobs.
+var streamReapScript = redis.NewScript(`
+local p = redis.call('XPENDING', KEYS[1], ARGV[1], 'IDLE', ARGV[3], '-', '+', tonumber(ARGV[4]))
+local moved = 0
+for _, e in ipairs(p) do
+	if e[2] ~= ARGV[2] then
+		redis.call('XCLAIM', KEYS[1], ARGV[1], ARGV[2], ARGV[3], e[1], 'JUSTID')
+		moved = moved + 1
+	end
+end
+return moved
+`)
+
+// streamReclaimScript забирает у consumer'а-держателя самую старую возвращённую reaper'ом запись (XCLAIM);
+// записи, удалённые из stream (XDEL), снимает из PEL и пропускает.
+// KEYS: stream
+// ARGV: group, holder consumer, consumer
+// Returns entry {id, fields} or nil if holder has nothing.
+var streamReclaimScript = redis.NewScript(`
+while true do
+	local p = redis.call('XPENDING', KEYS[1], ARGV[1], '-', '+', 1, ARGV[2])
+	if #p == 0 then
+		return false
+	end
+	local res = redis.call('XCLAIM', KEYS[1], ARGV[1], ARGV[3], 0, p[1][1])
+	if #res > 0 and type(res[1]) == 'table' then
+		return res[1]
+	end
+	redis.call('XACK', KEYS[1], ARGV[1], p[1][1])
+end
+`)
+
+// trackedScript проверяет, знает ли очередь о job: она в очереди lane, в scheduled set
+// или взята worker'ом (есть в processing map).
+// KEYS: map, q1, s1, q2, s2, ... (пары queue/scheduled)
+// ARGV: job ids
+// Returns array of 0/1 in ARGV order.
+var trackedScript = redis.NewScript(`
+local out = {}
+for i, id in ipairs(ARGV) do
+	local found = 0
+	if redis.call('HEXISTS', KEYS[1], id) == 1 then
+		found = 1
+	else
+		for k = 2, #KEYS, 2 do
+			if redis.call('LPOS', KEYS[k], id) or redis.call('ZSCORE', KEYS[k+1], id) then
+				found = 1
+				break
+			end
+		end
+	end
+	out[i] = found
+end
+return out
+`)
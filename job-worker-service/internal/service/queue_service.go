@@ -6,55 +6,118 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"job-worker-service/internal/entity"
 )
 
 type Queue interface {
 	Enqueue(ctx context.Context, jobID string, priority int) error
+	EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error
+	PromoteDue(ctx context.Context, maxPerLane int64) (int64, error)
 	ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error)
+	Heartbeat(ctx context.Context, jobID string) error
 	Ack(ctx context.Context, jobID string) error
-	RequeueStale(ctx context.Context, maxPerLane int64) (int64, error)
+	RequeueStale(ctx context.Context, limit int64) (int64, error)
+	DeadLetter(ctx context.Context, jobID string, reason string) error
+}
+
+// QueueBackend — реализация очереди целиком, как её собирают cmd/*:
+// сама очередь, dead-letter, отмена job и visibility timeout (для частоты heartbeat).
+type QueueBackend interface {
+	Queue
+	DeadLetters
+	CancelSignals
+	// Remove убирает ещё не взятую job из очереди (lane или scheduled); если job там нет — не ошибка.
+	Remove(ctx context.Context, jobID string) error
+	LeaseTTL() time.Duration
+}
+
+// CancelSignals — сигнал отмены для worker'а, который выполняет job.
+// Worker раз в ~секунду спрашивает CancelRequested по своим job и отменяет ctx handler'а.
+type CancelSignals interface {
+	SignalCancel(ctx context.Context, jobID string) error
+	CancelRequested(ctx context.Context, jobIDs []string) ([]string, error)
+	// ClearCancel снимает сигнал (перед повторным запуском отменённой job).
+	ClearCancel(ctx context.Context, jobID string) error
+}
+
+// DeadLetters — просмотр и разбор dead-letter очереди (для API).
+type DeadLetters interface {
+	ListDead(ctx context.Context, offset, limit int64) ([]entity.DeadLetter, int64, error)
+	GetDead(ctx context.Context, jobID string) (*entity.DeadLetter, error)
+	RemoveDead(ctx context.Context, jobID string) error
+	PurgeDead(ctx context.Context) (int64, error)
 }
 
 type Lane struct {
 	QueueKey      string
 	ProcessingKey string
+	// ScheduledKey — sorted set отложенных job (score = unix ms, когда job пора выполнять).
+	ScheduledKey string
+}
+
+type RedisQueueConfig struct {
+	ProcessingMapKey string
+	DeadKey          string
+	// CancelKey — префикс ключей сигнала отмены (CancelKey:<job_id>), default "jobs:cancel".
+	CancelKey string
+	// LeaseKey — sorted set lease'ов: member = job_id, score = unix ms истечения lease.
+	LeaseKey string
+	LeaseTTL time.Duration
+
+	Low    Lane
+	Normal Lane
+	High   Lane
 }
 
-// redisPriorityQueue implements a reliable queue with priorities using Redis lists.
+// RedisPriorityQueue implements a reliable queue with priorities using Redis lists.
 // Lanes: high/normal/low.
-// Claim: BRPOPLPUSH lane.queue -> lane.processing
+// Claim: RPOPLPUSH lane.queue -> lane.processing + processing map + lease (leaseKey ZSET, продлевается Heartbeat)
 // Ack:   LREM from correct processing list (stored in processingMapKey hash)
-type redisPriorityQueue struct {
+// Claim/Heartbeat/Ack/Reap/Promote — Lua-скрипты (queue_scripts.go), состояние всегда согласовано.
+// Reaper (RequeueStale) возвращает в очередь только job с истёкшим lease.
+// Delayed jobs (retries) wait in lane.scheduled ZSET until PromoteDue moves them to lane.queue.
+// Dead letters: redisDeadLetters (list deadKey + hash deadKey:info).
+// Cancel: redisCancelSignals (ключ cancelKey:<job_id> с TTL).
+type RedisPriorityQueue struct {
+	redisDeadLetters
+	redisCancelSignals
+
 	rdb              *redis.Client
 	processingMapKey string
+	leaseKey         string
+	leaseTTL         time.Duration
 
 	low    Lane
 	normal Lane
 	high   Lane
 }
 
-func NewRedisPriorityQueue(rdb *redis.Client, processingMapKey string, low, normal, high Lane) Queue {
-	return &redisPriorityQueue{
-		rdb:              rdb,
-		processingMapKey: processingMapKey,
-		low:              low,
-		normal:           normal,
-		high:             high,
+// NewRedisPriorityQueue returns queue; it also implements DeadLetters.
+func NewRedisPriorityQueue(rdb *redis.Client, cfg RedisQueueConfig) *RedisPriorityQueue {
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = entity.DefaultLeaseTTL
+	}
+	return &RedisPriorityQueue{
+		redisDeadLetters:   redisDeadLetters{rdb: rdb, key: cfg.DeadKey},
+		redisCancelSignals: newRedisCancelSignals(rdb, cfg.CancelKey),
+		rdb:                rdb,
+		processingMapKey:   cfg.ProcessingMapKey,
+		leaseKey:           cfg.LeaseKey,
+		leaseTTL:           cfg.LeaseTTL,
+		low:                cfg.Low,
+		normal:             cfg.Normal,
+		high:               cfg.High,
 	}
 }
 
-func clampPriority(p int) int {
-	if p < 0 {
-		return 0
-	}
-	if p > 2 {
-		return 2
-	}
-	return p
+// LeaseTTL returns visibility timeout (worker heartbeat должен быть заметно чаще).
+func (q *RedisPriorityQueue) LeaseTTL() time.Duration {
+	return q.leaseTTL
 }
 
-func (q *redisPriorityQueue) laneByPriority(p int) Lane {
-	switch clampPriority(p) {
+func (q *RedisPriorityQueue) laneByPriority(p int) Lane {
+	switch entity.ClampPriority(p) {
 	case 2:
 		return q.high
 	case 1:
@@ -64,101 +127,158 @@ func (q *redisPriorityQueue) laneByPriority(p int) Lane {
 	}
 }
 
-func (q *redisPriorityQueue) Enqueue(ctx context.Context, jobID string, priority int) error {
+func (q *RedisPriorityQueue) Enqueue(ctx context.Context, jobID string, priority int) error {
 	ln := q.laneByPriority(priority)
 	return q.rdb.LPush(ctx, ln.QueueKey, jobID).Err()
 }
 
-// ClaimBlocking tries high->normal->low with small blocking slots,
-// so it is "mostly blocking" but still respects priority.
-func (q *redisPriorityQueue) ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error) {
+// EnqueueAt puts job into lane's scheduled set; it becomes visible to workers after PromoteDue.
+func (q *RedisPriorityQueue) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	if !at.After(time.Now()) {
+		return q.Enqueue(ctx, jobID, priority)
+	}
+	ln := q.laneByPriority(priority)
+	return q.rdb.ZAdd(ctx, ln.ScheduledKey, redis.Z{Score: float64(at.UnixMilli()), Member: jobID}).Err()
+}
+
+// PromoteDue moves due jobs from scheduled sets to lane queues (atomically per lane, see promoteScript).
+func (q *RedisPriorityQueue) PromoteDue(ctx context.Context, maxPerLane int64) (int64, error) {
+	var moved int64
+	now := time.Now().UnixMilli()
+
+	for _, ln := range q.lanes() {
+		n, err := promoteScript.Run(ctx, q.rdb, []string{ln.ScheduledKey, ln.QueueKey}, now, maxPerLane).Int64()
+		if err != nil {
+			return moved, err
+		}
+		moved += n
+	}
+
+	return moved, nil
+}
+
+// claimPollInterval — пауза между попытками claim, когда все lane пусты.
+const claimPollInterval = 200 * time.Millisecond
+
+// ClaimBlocking polls claimScript (high->normal->low in one round trip) until a job appears
+// or timeout expires. Claim, processing map and lease пишутся атомарно.
+func (q *RedisPriorityQueue) ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error) {
 	// if timeout <= 0, loop forever (like a worker daemon)
 	forever := timeout <= 0
 	deadline := time.Now().Add(timeout)
 
-	slot := 1 * time.Second
-	if !forever && timeout < slot {
-		slot = timeout
-	}
-
 	for {
-		// stop if timed out
-		if !forever && time.Now().After(deadline) {
-			return "", redis.Nil
+		id, err := q.Claim(ctx)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return "", err
 		}
 
-		// try lanes in priority order
-		for _, ln := range []Lane{q.high, q.normal, q.low} {
-			wait := slot
-			if !forever {
-				remain := time.Until(deadline)
-				if remain <= 0 {
-					return "", redis.Nil
-				}
-				if remain < wait {
-					wait = remain
-				}
+		wait := claimPollInterval
+		if !forever {
+			remain := time.Until(deadline)
+			if remain <= 0 {
+				return "", redis.Nil
 			}
-
-			id, err := q.rdb.BRPopLPush(ctx, ln.QueueKey, ln.ProcessingKey, wait).Result()
-			if err == nil {
-				// remember which processing list holds this id (for Ack)
-				if hErr := q.rdb.HSet(ctx, q.processingMapKey, id, ln.ProcessingKey).Err(); hErr != nil {
-					// can't safely ack later => return error
-					return "", hErr
-				}
-				return id, nil
+			if remain < wait {
+				wait = remain
 			}
+		}
 
-			if errors.Is(err, redis.Nil) {
-				// nothing in this lane during wait slot
-				continue
-			}
-			return "", err
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(wait):
 		}
 	}
 }
 
-func (q *redisPriorityQueue) Ack(ctx context.Context, jobID string) error {
-	processingKey, err := q.rdb.HGet(ctx, q.processingMapKey, jobID).Result()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			// mapping is missing (e.g. old jobs or manual вмешательство) — попробуем удалить из всех processing
-			_ = q.rdb.LRem(ctx, q.high.ProcessingKey, 1, jobID).Err()
-			_ = q.rdb.LRem(ctx, q.normal.ProcessingKey, 1, jobID).Err()
-			_ = q.rdb.LRem(ctx, q.low.ProcessingKey, 1, jobID).Err()
-			return nil
-		}
-		return err
+// Claim is a non-blocking claim; returns redis.Nil if all lanes are empty.
+func (q *RedisPriorityQueue) Claim(ctx context.Context) (string, error) {
+	keys := []string{q.processingMapKey, q.leaseKey}
+	for _, ln := range q.lanes() {
+		keys = append(keys, ln.QueueKey, ln.ProcessingKey)
 	}
+	return claimScript.Run(ctx, q.rdb, keys, q.leaseExpiry()).Text()
+}
 
-	if err := q.rdb.LRem(ctx, processingKey, 1, jobID).Err(); err != nil {
+func (q *RedisPriorityQueue) leaseExpiry() int64 {
+	return time.Now().Add(q.leaseTTL).UnixMilli()
+}
+
+// Heartbeat продлевает lease job'а ещё на leaseTTL.
+// Если reaper уже вернул job в очередь (или она ACK'нута) — entity.ErrLeaseLost, lease не создаётся заново.
+func (q *RedisPriorityQueue) Heartbeat(ctx context.Context, jobID string) error {
+	ok, err := heartbeatScript.Run(ctx, q.rdb, []string{q.processingMapKey, q.leaseKey}, jobID, q.leaseExpiry()).Int()
+	if err != nil {
 		return err
 	}
-	_ = q.rdb.HDel(ctx, q.processingMapKey, jobID).Err()
+	if ok == 0 {
+		return entity.ErrLeaseLost
+	}
 	return nil
 }
 
-// RequeueStale moves items from processing back to queue per lane.
-// It's a simple "reaper": at-least-once delivery.
-func (q *redisPriorityQueue) RequeueStale(ctx context.Context, maxPerLane int64) (int64, error) {
-	var moved int64
+func (q *RedisPriorityQueue) Ack(ctx context.Context, jobID string) error {
+	keys := []string{q.processingMapKey, q.leaseKey}
+	for _, ln := range q.lanes() {
+		keys = append(keys, ln.ProcessingKey)
+	}
+	return ackScript.Run(ctx, q.rdb, keys, jobID).Err()
+}
 
-	for _, ln := range []Lane{q.high, q.normal, q.low} {
-		for i := int64(0); i < maxPerLane; i++ {
-			id, err := q.rdb.RPopLPush(ctx, ln.ProcessingKey, ln.QueueKey).Result()
-			if err != nil {
-				if errors.Is(err, redis.Nil) {
-					break
-				}
-				return moved, err
-			}
-			if id != "" {
-				moved++
-				_ = q.rdb.HDel(ctx, q.processingMapKey, id).Err()
-			}
+// RequeueStale moves jobs whose lease expired (worker умер / завис без heartbeat)
+// from processing back to their lane queue. Job'ы с живым lease не трогает.
+// At-least-once delivery: job может быть выполнена повторно только после истечения lease.
+func (q *RedisPriorityQueue) RequeueStale(ctx context.Context, limit int64) (int64, error) {
+	keys := []string{q.processingMapKey, q.leaseKey}
+	for _, ln := range q.lanes() {
+		keys = append(keys, ln.QueueKey, ln.ProcessingKey)
+	}
+	return reapScript.Run(ctx, q.rdb, keys, time.Now().UnixMilli(), limit).Int64()
+}
+
+// Remove убирает job из очереди и scheduled set всех lanes (MULTI); взятую worker'ом job не трогает.
+func (q *RedisPriorityQueue) Remove(ctx context.Context, jobID string) error {
+	_, err := q.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, ln := range q.lanes() {
+			p.LRem(ctx, ln.QueueKey, 0, jobID)
+			p.ZRem(ctx, ln.ScheduledKey, jobID)
 		}
+		return nil
+	})
+	return err
+}
+
+// Tracked reports which of jobIDs the queue knows about (queued, scheduled or claimed).
+func (q *RedisPriorityQueue) Tracked(ctx context.Context, jobIDs []string) (map[string]bool, error) {
+	out := make(map[string]bool, len(jobIDs))
+	if len(jobIDs) == 0 {
+		return out, nil
 	}
 
-	return moved, nil
+	keys := []string{q.processingMapKey}
+	for _, ln := range q.lanes() {
+		keys = append(keys, ln.QueueKey, ln.ScheduledKey)
+	}
+	args := make([]any, len(jobIDs))
+	for i, id := range jobIDs {
+		args[i] = id
+	}
+
+	found, err := trackedScript.Run(ctx, q.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range jobIDs {
+		out[id] = found[i] == 1
+	}
+	return out, nil
+}
+
+// lanes returns lanes in priority order.
+func (q *RedisPriorityQueue) lanes() []Lane {
+	return []Lane{q.high, q.normal, q.low}
 }
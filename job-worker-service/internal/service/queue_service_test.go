@@ -0,0 +1,277 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+func newTestQueue(t *testing.T, leaseTTL time.Duration) (*service.RedisPriorityQueue, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	lane := func(name string) service.Lane {
+		return service.Lane{
+			QueueKey:      "jobs:queue:" + name,
+			ProcessingKey: "jobs:processing:" + name,
+			ScheduledKey:  "jobs:scheduled:" + name,
+		}
+	}
+
+	q := service.NewRedisPriorityQueue(rdb, service.RedisQueueConfig{
+		ProcessingMapKey: "jobs:processing:map",
+		DeadKey:          "jobs:dead",
+		LeaseKey:         "jobs:processing:leases",
+		LeaseTTL:         leaseTTL,
+		Low:              lane("low"),
+		Normal:           lane("normal"),
+		High:             lane("high"),
+	})
+	return q, mr
+}
+
+func TestRedisQueue_ClaimRespectsPriorityAndRecordsLease(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, time.Minute)
+
+	_ = q.Enqueue(ctx, "low-1", 0)
+	_ = q.Enqueue(ctx, "normal-1", 1)
+	_ = q.Enqueue(ctx, "high-1", 2)
+
+	for _, want := range []string{"high-1", "normal-1", "low-1"} {
+		id, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if id != want {
+			t.Fatalf("expected %s, got %s", want, id)
+		}
+	}
+
+	if _, err := q.Claim(ctx); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil on empty queue, got %v", err)
+	}
+
+	// claim атомарно пишет processing list + map + lease
+	if got := mr.HGet("jobs:processing:map", "high-1"); got != "jobs:processing:high" {
+		t.Fatalf("expected mapping to high processing list, got %q", got)
+	}
+	if !mr.Exists("jobs:processing:leases") {
+		t.Fatalf("expected lease set")
+	}
+	if score, err := mr.ZScore("jobs:processing:leases", "high-1"); err != nil || score <= float64(time.Now().UnixMilli()) {
+		t.Fatalf("expected lease in the future, got %v (err=%v)", score, err)
+	}
+}
+
+func TestRedisQueue_AckCleansEverything(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, time.Minute)
+
+	_ = q.Enqueue(ctx, "job-1", 1)
+	id, err := q.Claim(ctx)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	if err := q.Ack(ctx, id); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	if mr.Exists("jobs:processing:normal") {
+		list, _ := mr.List("jobs:processing:normal")
+		if len(list) != 0 {
+			t.Fatalf("expected empty processing list, got %v", list)
+		}
+	}
+	if mr.HGet("jobs:processing:map", id) != "" {
+		t.Fatalf("expected mapping removed")
+	}
+	if _, err := mr.ZScore("jobs:processing:leases", id); err == nil {
+		t.Fatalf("expected lease removed")
+	}
+
+	// heartbeat после ACK — lease потерян
+	if err := q.Heartbeat(ctx, id); !errors.Is(err, entity.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+}
+
+func TestRedisQueue_ReaperRequeuesOnlyExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, 100*time.Millisecond)
+
+	_ = q.Enqueue(ctx, "alive", 2)
+	_ = q.Enqueue(ctx, "dead-worker", 2)
+
+	if _, err := q.Claim(ctx); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if _, err := q.Claim(ctx); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := q.Heartbeat(ctx, "alive"); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// "dead-worker" без heartbeat уже истёк, "alive" — продлён
+	n, err := q.RequeueStale(ctx, 100)
+	if err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 requeued job, got %d", n)
+	}
+
+	queued, _ := mr.List("jobs:queue:high")
+	if len(queued) != 1 || queued[0] != "dead-worker" {
+		t.Fatalf("expected dead-worker back in queue, got %v", queued)
+	}
+	processing, _ := mr.List("jobs:processing:high")
+	if len(processing) != 1 || processing[0] != "alive" {
+		t.Fatalf("expected alive still processing, got %v", processing)
+	}
+	if mr.HGet("jobs:processing:map", "dead-worker") != "" {
+		t.Fatalf("expected mapping of reaped job removed")
+	}
+
+	// reaped job уже не может продлить lease
+	if err := q.Heartbeat(ctx, "dead-worker"); !errors.Is(err, entity.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+}
+
+func TestRedisQueue_PromoteDue(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, time.Minute)
+
+	_ = q.EnqueueAt(ctx, "soon", 1, time.Now().Add(50*time.Millisecond))
+	_ = q.EnqueueAt(ctx, "later", 1, time.Now().Add(time.Hour))
+
+	if _, err := q.Claim(ctx); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected nothing to claim before due, got %v", err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	n, err := q.PromoteDue(ctx, 100)
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 promoted, got %d", n)
+	}
+
+	id, err := q.Claim(ctx)
+	if err != nil || id != "soon" {
+		t.Fatalf("expected to claim 'soon', got %q (err=%v)", id, err)
+	}
+	if members, _ := mr.ZMembers("jobs:scheduled:normal"); len(members) != 1 || members[0] != "later" {
+		t.Fatalf("expected 'later' still scheduled, got %v", members)
+	}
+}
+
+func TestRedisQueue_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(t, time.Minute)
+
+	_ = q.DeadLetter(ctx, "a", "boom")
+	_ = q.DeadLetter(ctx, "b", "invalid job id")
+	_ = q.DeadLetter(ctx, "a", "boom again") // без дублей
+
+	items, total, err := q.ListDead(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 2 || len(items) != 2 || items[0].JobID != "a" || items[0].Reason != "boom again" {
+		t.Fatalf("unexpected dead letters: total=%d items=%+v", total, items)
+	}
+
+	if err := q.RemoveDead(ctx, "a"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := q.GetDead(ctx, "a"); !errors.Is(err, entity.ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+
+	n, err := q.PurgeDead(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected purge of 1, got %d (err=%v)", n, err)
+	}
+}
+
+func TestRedisQueue_Tracked(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, time.Minute)
+
+	_ = q.Enqueue(ctx, "queued", 0)
+	_ = q.EnqueueAt(ctx, "scheduled", 2, time.Now().Add(time.Hour))
+	_ = q.Enqueue(ctx, "claimed", 2)
+	if id, err := q.Claim(ctx); err != nil || id != "claimed" {
+		t.Fatalf("expected to claim 'claimed', got %q (err=%v)", id, err)
+	}
+	_ = q.Enqueue(ctx, "flushed", 1)
+	mr.Del("jobs:queue:normal")
+
+	got, err := q.Tracked(ctx, []string{"queued", "scheduled", "claimed", "flushed", "unknown"})
+	if err != nil {
+		t.Fatalf("tracked: %v", err)
+	}
+	for id, want := range map[string]bool{"queued": true, "scheduled": true, "claimed": true, "flushed": false, "unknown": false} {
+		if got[id] != want {
+			t.Fatalf("tracked[%s]: expected %v, got %v", id, want, got[id])
+		}
+	}
+}
+
+func TestRedisQueue_RemoveAndCancelSignals(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, time.Minute)
+
+	_ = q.Enqueue(ctx, "a", 1)
+	_ = q.Enqueue(ctx, "b", 1)
+	_ = q.EnqueueAt(ctx, "later", 2, time.Now().Add(time.Hour))
+
+	if err := q.Remove(ctx, "a"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := q.Remove(ctx, "later"); err != nil {
+		t.Fatalf("remove scheduled: %v", err)
+	}
+	if items, _ := mr.List("jobs:queue:normal"); len(items) != 1 || items[0] != "b" {
+		t.Fatalf("expected only 'b' in lane, got %v", items)
+	}
+	if members, _ := mr.ZMembers("jobs:scheduled:high"); len(members) != 0 {
+		t.Fatalf("expected scheduled set empty, got %v", members)
+	}
+
+	if err := q.SignalCancel(ctx, "b"); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	ids, err := q.CancelRequested(ctx, []string{"a", "b"})
+	if err != nil || len(ids) != 1 || ids[0] != "b" {
+		t.Fatalf("expected cancel requested for b, got %v (err=%v)", ids, err)
+	}
+	if ttl := mr.TTL("jobs:cancel:b"); ttl <= 0 {
+		t.Fatalf("expected cancel signal with TTL, got %s", ttl)
+	}
+
+	if err := q.ClearCancel(ctx, "b"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if ids, _ := q.CancelRequested(ctx, []string{"b"}); len(ids) != 0 {
+		t.Fatalf("expected cancel signal cleared, got %v", ids)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// ReconcileAction — что делать с job, которую БД считает pending/processing, а очередь не знает.
+type ReconcileAction string
+
+const (
+	ReconcileRequeue ReconcileAction = "requeue" // поставить обратно в очередь
+	ReconcileFail    ReconcileAction = "fail"    // status=error
+)
+
+func ParseReconcileAction(s string) (ReconcileAction, error) {
+	switch a := ReconcileAction(s); a {
+	case ReconcileRequeue, ReconcileFail:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown reconcile action %q (want requeue or fail)", s)
+	}
+}
+
+// ReconcilePolicy: job считается "потерянной", если не обновлялась дольше порога
+// и её нет ни в одной lane / scheduled set / processing.
+type ReconcilePolicy struct {
+	PendingAfter    time.Duration
+	ProcessingAfter time.Duration
+	Action          ReconcileAction
+	BatchSize       int
+}
+
+// Порт репозитория для reconciler'а (реализация: postgresql.JobRepository)
+type ReconcileRepository interface {
+	// ListStale: по (updated_at, id), после after (nil — с начала).
+	ListStale(ctx context.Context, status entity.JobStatus, updatedBefore time.Time, after *entity.StaleCursor, limit int) ([]*entity.Job, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+	// SetResultError: entity.ErrStaleAttempt / entity.ErrInvalidTransition, если job изменилась после GetByID (token — её FenceToken).
+	SetResultError(ctx context.Context, id uuid.UUID, token int64, errText string) error
+}
+
+// QueueInspector — очередь, которая умеет сказать, знает ли она о job
+// (реализации: RedisPriorityQueue, RedisStreamQueue, memory.Queue).
+type QueueInspector interface {
+	JobQueue
+	Tracked(ctx context.Context, jobIDs []string) (map[string]bool, error)
+}
+
+// PassLock — lock прохода на все процессы (реализация: postgresql.AdvisoryLock): reconciler запущен в каждой
+// реплике worker'а, а Tracked + Enqueue не атомарны — без lock N реплик поставили бы одну job N раз.
+type PassLock interface {
+	// TryLock не ждёт: ok=false — lock держит другой процесс.
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+}
+
+type ReconcileReport struct {
+	Requeued int
+	Failed   int
+}
+
+// Reconciler сверяет таблицу jobs с очередью: после FLUSHALL / рестарта Redis без persistence
+// pending и processing job остаются в БД, но их id уже нет в очереди — без сверки они висят вечно.
+// Проход смотрит BatchSize job каждого статуса и продолжает со следующего прохода с того же места:
+// job, которые очередь знает (и чей updated_at не меняется), не закрывают собой остальные.
+// Reconcile не вызывается параллельно в одном процессе; между репликами проход держит lock.
+type Reconciler struct {
+	repo   ReconcileRepository
+	queue  QueueInspector
+	policy ReconcilePolicy
+	lock   PassLock
+
+	// cursors — где продолжить ListStale для статуса; nil — с начала.
+	cursors map[entity.JobStatus]*entity.StaleCursor
+}
+
+// NewReconciler: lock может быть nil — тогда reconciler должен быть единственным (один процесс).
+func NewReconciler(repo ReconcileRepository, queue QueueInspector, policy ReconcilePolicy, lock PassLock) *Reconciler {
+	if policy.Action == "" {
+		policy.Action = ReconcileRequeue
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 100
+	}
+	return &Reconciler{repo: repo, queue: queue, policy: policy, lock: lock, cursors: make(map[entity.JobStatus]*entity.StaleCursor)}
+}
+
+// Reconcile делает один проход: pending старше PendingAfter и processing старше ProcessingAfter.
+// Если lock держит другая реплика, проход пропускается (пустой report).
+func (r *Reconciler) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	var report ReconcileReport
+	if r.lock != nil {
+		unlock, ok, err := r.lock.TryLock(ctx)
+		if err != nil {
+			return report, err
+		}
+		if !ok {
+			return report, nil
+		}
+		defer unlock()
+	}
+	ctx = entity.WithActor(ctx, entity.ActorReconciler)
+
+	now := time.Now()
+	for _, st := range []struct {
+		status entity.JobStatus
+		after  time.Duration
+	}{
+		{entity.StatusPending, r.policy.PendingAfter},
+		{entity.StatusProcessing, r.policy.ProcessingAfter},
+	} {
+		jobs, err := r.repo.ListStale(ctx, st.status, now.Add(-st.after), r.cursors[st.status], r.policy.BatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(jobs) == 0 {
+			r.cursors[st.status] = nil
+			continue
+		}
+
+		ids := make([]string, len(jobs))
+		for i, j := range jobs {
+			ids[i] = j.ID.String()
+		}
+		tracked, err := r.queue.Tracked(ctx, ids)
+		if err != nil {
+			return report, err
+		}
+
+		for _, j := range jobs {
+			if tracked[j.ID.String()] {
+				continue
+			}
+			if err := r.fix(ctx, j, &report); err != nil {
+				return report, err
+			}
+		}
+
+		// неполная страница — дошли до конца, следующий проход снова с начала
+		if len(jobs) < r.policy.BatchSize {
+			r.cursors[st.status] = nil
+		} else {
+			last := jobs[len(jobs)-1]
+			r.cursors[st.status] = &entity.StaleCursor{UpdatedAt: last.UpdatedAt, ID: last.ID}
+		}
+	}
+
+	return report, nil
+}
+
+func (r *Reconciler) fix(ctx context.Context, stale *entity.Job, report *ReconcileReport) error {
+	// job могли обработать между ListStale и Tracked — трогаем только если она не менялась
+	cur, err := r.repo.GetByID(ctx, stale.ID)
+	if err != nil {
+		return err
+	}
+	if cur.Status != stale.Status || !cur.UpdatedAt.Equal(stale.UpdatedAt) {
+		return nil
+	}
+
+	id := cur.ID.String()
+
+	if r.policy.Action == ReconcileFail {
+		err := r.repo.SetResultError(ctx, cur.ID, cur.FenceToken, "lost from queue (status "+string(cur.Status)+")")
+		if errors.Is(err, entity.ErrStaleAttempt) || errors.Is(err, entity.ErrInvalidTransition) {
+			return nil // worker взял job (или её завершили) после GetByID
+		}
+		if err != nil {
+			return err
+		}
+		report.Failed++
+		log.Printf("[reconciler] job_id=%s status=%s action=fail", id, cur.Status)
+		return nil
+	}
+
+	if cur.RunAt != nil {
+		err = r.queue.EnqueueAt(ctx, id, cur.Priority, *cur.RunAt)
+	} else {
+		err = r.queue.Enqueue(ctx, id, cur.Priority)
+	}
+	if err != nil {
+		return err
+	}
+	report.Requeued++
+	log.Printf("[reconciler] job_id=%s status=%s action=requeue", id, cur.Status)
+	return nil
+}
@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/memory"
+	"job-worker-service/internal/service"
+)
+
+// createLost создаёт job в БД в статусе status, но не ставит её в очередь (как после FLUSHALL).
+func createLost(t *testing.T, repo *memory.JobRepository, status entity.JobStatus) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := repo.Create(ctx, &entity.Job{Type: "echo", Priority: 2})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if status == entity.StatusProcessing {
+		if _, err := repo.StartAttempt(ctx, id, time.Minute); err != nil {
+			t.Fatalf("start attempt: %v", err)
+		}
+	}
+	return id
+}
+
+func TestReconciler_RequeuesLostPendingAndKeepsTracked(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	lost := createLost(t, repo, entity.StatusPending)
+	tracked := createLost(t, repo, entity.StatusPending)
+	_ = queue.Enqueue(ctx, tracked.String(), 2)
+
+	r := service.NewReconciler(repo, queue, service.ReconcilePolicy{}, nil)
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Requeued != 1 || report.Failed != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	pending := queue.Pending(2)
+	if len(pending) != 2 || pending[0] != tracked.String() || pending[1] != lost.String() {
+		t.Fatalf("expected [tracked lost] in high lane, got %v", pending)
+	}
+
+	// повторный проход ничего не делает: обе job уже в очереди
+	if report, _ := r.Reconcile(ctx); report.Requeued != 0 {
+		t.Fatalf("expected nothing on second pass, got %+v", report)
+	}
+}
+
+func TestReconciler_SkipsFreshJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	createLost(t, repo, entity.StatusPending)
+
+	// job могла только что попасть в outbox / ещё не дойти до очереди
+	r := service.NewReconciler(repo, queue, service.ReconcilePolicy{PendingAfter: time.Hour}, nil)
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Requeued != 0 || len(queue.Pending(2)) != 0 {
+		t.Fatalf("expected fresh job to be left alone, got %+v", report)
+	}
+}
+
+func TestReconciler_FailsLostProcessing(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	lost := createLost(t, repo, entity.StatusProcessing)
+	claimed := createLost(t, repo, entity.StatusProcessing)
+	_ = queue.Enqueue(ctx, claimed.String(), 2)
+	if _, err := queue.Claim(ctx); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	r := service.NewReconciler(repo, queue, service.ReconcilePolicy{Action: service.ReconcileFail}, nil)
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Failed != 1 || report.Requeued != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	if j := mustGetJob(t, repo, lost); j.Status != entity.StatusError || j.Error == nil {
+		t.Fatalf("expected lost job to be error, got status=%s error=%v", j.Status, j.Error)
+	}
+	if j := mustGetJob(t, repo, claimed); j.Status != entity.StatusProcessing {
+		t.Fatalf("expected claimed job untouched, got %s", j.Status)
+	}
+}
+
+func TestParseReconcileAction(t *testing.T) {
+	if a, err := service.ParseReconcileAction("fail"); err != nil || a != service.ReconcileFail {
+		t.Fatalf("expected fail, got %q (err=%v)", a, err)
+	}
+	if _, err := service.ParseReconcileAction("drop"); err == nil {
+		t.Fatalf("expected error for unknown action")
+	}
+}
+
+func TestReconciler_BatchesMovePastTrackedJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+
+	// самые старые job очередь знает; потерянная — за ними
+	for range 3 {
+		id := createLost(t, repo, entity.StatusPending)
+		_ = queue.Enqueue(ctx, id.String(), 2)
+		time.Sleep(time.Millisecond)
+	}
+	lost := createLost(t, repo, entity.StatusPending)
+
+	r := service.NewReconciler(repo, queue, service.ReconcilePolicy{BatchSize: 2}, nil)
+	requeued := 0
+	for range 2 {
+		report, err := r.Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		requeued += report.Requeued
+	}
+	if requeued != 1 {
+		t.Fatalf("expected lost job requeued within 2 passes, got %d", requeued)
+	}
+	if pending := queue.Pending(2); pending[len(pending)-1] != lost.String() {
+		t.Fatalf("expected lost job at the tail of high lane, got %v", pending)
+	}
+}
+
+// lockStub — PassLock; held — lock держит другая реплика.
+type lockStub struct {
+	held     bool
+	unlocked int
+}
+
+func (l *lockStub) TryLock(ctx context.Context) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	return func() { l.unlocked++ }, true, nil
+}
+
+func TestReconciler_SkipsPassWhenLockHeldByAnotherReplica(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	lost := createLost(t, repo, entity.StatusPending)
+
+	lock := &lockStub{held: true}
+	r := service.NewReconciler(repo, queue, service.ReconcilePolicy{}, lock)
+	if report, err := r.Reconcile(ctx); err != nil || report.Requeued != 0 {
+		t.Fatalf("expected pass skipped, got %+v, %v", report, err)
+	}
+	if pending := queue.Pending(2); len(pending) != 0 {
+		t.Fatalf("expected nothing enqueued, got %v", pending)
+	}
+
+	lock.held = false
+	if report, err := r.Reconcile(ctx); err != nil || report.Requeued != 1 {
+		t.Fatalf("expected lost job requeued, got %+v, %v", report, err)
+	}
+	if pending := queue.Pending(2); len(pending) != 1 || pending[0] != lost.String() {
+		t.Fatalf("expected lost job in queue, got %v", pending)
+	}
+	if lock.unlocked != 1 {
+		t.Fatalf("expected lock released once, got %d", lock.unlocked)
+	}
+}
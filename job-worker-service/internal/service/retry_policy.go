@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"job-worker-service/internal/entity"
+)
+
+// DefaultRetryPolicy используется, если для типа job ничего не настроено.
+var DefaultRetryPolicy = entity.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   1 * time.Second,
+	MaxDelay:    1 * time.Minute,
+}
+
+// RetryPolicies — политика ретраев по умолчанию + переопределения по типу job.
+// Нулевое значение валидно: везде используется DefaultRetryPolicy.
+type RetryPolicies struct {
+	Default entity.RetryPolicy
+	ByType  map[string]entity.RetryPolicy
+}
+
+// For returns effective policy for job type; zero fields are filled from defaults.
+func (p RetryPolicies) For(typ string) entity.RetryPolicy {
+	def := mergeRetryPolicy(DefaultRetryPolicy, p.Default)
+	if byType, ok := p.ByType[typ]; ok {
+		return mergeRetryPolicy(def, byType)
+	}
+	return def
+}
+
+// mergeRetryPolicy overrides base with non-zero fields of override.
+func mergeRetryPolicy(base, override entity.RetryPolicy) entity.RetryPolicy {
+	if override.MaxAttempts > 0 {
+		base.MaxAttempts = override.MaxAttempts
+	}
+	if override.BaseDelay > 0 {
+		base.BaseDelay = override.BaseDelay
+	}
+	if override.MaxDelay > 0 {
+		base.MaxDelay = override.MaxDelay
+	}
+	if override.TimeoutMaxAttempts > 0 {
+		base.TimeoutMaxAttempts = override.TimeoutMaxAttempts
+	}
+	if base.MaxDelay < base.BaseDelay {
+		base.MaxDelay = base.BaseDelay
+	}
+	return base
+}
+
+// ParseRetryPolicies parses per-type policies from a string like
+//
+//	convert_video:5:2s:5m:1,generate_report:3:1s:1m,echo:1
+//
+// Format of one item: type:max_attempts[:base_delay[:max_delay[:timeout_max_attempts]]].
+func ParseRetryPolicies(spec string) (map[string]entity.RetryPolicy, error) {
+	out := map[string]entity.RetryPolicy{}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 5 || parts[0] == "" {
+			return nil, fmt.Errorf("retry policy %q: expected type:max_attempts[:base_delay[:max_delay[:timeout_max_attempts]]]", item)
+		}
+
+		var p entity.RetryPolicy
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("retry policy %q: invalid max_attempts", item)
+		}
+		p.MaxAttempts = n
+
+		if len(parts) > 2 {
+			if p.BaseDelay, err = time.ParseDuration(parts[2]); err != nil {
+				return nil, fmt.Errorf("retry policy %q: invalid base_delay: %w", item, err)
+			}
+		}
+		if len(parts) > 3 {
+			if p.MaxDelay, err = time.ParseDuration(parts[3]); err != nil {
+				return nil, fmt.Errorf("retry policy %q: invalid max_delay: %w", item, err)
+			}
+		}
+		if len(parts) > 4 {
+			n, err := strconv.Atoi(parts[4])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("retry policy %q: invalid timeout_max_attempts", item)
+			}
+			p.TimeoutMaxAttempts = n
+		}
+
+		out[parts[0]] = p
+	}
+
+	return out, nil
+}
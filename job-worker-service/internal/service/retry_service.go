@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+var (
+	// ErrNotRetryable — повторить можно только завершившуюся неуспехом job (error / dead / canceled).
+	ErrNotRetryable = errors.New("job can be retried only in status error, dead or canceled")
+	// ErrInvalidPriority — priority вне 0..2 (HTTP 400).
+	ErrInvalidPriority = errors.New("priority must be 0, 1 or 2")
+)
+
+// Порт репозитория для ручного повтора (реализация: postgresql.JobRepository)
+type RetryRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+	// ResetToPending: entity.ErrNotFound — job нет, entity.ErrInvalidTransition — она не error/dead/canceled.
+	ResetToPending(ctx context.Context, id uuid.UUID, priority int) error
+}
+
+// RetryQueueState — следы прошлого запуска в очереди (реализация: QueueBackend, не outbox).
+type RetryQueueState interface {
+	ClearCancel(ctx context.Context, jobID string) error
+	RemoveDead(ctx context.Context, jobID string) error
+}
+
+// RetryService — ручной повтор job через API (POST /jobs/{id}/retry).
+// Автоматические ретраи делает worker по entity.RetryPolicy.
+type RetryService struct {
+	repo  RetryRepository
+	queue JobQueue
+	tx    Transactor
+	state RetryQueueState
+}
+
+// NewRetryService: queue и tx — как у JobService (outbox в app), state — сама очередь.
+func NewRetryService(repo RetryRepository, queue JobQueue, tx Transactor, state RetryQueueState) *RetryService {
+	return &RetryService{repo: repo, queue: queue, tx: tx, state: state}
+}
+
+// Retry сбрасывает job (pending, attempts=0, без ошибки и результата) и ставит её в очередь
+// с исходным priority или с priority, если он задан.
+func (s *RetryService) Retry(ctx context.Context, id uuid.UUID, priority *int) (*entity.Job, error) {
+	if priority != nil && (*priority < 0 || *priority > 2) {
+		return nil, ErrInvalidPriority
+	}
+	job, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !retryable(job.Status) {
+		return nil, ErrNotRetryable
+	}
+
+	p := job.Priority
+	if priority != nil {
+		p = *priority
+	}
+
+	jobID := id.String()
+	// иначе worker сразу отменит job по старому сигналу
+	if job.Status == entity.StatusCanceled {
+		if err := s.state.ClearCancel(ctx, jobID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.ResetToPending(ctx, id, p); err != nil {
+			return err
+		}
+		return s.queue.Enqueue(ctx, jobID, p)
+	})
+	// job могли повторить / перезапустить между GetByID и ResetToPending
+	if errors.Is(err, entity.ErrInvalidTransition) {
+		return nil, ErrNotRetryable
+	}
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status == entity.StatusDead {
+		if err := s.state.RemoveDead(ctx, jobID); err != nil && !errors.Is(err, entity.ErrDeadLetterNotFound) {
+			log.Printf("[retry] job_id=%s remove from dead-letter error=%v", jobID, err)
+		}
+	}
+	log.Printf("[retry] job_id=%s prev_status=%s priority=%d", jobID, job.Status, p)
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *RetryService) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTx(ctx, fn)
+}
+
+func retryable(st entity.JobStatus) bool {
+	return st == entity.StatusError || st == entity.StatusDead || st == entity.StatusCanceled
+}
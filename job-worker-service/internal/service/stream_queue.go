@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"job-worker-service/internal/entity"
+)
+
+// StreamLane — priority lane stream-бэкенда.
+type StreamLane struct {
+	StreamKey string
+	// ScheduledKey — sorted set отложенных job (как у Lane).
+	ScheduledKey string
+}
+
+// streamIndexKey — hash job_id -> id записи в stream (для Remove и Tracked без сканирования stream).
+func streamIndexKey(stream string) string {
+	return stream + ":index"
+}
+
+type RedisStreamQueueConfig struct {
+	Group    string
+	Consumer string // уникальное имя процесса worker'а в группе
+	DeadKey  string
+	LeaseTTL time.Duration
+	// CancelKey — префикс ключей сигнала отмены (CancelKey:<job_id>), default "jobs:cancel".
+	CancelKey string
+
+	Low    StreamLane
+	Normal StreamLane
+	High   StreamLane
+}
+
+// RedisStreamQueue implements Queue on Redis Streams with a consumer group.
+// Lanes: high/normal/low — отдельные streams.
+// Enqueue: XADD lane.stream * job_id <id>; id записи запоминается в hash lane.stream:index.
+// Claim:   XREADGROUP (без блокировки) по lanes в порядке приоритета; запись попадает в PEL consumer'а.
+// Lease:   idle time записи в PEL; Heartbeat сбрасывает его (XCLAIM JUSTID самому себе).
+// Ack:     XACK + XDEL.
+// Reaper:  записи с idle >= leaseTTL передаются consumer'у reclaimConsumer (XCLAIM), Claim забирает их первыми в lane;
+// запись не пересоздаётся, поэтому счётчик доставок (XPENDING) сохраняется.
+type RedisStreamQueue struct {
+	redisDeadLetters
+	redisCancelSignals
+
+	rdb      *redis.Client
+	group    string
+	consumer string
+	leaseTTL time.Duration
+
+	low    StreamLane
+	normal StreamLane
+	high   StreamLane
+
+	mu       sync.Mutex
+	inflight map[string]streamEntry // job_id -> запись в PEL этого consumer'а
+}
+
+// reclaimConsumer — consumer группы, у которого лежат записи с истёкшим lease до следующего Claim.
+const reclaimConsumer = "reclaimed"
+
+type streamEntry struct {
+	stream string
+	id     string
+}
+
+func NewRedisStreamQueue(rdb *redis.Client, cfg RedisStreamQueueConfig) *RedisStreamQueue {
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = entity.DefaultLeaseTTL
+	}
+	return &RedisStreamQueue{
+		redisDeadLetters:   redisDeadLetters{rdb: rdb, key: cfg.DeadKey},
+		redisCancelSignals: newRedisCancelSignals(rdb, cfg.CancelKey),
+		rdb:                rdb,
+		group:              cfg.Group,
+		consumer:           cfg.Consumer,
+		leaseTTL:           cfg.LeaseTTL,
+		low:                cfg.Low,
+		normal:             cfg.Normal,
+		high:               cfg.High,
+		inflight:           map[string]streamEntry{},
+	}
+}
+
+// EnsureGroups creates consumer group on every lane stream (idempotent).
+// Группа читает с начала stream ("0"), чтобы не потерять job, добавленные до старта worker'а.
+func (q *RedisStreamQueue) EnsureGroups(ctx context.Context) error {
+	for _, ln := range q.lanes() {
+		err := q.rdb.XGroupCreateMkStream(ctx, ln.StreamKey, q.group, "0").Err()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *RedisStreamQueue) LeaseTTL() time.Duration {
+	return q.leaseTTL
+}
+
+func (q *RedisStreamQueue) laneByPriority(p int) StreamLane {
+	switch entity.ClampPriority(p) {
+	case 2:
+		return q.high
+	case 1:
+		return q.normal
+	default:
+		return q.low
+	}
+}
+
+// lanes returns lanes in priority order.
+func (q *RedisStreamQueue) lanes() []StreamLane {
+	return []StreamLane{q.high, q.normal, q.low}
+}
+
+func (q *RedisStreamQueue) Enqueue(ctx context.Context, jobID string, priority int) error {
+	ln := q.laneByPriority(priority)
+	return streamEnqueueScript.Run(ctx, q.rdb, []string{ln.StreamKey, streamIndexKey(ln.StreamKey)}, jobID).Err()
+}
+
+func (q *RedisStreamQueue) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	if !at.After(time.Now()) {
+		return q.Enqueue(ctx, jobID, priority)
+	}
+	ln := q.laneByPriority(priority)
+	return q.rdb.ZAdd(ctx, ln.ScheduledKey, redis.Z{Score: float64(at.UnixMilli()), Member: jobID}).Err()
+}
+
+func (q *RedisStreamQueue) PromoteDue(ctx context.Context, maxPerLane int64) (int64, error) {
+	var moved int64
+	now := time.Now().UnixMilli()
+
+	for _, ln := range q.lanes() {
+		n, err := streamPromoteScript.Run(ctx, q.rdb, []string{ln.ScheduledKey, ln.StreamKey, streamIndexKey(ln.StreamKey)}, now, maxPerLane).Int64()
+		if err != nil {
+			return moved, err
+		}
+		moved += n
+	}
+
+	return moved, nil
+}
+
+func (q *RedisStreamQueue) ClaimBlocking(ctx context.Context, timeout time.Duration) (string, error) {
+	// if timeout <= 0, loop forever (like a worker daemon)
+	forever := timeout <= 0
+	deadline := time.Now().Add(timeout)
+
+	for {
+		id, err := q.Claim(ctx)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return "", err
+		}
+
+		wait := claimPollInterval
+		if !forever {
+			remain := time.Until(deadline)
+			if remain <= 0 {
+				return "", redis.Nil
+			}
+			if remain < wait {
+				wait = remain
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Claim reads one entry (high->normal->low) without blocking; redis.Nil if all lanes are empty.
+// В каждой lane сначала — записи, возвращённые reaper'ом, потом новые.
+func (q *RedisStreamQueue) Claim(ctx context.Context) (string, error) {
+	for _, ln := range q.lanes() {
+		msg, ok, err := q.reclaim(ctx, ln)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			msg, ok, err = q.readNew(ctx, ln)
+			if err != nil {
+				return "", err
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		jobID, _ := msg.Values["job_id"].(string)
+		if jobID == "" {
+			// poison: запись без job_id — отдаём id записи, Processor отправит его в dead-letter
+			jobID = ln.StreamKey + "/" + msg.ID
+		}
+
+		q.mu.Lock()
+		q.inflight[jobID] = streamEntry{stream: ln.StreamKey, id: msg.ID}
+		q.mu.Unlock()
+
+		return jobID, nil
+	}
+	return "", redis.Nil
+}
+
+// reclaim takes the oldest entry returned by the reaper to reclaimConsumer.
+func (q *RedisStreamQueue) reclaim(ctx context.Context, ln StreamLane) (redis.XMessage, bool, error) {
+	res, err := streamReclaimScript.Run(ctx, q.rdb, []string{ln.StreamKey}, q.group, reclaimConsumer, q.consumer).Slice()
+	if errors.Is(err, redis.Nil) {
+		return redis.XMessage{}, false, nil
+	}
+	if err != nil {
+		return redis.XMessage{}, false, err
+	}
+	if len(res) != 2 {
+		return redis.XMessage{}, false, errors.New("unexpected reclaim reply")
+	}
+
+	id, _ := res[0].(string)
+	fields, _ := res[1].([]any)
+	msg := redis.XMessage{ID: id, Values: make(map[string]any, len(fields)/2)}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if k, ok := fields[i].(string); ok {
+			msg.Values[k] = fields[i+1]
+		}
+	}
+	return msg, true, nil
+}
+
+// readNew reads one entry never delivered to the group.
+func (q *RedisStreamQueue) readNew(ctx context.Context, ln StreamLane) (redis.XMessage, bool, error) {
+	res, err := q.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: q.consumer,
+		Streams:  []string{ln.StreamKey, ">"},
+		Count:    1,
+		Block:    -1, // не блокироваться
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return redis.XMessage{}, false, nil
+	}
+	if err != nil {
+		return redis.XMessage{}, false, err
+	}
+	if len(res) == 0 || len(res[0].Messages) == 0 {
+		return redis.XMessage{}, false, nil
+	}
+	return res[0].Messages[0], true, nil
+}
+
+func (q *RedisStreamQueue) entry(jobID string) (streamEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.inflight[jobID]
+	return e, ok
+}
+
+func (q *RedisStreamQueue) Heartbeat(ctx context.Context, jobID string) error {
+	e, ok := q.entry(jobID)
+	if !ok {
+		return entity.ErrLeaseLost
+	}
+
+	extended, err := streamHeartbeatScript.Run(ctx, q.rdb, []string{e.stream}, q.group, e.id, q.consumer).Int()
+	if err != nil {
+		return err
+	}
+	if extended == 0 {
+		return entity.ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *RedisStreamQueue) Ack(ctx context.Context, jobID string) error {
+	e, ok := q.entry(jobID)
+	if !ok {
+		return nil
+	}
+
+	if err := streamAckScript.Run(ctx, q.rdb, []string{e.stream, streamIndexKey(e.stream)}, q.group, e.id, jobID).Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	delete(q.inflight, jobID)
+	q.mu.Unlock()
+	return nil
+}
+
+// RequeueStale returns entries idle longer than leaseTTL (consumer died or stopped heartbeats)
+// to reclaimConsumer, откуда их забирает следующий Claim. Живые записи (с heartbeat) не трогает.
+func (q *RedisStreamQueue) RequeueStale(ctx context.Context, limit int64) (int64, error) {
+	var moved int64
+
+	for _, ln := range q.lanes() {
+		n, err := streamReapScript.Run(ctx, q.rdb, []string{ln.StreamKey},
+			q.group, reclaimConsumer, q.leaseTTL.Milliseconds(), limit,
+		).Int64()
+		if err != nil {
+			return moved, err
+		}
+		moved += n
+	}
+
+	return moved, nil
+}
+
+// Remove удаляет запись job из lane stream (XDEL по индексу job_id -> entry id) и из scheduled sets.
+// Индекс хранит последнюю запись job: более старый дубль (повторная публикация outbox) останется,
+// его возьмёт worker и пропустит, как любую уже отменённую job.
+func (q *RedisStreamQueue) Remove(ctx context.Context, jobID string) error {
+	for _, ln := range q.lanes() {
+		if err := streamRemoveScript.Run(ctx, q.rdb, []string{ln.StreamKey, streamIndexKey(ln.StreamKey), ln.ScheduledKey}, jobID).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tracked reports which of jobIDs the queue knows about: job есть в индексе lane (её запись в stream
+// ещё не ACK'нута — в очереди или в PEL) или ждёт в scheduled set. Один pipeline: HMGET по индексам + ZSCORE.
+func (q *RedisStreamQueue) Tracked(ctx context.Context, jobIDs []string) (map[string]bool, error) {
+	out := make(map[string]bool, len(jobIDs))
+	if len(jobIDs) == 0 {
+		return out, nil
+	}
+	for _, id := range jobIDs {
+		out[id] = false
+	}
+
+	var indexed []*redis.SliceCmd
+	scheduled := make(map[string][]*redis.FloatCmd, len(jobIDs))
+	_, err := q.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, ln := range q.lanes() {
+			indexed = append(indexed, p.HMGet(ctx, streamIndexKey(ln.StreamKey), jobIDs...))
+			for _, id := range jobIDs {
+				scheduled[id] = append(scheduled[id], p.ZScore(ctx, ln.ScheduledKey, id))
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	for _, c := range indexed {
+		for i, v := range c.Val() {
+			if v != nil {
+				out[jobIDs[i]] = true
+			}
+		}
+	}
+	for id, cs := range scheduled {
+		for _, c := range cs {
+			if c.Err() == nil {
+				out[id] = true
+			}
+		}
+	}
+	return out, nil
+}
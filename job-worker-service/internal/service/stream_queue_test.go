@@ -0,0 +1,206 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+func newTestStreamQueue(t *testing.T, mr *miniredis.Miniredis, consumer string, leaseTTL time.Duration) *service.RedisStreamQueue {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	lane := func(name string) service.StreamLane {
+		return service.StreamLane{StreamKey: "jobs:stream:" + name, ScheduledKey: "jobs:scheduled:" + name}
+	}
+
+	q := service.NewRedisStreamQueue(rdb, service.RedisStreamQueueConfig{
+		Group:    "workers",
+		Consumer: consumer,
+		DeadKey:  "jobs:dead",
+		LeaseTTL: leaseTTL,
+		Low:      lane("low"),
+		Normal:   lane("normal"),
+		High:     lane("high"),
+	})
+	if err := q.EnsureGroups(context.Background()); err != nil {
+		t.Fatalf("ensure groups: %v", err)
+	}
+	return q
+}
+
+func TestStreamQueue_ClaimRespectsPriorityAndAck(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	q := newTestStreamQueue(t, mr, "w1", time.Minute)
+
+	_ = q.Enqueue(ctx, "low-1", 0)
+	_ = q.Enqueue(ctx, "high-1", 2)
+	_ = q.Enqueue(ctx, "normal-1", 1)
+
+	for _, want := range []string{"high-1", "normal-1", "low-1"} {
+		id, err := q.Claim(ctx)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if id != want {
+			t.Fatalf("expected %s, got %s", want, id)
+		}
+		if err := q.Heartbeat(ctx, id); err != nil {
+			t.Fatalf("heartbeat %s: %v", id, err)
+		}
+		if err := q.Ack(ctx, id); err != nil {
+			t.Fatalf("ack %s: %v", id, err)
+		}
+	}
+
+	if _, err := q.Claim(ctx); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil on empty streams, got %v", err)
+	}
+
+	// ACK'нутые записи удалены из stream
+	for _, lane := range []string{"low", "normal", "high"} {
+		if entries, _ := mr.Stream("jobs:stream:" + lane); len(entries) != 0 {
+			t.Fatalf("expected empty %s stream, got %d entries", lane, len(entries))
+		}
+	}
+}
+
+func TestStreamQueue_ReaperReclaimsOnlyIdleEntries(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	crashed := newTestStreamQueue(t, mr, "crashed", 100*time.Millisecond)
+	alive := newTestStreamQueue(t, mr, "alive", 100*time.Millisecond)
+
+	_ = crashed.Enqueue(ctx, "lost-job", 1)
+	_ = crashed.Enqueue(ctx, "running-job", 1)
+
+	if id, err := crashed.Claim(ctx); err != nil || id != "lost-job" {
+		t.Fatalf("expected lost-job, got %q (err=%v)", id, err)
+	}
+	if id, err := alive.Claim(ctx); err != nil || id != "running-job" {
+		t.Fatalf("expected running-job, got %q (err=%v)", id, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := alive.Heartbeat(ctx, "running-job"); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	before, _ := mr.Stream("jobs:stream:normal")
+
+	// "crashed" не слал heartbeat — его запись возвращается в очередь, "running-job" — нет
+	n, err := alive.RequeueStale(ctx, 100)
+	if err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 requeued entry, got %d", n)
+	}
+
+	id, err := alive.Claim(ctx)
+	if err != nil || id != "lost-job" {
+		t.Fatalf("expected to reclaim lost-job, got %q (err=%v)", id, err)
+	}
+
+	// запись та же (XCLAIM, а не новый XADD): счётчик доставок в PEL не сбрасывается
+	after, _ := mr.Stream("jobs:stream:normal")
+	if len(after) != len(before) || after[0].ID != before[0].ID {
+		t.Fatalf("expected reclaimed entry to keep its id, before=%v after=%v", before, after)
+	}
+
+	// старая запись crashed-consumer'а уже не его
+	if err := crashed.Heartbeat(ctx, "lost-job"); !errors.Is(err, entity.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost for crashed consumer, got %v", err)
+	}
+}
+
+func TestStreamQueue_PromoteDue(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	q := newTestStreamQueue(t, mr, "w1", time.Minute)
+
+	_ = q.EnqueueAt(ctx, "soon", 2, time.Now().Add(50*time.Millisecond))
+
+	if _, err := q.Claim(ctx); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected nothing to claim before due, got %v", err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if n, err := q.PromoteDue(ctx, 100); err != nil || n != 1 {
+		t.Fatalf("expected 1 promoted, got %d (err=%v)", n, err)
+	}
+	if id, err := q.Claim(ctx); err != nil || id != "soon" {
+		t.Fatalf("expected to claim 'soon', got %q (err=%v)", id, err)
+	}
+}
+
+func TestStreamQueue_Tracked(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	q := newTestStreamQueue(t, mr, "w1", time.Minute)
+
+	_ = q.Enqueue(ctx, "queued", 0)
+	_ = q.EnqueueAt(ctx, "scheduled", 1, time.Now().Add(time.Hour))
+	_ = q.Enqueue(ctx, "claimed", 2)
+	if id, err := q.Claim(ctx); err != nil || id != "claimed" {
+		t.Fatalf("expected to claim 'claimed', got %q (err=%v)", id, err)
+	}
+	_ = q.Enqueue(ctx, "acked", 2)
+	_, _ = q.Claim(ctx)
+	_ = q.Ack(ctx, "acked")
+	_ = q.Enqueue(ctx, "removed", 1)
+	_ = q.Remove(ctx, "removed")
+
+	got, err := q.Tracked(ctx, []string{"queued", "scheduled", "claimed", "acked", "removed"})
+	if err != nil {
+		t.Fatalf("tracked: %v", err)
+	}
+	for id, want := range map[string]bool{"queued": true, "scheduled": true, "claimed": true, "acked": false, "removed": false} {
+		if got[id] != want {
+			t.Fatalf("tracked[%s]: expected %v, got %v", id, want, got[id])
+		}
+	}
+}
+
+func TestStreamQueue_Remove(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	q := newTestStreamQueue(t, mr, "w1", time.Minute)
+
+	_ = q.Enqueue(ctx, "canceled", 2)
+	_ = q.Enqueue(ctx, "kept", 2)
+	_ = q.EnqueueAt(ctx, "promoted", 2, time.Now().Add(-time.Second))
+	_ = q.EnqueueAt(ctx, "scheduled", 2, time.Now().Add(time.Hour))
+
+	for _, id := range []string{"canceled", "promoted", "scheduled"} {
+		if err := q.Remove(ctx, id); err != nil {
+			t.Fatalf("remove %s: %v", id, err)
+		}
+	}
+	if id, err := q.Claim(ctx); err != nil || id != "kept" {
+		t.Fatalf("expected to claim 'kept', got %q (err=%v)", id, err)
+	}
+	if _, err := q.Claim(ctx); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected empty queue, got %v", err)
+	}
+
+	// ACK убирает job и из индекса
+	if err := q.Ack(ctx, "kept"); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if mr.Exists("jobs:stream:high:index") {
+		keys, _ := mr.HKeys("jobs:stream:high:index")
+		t.Fatalf("expected empty index, got %v", keys)
+	}
+}
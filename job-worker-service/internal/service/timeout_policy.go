@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultJobTimeout используется, если для типа job timeout не настроен.
+const DefaultJobTimeout = 30 * time.Minute
+
+// TimeoutPolicies — timeout одной попытки по умолчанию + переопределения по типу job.
+// Нулевое значение валидно: везде используется DefaultJobTimeout.
+type TimeoutPolicies struct {
+	Default time.Duration
+	ByType  map[string]time.Duration
+}
+
+// For returns effective attempt timeout for job type.
+func (p TimeoutPolicies) For(typ string) time.Duration {
+	if d, ok := p.ByType[typ]; ok && d > 0 {
+		return d
+	}
+	if p.Default > 0 {
+		return p.Default
+	}
+	return DefaultJobTimeout
+}
+
+// ParseTimeouts parses per-type timeouts from a string like
+//
+//	convert_video:10m,echo:5s
+func ParseTimeouts(spec string) (map[string]time.Duration, error) {
+	out := map[string]time.Duration{}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		typ, raw, ok := strings.Cut(item, ":")
+		if !ok || typ == "" {
+			return nil, fmt.Errorf("timeout %q: expected type:duration", item)
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("timeout %q: invalid duration", item)
+		}
+
+		out[typ] = d
+	}
+
+	return out, nil
+}
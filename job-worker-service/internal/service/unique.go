@@ -0,0 +1,23 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+const maxUniqueKeyLen = 255
+
+// inputFingerprint — hash канонизированного input: порядок ключей и форматирование не важны.
+func inputFingerprint(input json.RawMessage) (string, error) {
+	var v any
+	if err := unmarshalNumber(input, &v); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// ErrInvalidWebhook — некорректные webhook-настройки (HTTP 400).
+var ErrInvalidWebhook = errors.New("invalid webhook settings")
+
+// Заголовки webhook-запроса. Подпись: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), см. SignWebhook.
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Порт хранилища webhook'ов (реализации: postgresql.WebhookRepository, memory.JobRepository).
+// Доставки создаёт само хранилище, когда job переходит в done / error / dead
+// (trigger jobs_enqueue_webhook) — атомарно с SetResultDone / SetResultError / SetDead.
+type WebhookRepository interface {
+	// ClaimDueWebhooks забирает до limit pending доставок, которым пора, и откладывает их на lease:
+	// другой dispatcher не возьмёт доставку, пока идёт запрос.
+	ClaimDueWebhooks(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error)
+	// RecordWebhookAttempt сохраняет попытку и новое состояние доставки (next — время следующей попытки для pending).
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, a entity.WebhookAttempt, status entity.WebhookStatus, next time.Time) error
+	ListWebhookDeliveries(ctx context.Context, jobID uuid.UUID) ([]entity.WebhookDelivery, error)
+
+	// GetWebhookSettings: entity.ErrWebhookSettingsNotFound, если настроек нет.
+	GetWebhookSettings(ctx context.Context, clientID string) (*entity.WebhookSettings, error)
+	PutWebhookSettings(ctx context.Context, s entity.WebhookSettings) error
+	DeleteWebhookSettings(ctx context.Context, clientID string) error
+}
+
+// WebhookJobs — откуда брать job для payload (реализация: postgresql.JobRepository).
+type WebhookJobs interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
+}
+
+type WebhookConfig struct {
+	// Secret подписывает webhook'и клиентов без своего секрета ("" — такие webhook'и уходят без подписи).
+	Secret string
+	// MaxAttempts — попыток на доставку, после чего она failed.
+	MaxAttempts int
+	// Backoff между попытками (MaxAttempts политики не используется).
+	Backoff entity.RetryPolicy
+	// Timeout одного запроса.
+	Timeout time.Duration
+	// BatchSize — доставок за один Dispatch (отправляются параллельно).
+	BatchSize int
+
+	// AllowPrivateNetworks разрешает webhook'и на loopback / private / link-local адреса
+	// (локальная разработка); по умолчанию такие соединения рвутся до отправки запроса.
+	AllowPrivateNetworks bool
+}
+
+var DefaultWebhookConfig = WebhookConfig{
+	MaxAttempts: 8,
+	Backoff:     entity.RetryPolicy{BaseDelay: 5 * time.Second, MaxDelay: 10 * time.Minute},
+	Timeout:     10 * time.Second,
+	BatchSize:   20,
+}
+
+// WebhookService — webhook'и о завершении job: настройки клиентов и история доставок для API,
+// Dispatch — отправка (cmd/worker).
+type WebhookService struct {
+	repo   WebhookRepository
+	jobs   WebhookJobs
+	client *http.Client
+	cfg    WebhookConfig
+}
+
+// NewWebhookService: client может быть nil — тогда http.Client с cfg.Timeout, который не ходит во внутреннюю сеть
+// (см. publicOnlyDialControl); нулевые поля cfg — из DefaultWebhookConfig.
+func NewWebhookService(repo WebhookRepository, jobs WebhookJobs, client *http.Client, cfg WebhookConfig) *WebhookService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultWebhookConfig.MaxAttempts
+	}
+	if cfg.Backoff.BaseDelay <= 0 {
+		cfg.Backoff = DefaultWebhookConfig.Backoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultWebhookConfig.Timeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultWebhookConfig.BatchSize
+	}
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+		if !cfg.AllowPrivateNetworks {
+			client.Transport = publicOnlyTransport()
+		}
+	}
+	return &WebhookService{repo: repo, jobs: jobs, client: client, cfg: cfg}
+}
+
+// Deliveries returns webhook deliveries of job with their attempts (oldest first).
+func (s *WebhookService) Deliveries(ctx context.Context, jobID uuid.UUID) ([]entity.WebhookDelivery, error) {
+	_, err := s.jobs.GetByID(ctx, jobID)
+	if errors.Is(err, entity.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListWebhookDeliveries(ctx, jobID)
+}
+
+func (s *WebhookService) Settings(ctx context.Context, clientID string) (*entity.WebhookSettings, error) {
+	return s.repo.GetWebhookSettings(ctx, clientID)
+}
+
+// PutSettings заменяет webhook-настройки клиента целиком.
+func (s *WebhookService) PutSettings(ctx context.Context, st entity.WebhookSettings) (*entity.WebhookSettings, error) {
+	if st.ClientID == "" {
+		return nil, fmt.Errorf("%w: client_id is required", ErrInvalidWebhook)
+	}
+	if st.URL == "" && st.Secret == "" {
+		return nil, fmt.Errorf("%w: url or secret is required", ErrInvalidWebhook)
+	}
+	if st.URL != "" {
+		if err := validateWebhookURL(st.URL); err != nil {
+			return nil, fmt.Errorf("%w: url: %v", ErrInvalidWebhook, err)
+		}
+	}
+	st.UpdatedAt = time.Now().UTC()
+	if err := s.repo.PutWebhookSettings(ctx, st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (s *WebhookService) DeleteSettings(ctx context.Context, clientID string) error {
+	return s.repo.DeleteWebhookSettings(ctx, clientID)
+}
+
+// validateWebhookURL: абсолютный http(s) URL.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// publicOnlyTransport — http.DefaultTransport без proxy, который соединяется только с публичными адресами.
+// Проверка в Control, а не по URL: адрес проверяется после DNS, на каждом соединении (и на redirect'ах).
+func publicOnlyTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnlyDialControl,
+	}).DialContext
+	return t
+}
+
+// errNonPublicAddress — webhook ведёт на loopback / private / link-local адрес.
+var errNonPublicAddress = errors.New("webhook target address is not public")
+
+func publicOnlyDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+	}
+	return nil
+}
+
+// SignWebhook returns X-Webhook-Signature for body sent at timestamp (unix seconds).
+// Получатель считает то же самое своим секретом и сравнивает через hmac.Equal;
+// timestamp в подписи не даёт переиграть старый запрос.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookPayload — тело webhook-запроса. Job — её состояние на момент отправки.
+type WebhookPayload struct {
+	DeliveryID int64       `json:"delivery_id"`
+	Event      string      `json:"event"` // job.done | job.error | job.dead
+	Job        *entity.Job `json:"job"`
+}
+
+// Dispatch делает один проход: отправляет до BatchSize доставок, которым пора; возвращает число доставленных.
+// Неудачная попытка (не 2xx, сеть, timeout) откладывает доставку по Backoff, после MaxAttempts — failed.
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	// lease больше timeout'а запроса: пока идёт попытка, доставку не возьмёт другой worker
+	due, err := s.repo.ClaimDueWebhooks(ctx, s.cfg.BatchSize, 2*s.cfg.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+	)
+	for _, d := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.deliver(ctx, d)
+			if err != nil {
+				log.Printf("[webhook] delivery_id=%d job_id=%s record error=%v", d.ID, d.JobID, err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				delivered++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return delivered, nil
+}
+
+// deliver делает одну попытку и сохраняет её; ok — получатель ответил 2xx.
+func (s *WebhookService) deliver(ctx context.Context, d entity.WebhookDelivery) (ok bool, err error) {
+	attempt := entity.WebhookAttempt{Attempt: d.Attempts + 1, At: time.Now().UTC()}
+
+	req, err := s.request(ctx, d, attempt.At)
+	if err == nil {
+		var resp *http.Response
+		resp, err = s.client.Do(req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // чтобы соединение вернулось в pool
+			_ = resp.Body.Close()
+			attempt.StatusCode = resp.StatusCode
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}
+	}
+	attempt.Duration = time.Since(attempt.At)
+
+	status, next := entity.WebhookDelivered, time.Time{}
+	if err != nil {
+		attempt.Error = err.Error()
+		status = entity.WebhookFailed
+		if attempt.Attempt < s.cfg.MaxAttempts {
+			status = entity.WebhookPending
+			next = time.Now().Add(s.cfg.Backoff.Backoff(attempt.Attempt))
+		}
+		log.Printf("[webhook] delivery_id=%d job_id=%s url=%s attempt=%d error=%v status=%s",
+			d.ID, d.JobID, d.URL, attempt.Attempt, err, status,
+		)
+	} else {
+		log.Printf("[webhook] delivery_id=%d job_id=%s url=%s attempt=%d delivered", d.ID, d.JobID, d.URL, attempt.Attempt)
+	}
+
+	// попытку сохраняем и при отменённом ctx (shutdown посреди запроса)
+	if err := s.repo.RecordWebhookAttempt(context.WithoutCancel(ctx), d.ID, attempt, status, next); err != nil {
+		return false, err
+	}
+	return status == entity.WebhookDelivered, nil
+}
+
+// request builds signed POST for delivery d.
+func (s *WebhookService) request(ctx context.Context, d entity.WebhookDelivery, at time.Time) (*http.Request, error) {
+	job, err := s.jobs.GetByID(ctx, d.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("load job: %w", err)
+	}
+	body, err := json.Marshal(WebhookPayload{DeliveryID: d.ID, Event: "job." + string(d.JobStatus), Job: job})
+	if err != nil {
+		return nil, err
+	}
+
+	secret := s.cfg.Secret
+	if job.ClientID != "" {
+		st, err := s.repo.GetWebhookSettings(ctx, job.ClientID)
+		switch {
+		case err == nil && st.Secret != "":
+			secret = st.Secret
+		case err != nil && !errors.Is(err, entity.ErrWebhookSettingsNotFound):
+			return nil, fmt.Errorf("load settings: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	ts := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(ts, 10))
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, ts, body))
+	}
+	return req, nil
+}
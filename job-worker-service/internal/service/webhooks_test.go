@@ -0,0 +1,247 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/memory"
+	"job-worker-service/internal/service"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver — httptest-получатель: отвечает кодами из codes по очереди (последний — дальше всегда).
+type webhookReceiver struct {
+	mu    sync.Mutex
+	codes []int
+	got   []receivedWebhook
+}
+
+func newWebhookReceiver(t *testing.T, codes ...int) (*webhookReceiver, *httptest.Server) {
+	rcv := &webhookReceiver{codes: codes}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		rcv.mu.Lock()
+		rcv.got = append(rcv.got, receivedWebhook{header: r.Header.Clone(), body: body})
+		code := rcv.codes[min(len(rcv.got), len(rcv.codes))-1]
+		rcv.mu.Unlock()
+
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return rcv, srv
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.got...)
+}
+
+// verify checks X-Webhook-Signature the way a receiver would.
+func (w receivedWebhook) verify(t *testing.T, secret string) service.WebhookPayload {
+	t.Helper()
+	ts, err := strconv.ParseInt(w.header.Get(service.WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header: %v", err)
+	}
+	if got, want := w.header.Get(service.WebhookSignatureHeader), service.SignWebhook(secret, ts, w.body); got != want {
+		t.Fatalf("signature: got %q want %q", got, want)
+	}
+
+	var p service.WebhookPayload
+	if err := json.Unmarshal(w.body, &p); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	return p
+}
+
+func deliveriesOf(t *testing.T, svc *service.WebhookService, jobID uuid.UUID) []entity.WebhookDelivery {
+	t.Helper()
+	ds, err := svc.Deliveries(context.Background(), jobID)
+	if err != nil {
+		t.Fatalf("deliveries: %v", err)
+	}
+	return ds
+}
+
+func TestWebhookService_DeliversSignedPayloadWithRetries(t *testing.T) {
+	ctx := context.Background()
+	rcv, srv := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusOK)
+
+	jobSvc, repo, _ := newTestJobService(service.RetryPolicies{})
+	webhooks := service.NewWebhookService(repo, repo, nil, service.WebhookConfig{
+		Secret:  "s3cret",
+		Backoff: entity.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+
+		AllowPrivateNetworks: true, // httptest-получатель на 127.0.0.1
+	})
+
+	id, err := jobSvc.CreateJob(ctx, service.CreateJobRequest{Type: "echo", CallbackURL: srv.URL + "/hook"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := jobSvc.CreateJob(ctx, service.CreateJobRequest{Type: "echo", CallbackURL: "ftp://example.com"}); err == nil {
+		t.Fatalf("expected error for non-http callback_url")
+	}
+
+	// до завершения job webhook'ов нет
+	if n, err := webhooks.Dispatch(ctx); err != nil || n != 0 {
+		t.Fatalf("dispatch before finish: n=%d err=%v", n, err)
+	}
+	token, _ := repo.StartAttempt(ctx, id, time.Minute)
+	if err := repo.SetResultDone(ctx, id, token, json.RawMessage(`{"ok":true}`)); err != nil {
+		t.Fatalf("set done: %v", err)
+	}
+
+	// 1-я попытка: 500 -> pending с backoff
+	if n, err := webhooks.Dispatch(ctx); err != nil || n != 0 {
+		t.Fatalf("first dispatch: n=%d err=%v", n, err)
+	}
+	ds := deliveriesOf(t, webhooks, id)
+	if len(ds) != 1 || ds[0].Status != entity.WebhookPending || ds[0].Attempts != 1 || ds[0].AttemptLog[0].StatusCode != 500 {
+		t.Fatalf("expected pending delivery after 500, got %+v", ds)
+	}
+
+	// 2-я попытка после backoff: 200 -> delivered
+	time.Sleep(5 * time.Millisecond)
+	if n, err := webhooks.Dispatch(ctx); err != nil || n != 1 {
+		t.Fatalf("second dispatch: n=%d err=%v", n, err)
+	}
+	ds = deliveriesOf(t, webhooks, id)
+	if ds[0].Status != entity.WebhookDelivered || ds[0].Attempts != 2 || len(ds[0].AttemptLog) != 2 || ds[0].DeliveredAt == nil {
+		t.Fatalf("expected delivered after 2 attempts, got %+v", ds[0])
+	}
+
+	got := rcv.received()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(got))
+	}
+	p := got[1].verify(t, "s3cret")
+	if p.Event != "job.done" || p.Job.ID != id || p.Job.Status != entity.StatusDone || string(p.Job.Output) != `{"ok":true}` {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+	if got[1].header.Get(service.WebhookIDHeader) != strconv.FormatInt(ds[0].ID, 10) {
+		t.Fatalf("unexpected delivery id header %q", got[1].header.Get(service.WebhookIDHeader))
+	}
+
+	// доставленный webhook больше не шлётся
+	if n, _ := webhooks.Dispatch(ctx); n != 0 || len(rcv.received()) != 2 {
+		t.Fatalf("delivered webhook was sent again")
+	}
+}
+
+func TestWebhookService_ClientDefaultsAndGivingUp(t *testing.T) {
+	ctx := context.Background()
+	rcv, srv := newWebhookReceiver(t, http.StatusServiceUnavailable)
+
+	repo := memory.NewJobRepository()
+	webhooks := service.NewWebhookService(repo, repo, nil, service.WebhookConfig{
+		Secret:      "service-secret",
+		MaxAttempts: 2,
+		Backoff:     entity.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+
+		AllowPrivateNetworks: true,
+	})
+	if _, err := webhooks.PutSettings(ctx, entity.WebhookSettings{ClientID: "acme", URL: "not a url"}); err == nil {
+		t.Fatalf("expected invalid settings error")
+	}
+	if _, err := webhooks.PutSettings(ctx, entity.WebhookSettings{ClientID: "acme", URL: srv.URL, Secret: "acme-secret"}); err != nil {
+		t.Fatalf("put settings: %v", err)
+	}
+
+	// job клиента без callback_url -> URL клиента; job без клиента и callback_url -> без webhook'а
+	clientJob, _ := repo.Create(ctx, &entity.Job{Type: "echo", ClientID: "acme"})
+	anonJob, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	for _, id := range []uuid.UUID{clientJob, anonJob} {
+		if err := repo.SetResultError(ctx, id, 0, "boom"); err != nil {
+			t.Fatalf("set error: %v", err)
+		}
+	}
+	if ds := deliveriesOf(t, webhooks, anonJob); len(ds) != 0 {
+		t.Fatalf("expected no deliveries for job without webhook, got %+v", ds)
+	}
+
+	for range 2 {
+		if _, err := webhooks.Dispatch(ctx); err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	ds := deliveriesOf(t, webhooks, clientJob)
+	if len(ds) != 1 || ds[0].Status != entity.WebhookFailed || ds[0].Attempts != 2 || ds[0].URL != srv.URL {
+		t.Fatalf("expected failed delivery after 2 attempts, got %+v", ds)
+	}
+	if ds[0].LastError == "" || ds[0].AttemptLog[1].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected last error recorded, got %+v", ds[0])
+	}
+
+	// исчерпанная доставка больше не шлётся; подпись — секретом клиента
+	if _, err := webhooks.Dispatch(ctx); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	got := rcv.received()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(got))
+	}
+	if p := got[0].verify(t, "acme-secret"); p.Event != "job.error" || p.Job.ID != clientJob {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+}
+
+func TestWebhookService_RefusesPrivateTargets(t *testing.T) {
+	ctx := context.Background()
+	rcv, srv := newWebhookReceiver(t, http.StatusOK)
+
+	repo := memory.NewJobRepository()
+	webhooks := service.NewWebhookService(repo, repo, nil, service.WebhookConfig{
+		MaxAttempts: 1,
+		Backoff:     entity.RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+
+	id, _ := repo.Create(ctx, &entity.Job{Type: "echo", CallbackURL: srv.URL + "/hook"})
+	if err := repo.SetResultError(ctx, id, 0, "boom"); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	if n, err := webhooks.Dispatch(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing delivered, got n=%d err=%v", n, err)
+	}
+
+	if len(rcv.received()) != 0 {
+		t.Fatalf("webhook reached loopback receiver")
+	}
+	ds := deliveriesOf(t, webhooks, id)
+	if len(ds) != 1 || ds[0].Status != entity.WebhookFailed || !strings.Contains(ds[0].LastError, "not public") {
+		t.Fatalf("expected failed delivery with non-public address error, got %+v", ds)
+	}
+}
+
+func TestWebhookService_Deliveries_NotFoundOnlyForMissingJob(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewJobRepository()
+
+	if _, err := service.NewWebhookService(repo, repo, nil, service.WebhookConfig{}).Deliveries(ctx, uuid.New()); !errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for missing job, got %v", err)
+	}
+
+	_, err := service.NewWebhookService(repo, brokenRepo{repo}, nil, service.WebhookConfig{}).Deliveries(ctx, uuid.New())
+	if err == nil || errors.Is(err, service.ErrJobNotFound) {
+		t.Fatalf("expected repository error, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+)
+
+// MaxFollowUpDepth — сколько звеньев on_success / on_failure можно вложить в один запрос.
+const MaxFollowUpDepth = 8
+
+// refKey — объект {"$ref": "<JSON pointer>"} в input шаблона заменяется значением из родителя.
+const refKey = "$ref"
+
+// CreateFollowUp создаёт follow-up job завершённой job parentID: OnSuccess для done, OnFailure для error / dead.
+// Возвращает uuid.Nil, если шаблона для статуса родителя нет. Повторный вызов для того же завершения
+// возвращает уже созданную job (Idempotency-Key по fencing token'у попытки), новый запуск родителя — новую.
+func (s *JobService) CreateFollowUp(ctx context.Context, parentID uuid.UUID) (uuid.UUID, error) {
+	parent, err := s.GetJob(ctx, parentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var t *entity.JobTemplate
+	switch parent.Status {
+	case entity.StatusDone:
+		t = parent.OnSuccess
+	case entity.StatusError, entity.StatusDead:
+		t = parent.OnFailure
+	}
+	if t == nil {
+		return uuid.Nil, nil
+	}
+
+	input, err := followUpInput(t.Input, parent)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("follow-up input: %w", err)
+	}
+	priority := parent.Priority
+	if t.Priority != nil {
+		priority = *t.Priority
+	}
+
+	req := CreateJobRequest{
+		Type:     t.Type,
+		Priority: priority,
+		Input:    input,
+		Retry:    entity.RetryPolicy{MaxAttempts: t.MaxAttempts},
+		Timeout:  t.Timeout,
+		Tags:     t.Tags,
+
+		ClientID:       parent.ClientID,
+		IdempotencyKey: "follow-up:" + parent.ID.String() + ":" + strconv.FormatInt(parent.FenceToken, 10),
+
+		CallbackURL: t.CallbackURL,
+
+		OnSuccess: t.OnSuccess,
+		OnFailure: t.OnFailure,
+		ParentID:  &parent.ID,
+	}
+	if t.Delay > 0 {
+		delay := t.Delay
+		req.Delay = &delay
+	}
+	return s.CreateJob(ctx, req)
+}
+
+// followUpInput подставляет значения родителя вместо {"$ref": ...} в input шаблона.
+// Pointer считается от {"id", "type", "input", "output", "error"} родителя.
+func followUpInput(tmpl []byte, parent *entity.Job) ([]byte, error) {
+	if len(tmpl) == 0 {
+		return nil, nil
+	}
+	doc := map[string]any{
+		"id":   parent.ID.String(),
+		"type": parent.Type,
+	}
+	for key, raw := range map[string][]byte{"input": parent.Input, "output": parent.Output} {
+		if len(raw) == 0 {
+			continue
+		}
+		var v any
+		if err := unmarshalNumber(raw, &v); err != nil {
+			return nil, err
+		}
+		doc[key] = v
+	}
+	if parent.Error != nil {
+		doc["error"] = *parent.Error
+	}
+
+	var input any
+	if err := unmarshalNumber(tmpl, &input); err != nil {
+		return nil, err
+	}
+	resolved, err := substituteRefs(input, func(ptr string) (any, error) { return jsonPointer(doc, ptr) })
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+// substituteRefs заменяет каждый {"$ref": ptr} в v на resolve(ptr).
+func substituteRefs(v any, resolve func(ptr string) (any, error)) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v[refKey]; ok && len(v) == 1 {
+			ptr, ok := ref.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string", refKey)
+			}
+			return resolve(ptr)
+		}
+		for k, item := range v {
+			r, err := substituteRefs(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	case []any:
+		for i, item := range v {
+			r, err := substituteRefs(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = r
+		}
+	}
+	return v, nil
+}
+
+// jsonPointer returns value at RFC 6901 pointer ptr in doc ("" — весь doc).
+func jsonPointer(doc any, ptr string) (any, error) {
+	if err := validatePointer(ptr); err != nil {
+		return nil, err
+	}
+	if ptr == "" {
+		return doc, nil
+	}
+	cur := doc
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[tok]
+			if !ok {
+				return nil, fmt.Errorf("%s %q: no key %q", refKey, ptr, tok)
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%s %q: no index %q", refKey, ptr, tok)
+			}
+			cur = v[i]
+		default:
+			return nil, fmt.Errorf("%s %q: %q is not an object or array", refKey, ptr, tok)
+		}
+	}
+	return cur, nil
+}
+
+func validatePointer(ptr string) error {
+	if ptr != "" && !strings.HasPrefix(ptr, "/") {
+		return fmt.Errorf("%s %q: JSON pointer must start with /", refKey, ptr)
+	}
+	return nil
+}
+
+// validateFollowUps проверяет шаблоны до создания job: ошибка в цепочке должна вернуться клиенту (400),
+// а не всплыть в worker'е, когда родитель уже завершился.
+func validateFollowUps(onSuccess, onFailure *entity.JobTemplate, depth int) error {
+	if err := validateTemplate(onSuccess, depth); err != nil {
+		return fmt.Errorf("on_success: %w", err)
+	}
+	if err := validateTemplate(onFailure, depth); err != nil {
+		return fmt.Errorf("on_failure: %w", err)
+	}
+	return nil
+}
+
+func validateTemplate(t *entity.JobTemplate, depth int) error {
+	if t == nil {
+		return nil
+	}
+	if depth > MaxFollowUpDepth {
+		return fmt.Errorf("chain is deeper than %d", MaxFollowUpDepth)
+	}
+	if t.Type == "" {
+		return errors.New("type is required")
+	}
+	if t.MaxAttempts < 0 || t.Timeout < 0 || t.Delay < 0 {
+		return errors.New("max_attempts, timeout and delay must not be negative")
+	}
+	for _, tag := range t.Tags {
+		if tag == "" {
+			return errors.New("tags must not be empty")
+		}
+	}
+	if t.CallbackURL != "" {
+		if err := validateWebhookURL(t.CallbackURL); err != nil {
+			return fmt.Errorf("callback_url: %w", err)
+		}
+	}
+	if len(t.Input) > 0 {
+		var input any
+		if err := unmarshalNumber(t.Input, &input); err != nil {
+			return fmt.Errorf("input: %w", err)
+		}
+		// значения родителя ещё неизвестны — проверяем только синтаксис pointer'ов
+		if _, err := substituteRefs(input, func(ptr string) (any, error) { return nil, validatePointer(ptr) }); err != nil {
+			return fmt.Errorf("input: %w", err)
+		}
+	}
+	return validateFollowUps(t.OnSuccess, t.OnFailure, depth+1)
+}
@@ -0,0 +1,174 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+type deadLetterResp struct {
+	JobID  string   `json:"job_id"`
+	Reason string   `json:"reason"`
+	DeadAt string   `json:"dead_at,omitempty"`
+	Job    *jobResp `json:"job,omitempty"` // nil, если id не UUID или job нет в БД
+}
+
+type deadLetterListResp struct {
+	Items []deadLetterResp `json:"items"`
+	Total int64            `json:"total"`
+}
+
+type purgeResp struct {
+	Purged int64 `json:"purged"`
+}
+
+func toDeadLetterResp(d service.DeadJob) deadLetterResp {
+	resp := deadLetterResp{JobID: d.JobID, Reason: d.Reason}
+	if !d.DeadAt.IsZero() {
+		resp.DeadAt = d.DeadAt.Format(time.RFC3339)
+	}
+	if d.Job != nil {
+		j := toJobResp(d.Job)
+		resp.Job = &j
+	}
+	return resp
+}
+
+// ListDeadLetters godoc
+// @Summary List dead-lettered jobs
+// @Description Jobs that exhausted retries or could not be processed at all (newest first).
+// @Tags dead-letters
+// @Produce json
+// @Param offset query int false "offset (default 0)"
+// @Param limit query int false "limit (default 50, max 500)"
+// @Success 200 {object} deadLetterListResp
+// @Failure 400 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /dead-letters [get]
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid offset")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultListLimit)
+	if err != nil || limit <= 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	items, total, err := h.deadSvc.List(r.Context(), int64(offset), int64(limit))
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := deadLetterListResp{Items: make([]deadLetterResp, 0, len(items)), Total: total}
+	for _, d := range items {
+		resp.Items = append(resp.Items, toDeadLetterResp(d))
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// GetDeadLetter godoc
+// @Summary Inspect dead-lettered job
+// @Tags dead-letters
+// @Produce json
+// @Param id path string true "job id"
+// @Success 200 {object} deadLetterResp
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /dead-letters/{id} [get]
+func (h *Handler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	d, err := h.deadSvc.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeDeadLetterError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toDeadLetterResp(*d))
+}
+
+// RequeueDeadLetter godoc
+// @Summary Requeue dead-lettered job
+// @Description Resets job (pending, attempts=0) and enqueues it with its original priority.
+// @Tags dead-letters
+// @Produce json
+// @Param id path string true "job id (uuid)"
+// @Success 202 {object} createJobResp
+// @Failure 404 {object} apiError
+// @Failure 409 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /dead-letters/{id}/requeue [post]
+func (h *Handler) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.deadSvc.Requeue(r.Context(), id); err != nil {
+		h.writeDeadLetterError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusAccepted, createJobResp{ID: id})
+}
+
+// PurgeDeadLetter godoc
+// @Summary Remove job from dead-letter queue
+// @Tags dead-letters
+// @Param id path string true "job id"
+// @Success 204
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /dead-letters/{id} [delete]
+func (h *Handler) PurgeDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := h.deadSvc.Purge(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.writeDeadLetterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeDeadLetters godoc
+// @Summary Purge dead-letter queue
+// @Tags dead-letters
+// @Produce json
+// @Success 200 {object} purgeResp
+// @Failure 500 {object} apiError
+// @Router /dead-letters [delete]
+func (h *Handler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	n, err := h.deadSvc.PurgeAll(r.Context())
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.writeJSON(w, http.StatusOK, purgeResp{Purged: n})
+}
+
+func (h *Handler) writeDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, entity.ErrDeadLetterNotFound):
+		h.writeError(w, http.StatusNotFound, "dead letter not found")
+	case errors.Is(err, service.ErrNotRequeueable), errors.Is(err, entity.ErrUniqueJobExists):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
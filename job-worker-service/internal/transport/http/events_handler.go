@@ -0,0 +1,150 @@
+package httptransport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+// sseKeepAlive — комментарий-ping, чтобы прокси не закрывали молчащее соединение.
+const sseKeepAlive = 15 * time.Second
+
+// JobEvents godoc
+// @Summary Stream job events (SSE)
+// @Description text/event-stream. The first event is the current job state, then status transitions
+// @Description (event: status) and handler progress (event: progress). Status event done / error / dead carries
+// @Description output / error; the stream ends after a final status (done, error, dead, canceled).
+// @Tags events
+// @Produce text/event-stream
+// @Param id path string true "job id (uuid)"
+// @Success 200 {object} entity.JobEvent
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/events [get]
+func (h *Handler) JobEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// подписка до snapshot'а: переход между ними не потеряется
+	events, err := h.eventSvc.Stream(ctx, service.EventFilter{JobID: id})
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	snap, err := h.eventSvc.Snapshot(ctx, id)
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sse := startSSE(w)
+	if sse.event(snap) != nil || snap.Status.Final() {
+		return
+	}
+
+	sse.stream(ctx, events, func(ev entity.JobEvent) (skip, last bool) {
+		if ev.Kind != entity.EventStatus {
+			return false, false
+		}
+		// переходы, которые уже есть в snapshot'е
+		if !ev.At.After(snap.At) {
+			return true, false
+		}
+		return false, ev.Status.Final()
+	})
+}
+
+// Events godoc
+// @Summary Stream events of all jobs (SSE)
+// @Description text/event-stream of status transitions and progress of all jobs, optionally filtered by job type.
+// @Tags events
+// @Produce text/event-stream
+// @Param type query string false "job types, e.g. generate_report,convert_video"
+// @Success 200 {object} entity.JobEvent
+// @Failure 500 {object} apiError
+// @Router /events [get]
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	events, err := h.eventSvc.Stream(r.Context(), service.EventFilter{Types: queryList(r, "type")})
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	startSSE(w).stream(r.Context(), events, func(entity.JobEvent) (bool, bool) { return false, false })
+}
+
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func startSSE(w http.ResponseWriter) *sseWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx: не буферизовать поток
+	w.WriteHeader(http.StatusOK)
+
+	s := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	_ = s.rc.Flush()
+	return s
+}
+
+func (s *sseWriter) event(ev entity.JobEvent) error {
+	data, err := json.Marshal(ev) // без переводов строк: одна строка data
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", ev.Kind, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// stream пишет события, пока клиент не отключился, источник не закрылся или filter не вернул last.
+func (s *sseWriter) stream(ctx context.Context, events <-chan entity.JobEvent, filter func(entity.JobEvent) (skip, last bool)) {
+	ping := time.NewTicker(sseKeepAlive)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil || s.rc.Flush() != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			skip, last := filter(ev)
+			if skip {
+				continue
+			}
+			if s.event(ev) != nil || last {
+				return
+			}
+		}
+	}
+}
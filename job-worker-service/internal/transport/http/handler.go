@@ -2,7 +2,12 @@ package httptransport
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,11 +18,21 @@ import (
 )
 
 type Handler struct {
-	jobSvc *service.JobService
+	jobSvc    *service.JobService
+	deadSvc   *service.DeadLetterService
+	cancelSvc *service.CancelService
+	retrySvc  *service.RetryService
+	eventSvc  *service.EventService
+
+	webhookSvc *service.WebhookService
+	logSvc     *service.JobLogService
 }
 
-func NewHandler(jobSvc *service.JobService) *Handler {
-	return &Handler{jobSvc: jobSvc}
+func NewHandler(jobSvc *service.JobService, deadSvc *service.DeadLetterService, cancelSvc *service.CancelService,
+	retrySvc *service.RetryService, eventSvc *service.EventService, webhookSvc *service.WebhookService,
+	logSvc *service.JobLogService) *Handler {
+	return &Handler{jobSvc: jobSvc, deadSvc: deadSvc, cancelSvc: cancelSvc, retrySvc: retrySvc, eventSvc: eventSvc,
+		webhookSvc: webhookSvc, logSvc: logSvc}
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
@@ -34,6 +49,115 @@ type createJobDTO struct {
 	Type     string                 `json:"type"`
 	Priority *int                   `json:"priority,omitempty"` // 0=low,1=normal,2=high (nil => default 1)
 	Input    map[string]interface{} `json:"input"`
+	Retry    *retryDTO              `json:"retry,omitempty"` // nil => политика для типа job
+
+	// отложенный запуск: либо абсолютное время (RFC3339), либо задержка в секундах
+	RunAt        *time.Time `json:"run_at,omitempty"`
+	DelaySeconds *int       `json:"delay_seconds,omitempty"`
+
+	// timeout одной попытки (nil => timeout для типа job)
+	TimeoutSeconds *int `json:"timeout_seconds,omitempty"`
+
+	Tags []string `json:"tags,omitempty"` // метки для GET /jobs?tag=...
+
+	// пока такая же job (type + input или type + unique_key) pending/processing — вернуть её id
+	Unique    bool   `json:"unique,omitempty"`
+	UniqueKey string `json:"unique_key,omitempty"`
+
+	// webhook о завершении (done/error/dead); пусто => URL из настроек клиента (X-Client-ID)
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// follow-up job: после done / после error или dead
+	OnSuccess *jobTemplateDTO `json:"on_success,omitempty"`
+	OnFailure *jobTemplateDTO `json:"on_failure,omitempty"`
+}
+
+// jobTemplateDTO — follow-up job; в input объект {"$ref": "/output/..."} заменяется значением из родителя
+// (JSON pointer от {"id","type","input","output","error"} родителя).
+type jobTemplateDTO struct {
+	Type           string          `json:"type"`
+	Priority       *int            `json:"priority,omitempty"` // nil => priority родителя
+	Input          json.RawMessage `json:"input,omitempty" swaggertype:"object"`
+	Tags           []string        `json:"tags,omitempty"`
+	MaxAttempts    int             `json:"max_attempts,omitempty"`    // 0 => политика для типа
+	TimeoutSeconds int             `json:"timeout_seconds,omitempty"` // 0 => timeout для типа
+	DelaySeconds   int             `json:"delay_seconds,omitempty"`   // после завершения родителя
+	CallbackURL    string          `json:"callback_url,omitempty"`
+	OnSuccess      *jobTemplateDTO `json:"on_success,omitempty"`
+	OnFailure      *jobTemplateDTO `json:"on_failure,omitempty"`
+}
+
+func (d *jobTemplateDTO) toEntity() (*entity.JobTemplate, error) {
+	if d == nil {
+		return nil, nil
+	}
+	t := &entity.JobTemplate{
+		Type:        d.Type,
+		Priority:    d.Priority,
+		Tags:        d.Tags,
+		MaxAttempts: d.MaxAttempts,
+		Timeout:     time.Duration(d.TimeoutSeconds) * time.Second,
+		Delay:       time.Duration(d.DelaySeconds) * time.Second,
+		CallbackURL: d.CallbackURL,
+	}
+	// input — объект (или null), как у job
+	var input map[string]json.RawMessage
+	if len(d.Input) > 0 {
+		if err := json.Unmarshal(d.Input, &input); err != nil {
+			return nil, err
+		}
+	}
+	if input != nil {
+		t.Input = d.Input
+	}
+	var err error
+	if t.OnSuccess, err = d.OnSuccess.toEntity(); err != nil {
+		return nil, err
+	}
+	if t.OnFailure, err = d.OnFailure.toEntity(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func toJobTemplateDTO(t *entity.JobTemplate) *jobTemplateDTO {
+	if t == nil {
+		return nil
+	}
+	d := &jobTemplateDTO{
+		Type:           t.Type,
+		Priority:       t.Priority,
+		Tags:           t.Tags,
+		MaxAttempts:    t.MaxAttempts,
+		TimeoutSeconds: int(t.Timeout / time.Second),
+		DelaySeconds:   int(t.Delay / time.Second),
+		CallbackURL:    t.CallbackURL,
+		OnSuccess:      toJobTemplateDTO(t.OnSuccess),
+		OnFailure:      toJobTemplateDTO(t.OnFailure),
+	}
+	if len(t.Input) > 0 {
+		d.Input = t.Input
+	}
+	return d
+}
+
+// retryDTO — переопределение политики ретраев; незаданные поля берутся из политики для типа.
+type retryDTO struct {
+	MaxAttempts int   `json:"max_attempts,omitempty"`
+	BaseDelayMs int64 `json:"base_delay_ms,omitempty"`
+	MaxDelayMs  int64 `json:"max_delay_ms,omitempty"`
+	// лимит попыток, если последняя упала по timeout (0 => max_attempts)
+	TimeoutMaxAttempts int `json:"timeout_max_attempts,omitempty"`
+}
+
+type retryJobDTO struct {
+	Priority *int `json:"priority,omitempty"` // nil => исходный priority
+}
+
+type cloneJobDTO struct {
+	// merge patch поверх input исходной job: ключи заменяются, null удаляет ключ
+	Input    json.RawMessage `json:"input,omitempty" swaggertype:"object"`
+	Priority *int            `json:"priority,omitempty"` // nil => как у исходной job
 }
 
 type createJobResp struct {
@@ -41,26 +165,116 @@ type createJobResp struct {
 }
 
 type jobResp struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"`
-	Status    entity.JobStatus       `json:"status"`
-	Priority  int                    `json:"priority"`
-	Input     map[string]interface{} `json:"input"`
-	Output    map[string]interface{} `json:"output,omitempty"`
-	Error     *string                `json:"error,omitempty"`
-	CreatedAt string                 `json:"created_at"`
-	UpdatedAt string                 `json:"updated_at"`
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Status      entity.JobStatus       `json:"status"`
+	Priority    int                    `json:"priority"`
+	Input       map[string]interface{} `json:"input"`
+	Output      map[string]interface{} `json:"output,omitempty"`
+	Error       *string                `json:"error,omitempty"`
+	ErrorClass  entity.ErrorClass      `json:"error_class,omitempty"` // error | timeout
+	Attempts    int                    `json:"attempts"`
+	MaxAttempts int                    `json:"max_attempts"`
+	RunAt       string                 `json:"run_at,omitempty"` // когда job будет (снова) запущена
+	CreatedAt   string                 `json:"created_at"`
+	UpdatedAt   string                 `json:"updated_at"`
+
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"` // timeout одной попытки (0 — без ограничения)
+
+	Tags      []string `json:"tags,omitempty"`
+	UniqueKey string   `json:"unique_key,omitempty"`
+
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	Progress *progressResp `json:"progress,omitempty"` // последний прогресс handler'а
+
+	ParentID  string          `json:"parent_id,omitempty"` // job, после которой создана эта (on_success / on_failure)
+	OnSuccess *jobTemplateDTO `json:"on_success,omitempty"`
+	OnFailure *jobTemplateDTO `json:"on_failure,omitempty"`
+}
+
+type progressResp struct {
+	Percent   int    `json:"percent"`
+	Stage     string `json:"stage,omitempty"`
+	Message   string `json:"message,omitempty"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type jobListResp struct {
+	Items      []jobResp `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"` // пусто на последней странице
+}
+
+func toJobResp(j *entity.Job) jobResp {
+	resp := jobResp{
+		ID:          j.ID.String(),
+		Type:        j.Type,
+		Status:      j.Status,
+		Priority:    j.Priority,
+		Error:       j.Error,
+		ErrorClass:  j.ErrorClass,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.Retry.MaxAttempts,
+		CreatedAt:   j.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   j.UpdatedAt.Format(time.RFC3339),
+
+		TimeoutSeconds: int(j.Timeout / time.Second),
+
+		Tags:      j.Tags,
+		UniqueKey: j.UniqueKey,
+
+		CallbackURL: j.CallbackURL,
+
+		OnSuccess: toJobTemplateDTO(j.OnSuccess),
+		OnFailure: toJobTemplateDTO(j.OnFailure),
+	}
+	if j.ParentID != nil {
+		resp.ParentID = j.ParentID.String()
+	}
+	if j.RunAt != nil {
+		resp.RunAt = j.RunAt.Format(time.RFC3339)
+	}
+	if p := j.Progress; p != nil {
+		resp.Progress = &progressResp{
+			Percent:   p.Percent,
+			Stage:     p.Stage,
+			Message:   p.Message,
+			UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+		}
+	}
+
+	// input/output: json.RawMessage -> map
+	if len(j.Input) > 0 {
+		_ = json.Unmarshal(j.Input, &resp.Input)
+	}
+	if j.Status == entity.StatusDone && len(j.Output) > 0 {
+		_ = json.Unmarshal(j.Output, &resp.Output)
+	}
+
+	return resp
 }
 
 // CreateJob godoc
 // @Summary Create a new job
 // @Description Creates job in DB (pending) and enqueues it for background processing.
+// @Description With run_at (RFC3339) or delay_seconds the job waits in the scheduled set until due.
+// @Description timeout_seconds limits one attempt (default: timeout for the job type); timed-out attempts get error_class=timeout.
+// @Description With Idempotency-Key a repeated request (same key and X-Client-ID, same body) returns the id of the job
+// @Description created by the first one instead of creating a duplicate; the same key with a different body gets 422.
+// @Description With unique=true (or unique_key) the id of a pending/processing job of the same type with the same input
+// @Description (or unique_key) is returned instead of creating another one.
+// @Description With callback_url (or a default webhook of X-Client-ID) a signed webhook is POSTed when the job is done, error or dead.
+// @Description on_success / on_failure create a follow-up job when this one is done / error or dead;
+// @Description {"$ref": "/output/url"} in its input is replaced with the value from the parent (JSON pointer into id, type, input, output, error).
 // @Tags jobs
 // @Accept json
 // @Produce json
-// @Param request body createJobDTO true "job payload (priority: 0=low,1=normal,2=high)"
+// @Param request body createJobDTO true "job payload (priority: 0=low,1=normal,2=high; retry overrides type policy)"
+// @Param Idempotency-Key header string false "idempotency key (max 255 chars)"
+// @Param X-Client-ID header string false "client scope for Idempotency-Key"
 // @Success 201 {object} createJobResp
 // @Failure 400 {object} apiError
+// @Failure 422 {object} apiError
 // @Failure 500 {object} apiError
 // @Router /jobs [post]
 func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
@@ -81,11 +295,54 @@ func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := h.jobSvc.CreateJob(r.Context(), service.CreateJobRequest{
+	req := service.CreateJobRequest{
 		Type:     dto.Type,
 		Priority: priority,
 		Input:    rawInput,
-	})
+		RunAt:    dto.RunAt,
+		Tags:     dto.Tags,
+
+		ClientID:       r.Header.Get("X-Client-ID"),
+		IdempotencyKey: r.Header.Get("Idempotency-Key"),
+
+		Unique:    dto.Unique,
+		UniqueKey: dto.UniqueKey,
+
+		CallbackURL: dto.CallbackURL,
+	}
+	if req.OnSuccess, err = dto.OnSuccess.toEntity(); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid on_success")
+		return
+	}
+	if req.OnFailure, err = dto.OnFailure.toEntity(); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid on_failure")
+		return
+	}
+	if dto.DelaySeconds != nil {
+		delay := time.Duration(*dto.DelaySeconds) * time.Second
+		req.Delay = &delay
+	}
+	if dto.TimeoutSeconds != nil {
+		if *dto.TimeoutSeconds <= 0 {
+			h.writeError(w, http.StatusBadRequest, "timeout_seconds must be positive")
+			return
+		}
+		req.Timeout = time.Duration(*dto.TimeoutSeconds) * time.Second
+	}
+	if dto.Retry != nil {
+		req.Retry = entity.RetryPolicy{
+			MaxAttempts:        dto.Retry.MaxAttempts,
+			BaseDelay:          time.Duration(dto.Retry.BaseDelayMs) * time.Millisecond,
+			MaxDelay:           time.Duration(dto.Retry.MaxDelayMs) * time.Millisecond,
+			TimeoutMaxAttempts: dto.Retry.TimeoutMaxAttempts,
+		}
+	}
+
+	id, err := h.jobSvc.CreateJob(r.Context(), req)
+	if errors.Is(err, service.ErrIdempotencyKeyReused) {
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
 	if err != nil {
 		h.writeError(w, http.StatusBadRequest, err.Error())
 		return
@@ -94,8 +351,145 @@ func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusCreated, createJobResp{ID: id.String()})
 }
 
+// ListJobs godoc
+// @Summary List jobs
+// @Description Jobs matching all given filters, ordered by (created_at, id); newest first by default.
+// @Description status, type and tag accept several values (repeated or comma-separated); a job must have all given tags.
+// @Description Pass next_cursor from the previous page as cursor to get the next one (keep the same filters and order).
+// @Tags jobs
+// @Produce json
+// @Param status query string false "status filter, e.g. error,dead"
+// @Param type query string false "job type filter"
+// @Param priority query int false "priority (0=low,1=normal,2=high)"
+// @Param created_from query string false "created_at >= (RFC3339)"
+// @Param created_to query string false "created_at < (RFC3339)"
+// @Param tag query string false "tag filter"
+// @Param parent_id query string false "follow-up jobs of this job (uuid)"
+// @Param order query string false "desc (default) or asc"
+// @Param limit query int false "limit (default 50, max 500)"
+// @Param cursor query string false "next_cursor from previous page"
+// @Success 200 {object} jobListResp
+// @Failure 400 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs [get]
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	f := entity.JobFilter{
+		Types: queryList(r, "type"),
+		Tags:  queryList(r, "tag"),
+		Order: entity.SortOrder(q.Get("order")),
+	}
+	for _, st := range queryList(r, "status") {
+		f.Statuses = append(f.Statuses, entity.JobStatus(st))
+	}
+	if q.Get("priority") != "" {
+		p, err := strconv.Atoi(q.Get("priority"))
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid priority")
+			return
+		}
+		f.Priority = &p
+	}
+	var err error
+	if p := q.Get("parent_id"); p != "" {
+		parentID, err := uuid.Parse(p)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid parent_id")
+			return
+		}
+		f.ParentID = &parentID
+	}
+	if f.CreatedFrom, err = queryTime(r, "created_from"); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid created_from (want RFC3339)")
+		return
+	}
+	if f.CreatedTo, err = queryTime(r, "created_to"); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid created_to (want RFC3339)")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultListLimit)
+	if err != nil || limit <= 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	f.Limit = limit
+	if c := q.Get("cursor"); c != "" {
+		if f.After, err = entity.ParseJobCursor(c); err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	page, err := h.jobSvc.ListJobs(r.Context(), f)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := jobListResp{Items: make([]jobResp, 0, len(page.Jobs))}
+	for _, j := range page.Jobs {
+		resp.Items = append(resp.Items, toJobResp(j))
+	}
+	if page.Next != nil {
+		resp.NextCursor = page.Next.String()
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// queryList collects repeated and comma-separated values: ?tag=a&tag=b,c -> [a b c].
+func queryList(r *http.Request, key string) []string {
+	var out []string
+	for _, v := range r.URL.Query()[key] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// queryWait parses duration ("30s") or whole seconds ("30"); 0 if absent.
+func queryWait(r *http.Request, key string) (time.Duration, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		secs, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			return 0, err
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	if d < 0 {
+		return 0, errors.New("negative wait")
+	}
+	return d, nil
+}
+
+func queryTime(r *http.Request, key string) (*time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 // GetJob godoc
 // @Summary Get job by id
+// @Description progress — the last progress reported by the job handler (percent, stage, message),
+// @Description saved at most once per second while the job is processing and reset when a new attempt starts.
 // @Tags jobs
 // @Produce json
 // @Param id path string true "job id (uuid)"
@@ -117,36 +511,82 @@ func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := jobResp{
-		ID:        j.ID.String(),
-		Type:      j.Type,
-		Status:    j.Status,
-		Priority:  j.Priority,
-		Error:     j.Error,
-		CreatedAt: j.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: j.UpdatedAt.Format(time.RFC3339),
-	}
+	h.writeJSON(w, http.StatusOK, toJobResp(j))
+}
 
-	// input/output: json.RawMessage -> map
-	if len(j.Input) > 0 {
-		_ = json.Unmarshal(j.Input, &resp.Input)
+type jobTransitionResp struct {
+	From    entity.JobStatus `json:"from,omitempty"` // нет — job создана
+	To      entity.JobStatus `json:"to"`
+	Attempt int              `json:"attempt"`
+	Actor   string           `json:"actor,omitempty"` // api:<client_id> | worker:<host>-<pid> | reaper | reconciler
+	Detail  string           `json:"detail,omitempty"`
+	At      string           `json:"at"`
+}
+
+type jobHistoryResp struct {
+	Items []jobTransitionResp `json:"items"`
+}
+
+// JobHistory godoc
+// @Summary Get status history of job
+// @Description Every status transition and every new attempt of the job, oldest first, with who made it:
+// @Description API client, worker, reaper (requeue after an expired lease) or reconciler.
+// @Description Two processing entries in a row mean the attempt was started again without finishing the previous one.
+// @Tags jobs
+// @Produce json
+// @Param id path string true "job id (uuid)"
+// @Success 200 {object} jobHistoryResp
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/history [get]
+func (h *Handler) JobHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
 	}
-	if j.Status == entity.StatusDone && len(j.Output) > 0 {
-		_ = json.Unmarshal(j.Output, &resp.Output)
+
+	history, err := h.jobSvc.History(r.Context(), id)
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
+	resp := jobHistoryResp{Items: make([]jobTransitionResp, 0, len(history))}
+	for _, t := range history {
+		resp.Items = append(resp.Items, jobTransitionResp{
+			From:    t.From,
+			To:      t.To,
+			Attempt: t.Attempt,
+			Actor:   t.Actor,
+			Detail:  t.Detail,
+			At:      t.At.Format(time.RFC3339Nano),
+		})
+	}
 	h.writeJSON(w, http.StatusOK, resp)
 }
 
+// maxResultWait — верхняя граница ?wait= у GET /jobs/{id}/result.
+const maxResultWait = 60 * time.Second
+
 // GetJobResult godoc
 // @Summary Get job result
+// @Description With wait (e.g. 30s or 30, max 60s; more is 400) the request blocks until the job reaches a final status
+// @Description (done, error, dead, canceled) or the wait expires; 409 if the job is still not done.
 // @Tags jobs
 // @Produce json
 // @Param id path string true "job id (uuid)"
+// @Param wait query string false "long-poll timeout: duration (30s) or seconds (30), max 60s"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} apiError
 // @Failure 404 {object} apiError
 // @Failure 409 {object} apiError
+// @Failure 500 {object} apiError
 // @Router /jobs/{id}/result [get]
 func (h *Handler) GetJobResult(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -155,11 +595,32 @@ func (h *Handler) GetJobResult(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusBadRequest, "invalid id")
 		return
 	}
-
-	j, err := h.jobSvc.GetJob(r.Context(), id)
+	wait, err := queryWait(r, "wait")
 	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid wait (want duration like 30s or seconds)")
+		return
+	}
+	if wait > maxResultWait {
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("wait must not exceed %s", maxResultWait))
+		return
+	}
+
+	var j *entity.Job
+	if wait > 0 {
+		j, err = h.eventSvc.WaitFinal(r.Context(), id, wait)
+	} else {
+		j, err = h.jobSvc.GetJob(r.Context(), id)
+	}
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
 		h.writeError(w, http.StatusNotFound, "job not found")
 		return
+	case r.Context().Err() != nil:
+		// клиент отключился, ответ никто не прочитает
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 	if j.Status != entity.StatusDone {
 		h.writeError(w, http.StatusConflict, "job not done")
@@ -171,3 +632,136 @@ func (h *Handler) GetJobResult(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(j.Output)
 }
+
+// CancelJob godoc
+// @Summary Cancel job
+// @Description Pending job is removed from the queue, processing job gets its handler context cancelled
+// @Description on the worker that holds it. In both cases status becomes canceled.
+// @Tags jobs
+// @Produce json
+// @Param id path string true "job id (uuid)"
+// @Success 200 {object} jobResp
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 409 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/cancel [post]
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	j, err := h.cancelSvc.Cancel(r.Context(), id)
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, service.ErrNotCancelable):
+		h.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toJobResp(j))
+}
+
+// RetryJob godoc
+// @Summary Retry finished job
+// @Description Resets error / dead / canceled job (pending, attempts=0, no error and output) and enqueues it
+// @Description with its original priority or with priority from the body. Dead job is removed from the dead-letter queue.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "job id (uuid)"
+// @Param request body retryJobDTO false "priority override"
+// @Success 202 {object} jobResp
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 409 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/retry [post]
+func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var dto retryJobDTO
+	if !h.decodeOptional(w, r, &dto) {
+		return
+	}
+	j, err := h.retrySvc.Retry(r.Context(), id, dto.Priority)
+	switch {
+	case errors.Is(err, service.ErrInvalidPriority):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, service.ErrNotRetryable), errors.Is(err, entity.ErrUniqueJobExists):
+		h.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.writeJSON(w, http.StatusAccepted, toJobResp(j))
+}
+
+// CloneJob godoc
+// @Summary Clone job
+// @Description Creates a new job with the same type, retry policy, timeout and tags as the source job (any status).
+// @Description input from the body is a JSON merge patch over the source input: keys are replaced, null removes a key.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "source job id (uuid)"
+// @Param request body cloneJobDTO false "input patch and priority override"
+// @Success 201 {object} createJobResp
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/clone [post]
+func (h *Handler) CloneJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var dto cloneJobDTO
+	if !h.decodeOptional(w, r, &dto) {
+		return
+	}
+
+	newID, err := h.jobSvc.CloneJob(r.Context(), id, service.CloneJobRequest{InputPatch: dto.Input, Priority: dto.Priority})
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, service.ErrInvalidPriority), errors.Is(err, service.ErrInvalidInputPatch):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, createJobResp{ID: newID.String()})
+}
+
+// decodeOptional decodes JSON body into dst; empty body is allowed. Writes 400 and returns false on bad JSON.
+func (h *Handler) decodeOptional(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return false
+	}
+	return true
+}
@@ -1,9 +1,11 @@
 package httptransport_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,80 +15,75 @@ import (
 	"github.com/google/uuid"
 
 	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/memory"
 	"job-worker-service/internal/service"
 	httptransport "job-worker-service/internal/transport/http"
 )
 
-// ---- fakes ----
+// ---- helpers ----
 
-type repoWithJobs struct {
-	createID uuid.UUID
-	jobs     map[uuid.UUID]*entity.Job
+type testEnv struct {
+	repo   *memory.JobRepository
+	queue  *memory.Queue
+	router http.Handler
 }
 
-func (r *repoWithJobs) Create(ctx context.Context, typ string, priority int, input json.RawMessage) (uuid.UUID, error) {
-	now := time.Now().UTC()
+func newTestEnv() *testEnv {
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
 
-	j := &entity.Job{
-		ID:        r.createID,
-		Type:      typ,
-		Status:    entity.StatusPending,
-		Priority:  priority,
-		Input:     input,
-		Output:    json.RawMessage(`{}`),
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	cancelSvc := service.NewCancelService(repo, queue)
+	retrySvc := service.NewRetryService(repo, queue, nil, queue)
+	deadSvc := service.NewDeadLetterService(repo, queue, retrySvc)
+	eventSvc := service.NewEventService(repo, repo)
+	webhookSvc := service.NewWebhookService(repo, repo, nil, service.WebhookConfig{})
+	logSvc := service.NewJobLogService(repo)
+	h := httptransport.NewHandler(svc, deadSvc, cancelSvc, retrySvc, eventSvc, webhookSvc, logSvc)
 
-	if r.jobs == nil {
-		r.jobs = map[uuid.UUID]*entity.Job{}
-	}
-	r.jobs[r.createID] = j
-	return r.createID, nil
+	return &testEnv{repo: repo, queue: queue, router: httptransport.Routes(h)}
 }
 
-func (r *repoWithJobs) GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error) {
-	j, ok := r.jobs[id]
-	if !ok {
-		return nil, context.Canceled // любой err => handler вернёт 404 (в твоей реализации)
+// createJob кладёт job прямо в репозиторий (в обход очереди).
+func (e *testEnv) createJob(t *testing.T, job entity.Job) uuid.UUID {
+	t.Helper()
+	id, err := e.repo.Create(context.Background(), &job)
+	if err != nil {
+		t.Fatalf("create job: %v", err)
 	}
-	return j, nil
-}
-
-type queueStub struct {
-	enqueuedIDs        []string
-	enqueuedPriorities []int
+	return id
 }
 
-func (q *queueStub) Enqueue(ctx context.Context, jobID string, priority int) error {
-	q.enqueuedIDs = append(q.enqueuedIDs, jobID)
-	q.enqueuedPriorities = append(q.enqueuedPriorities, priority)
-	return nil
+// startJob берёт job в работу, как worker, и возвращает fencing token попытки.
+func (e *testEnv) startJob(t *testing.T, id uuid.UUID) int64 {
+	t.Helper()
+	token, err := e.repo.StartAttempt(context.Background(), id, time.Minute)
+	if err != nil {
+		t.Fatalf("start attempt %s: %v", id, err)
+	}
+	return token
 }
 
-// ---- helpers ----
-
-func newTestRouter(repo service.JobRepository, queue service.JobQueue) http.Handler {
-	svc := service.NewJobService(repo, queue)
-	h := httptransport.NewHandler(svc)
-	return httptransport.Routes(h)
+func (e *testEnv) job(t *testing.T, id uuid.UUID) *entity.Job {
+	t.Helper()
+	j, err := e.repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get job %s: %v", id, err)
+	}
+	return j
 }
 
 // ---- tests ----
 
 func TestHTTP_CreateJob_201_AndPriorityStored(t *testing.T) {
-	id := uuid.MustParse("33333333-3333-3333-3333-333333333333")
-
-	repo := &repoWithJobs{createID: id, jobs: map[uuid.UUID]*entity.Job{}}
-	queue := &queueStub{}
-	router := newTestRouter(repo, queue)
+	env := newTestEnv()
 
 	body := `{"type":"echo","priority":2,"input":{"hello":"world"}}`
 	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	router.ServeHTTP(rr, req)
+	env.router.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
@@ -98,22 +95,19 @@ func TestHTTP_CreateJob_201_AndPriorityStored(t *testing.T) {
 	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("invalid json response: %v, body=%s", err, rr.Body.String())
 	}
-	if resp.ID != id.String() {
-		t.Fatalf("expected id=%s, got %s", id.String(), resp.ID)
+	if _, err := uuid.Parse(resp.ID); err != nil {
+		t.Fatalf("expected uuid id, got %q", resp.ID)
 	}
 
 	// очередь получила priority=2
-	if len(queue.enqueuedIDs) != 1 || queue.enqueuedIDs[0] != id.String() {
-		t.Fatalf("expected enqueue id=%s, got %#v", id.String(), queue.enqueuedIDs)
-	}
-	if len(queue.enqueuedPriorities) != 1 || queue.enqueuedPriorities[0] != 2 {
-		t.Fatalf("expected enqueue priority=2, got %#v", queue.enqueuedPriorities)
+	if pending := env.queue.Pending(2); len(pending) != 1 || pending[0] != resp.ID {
+		t.Fatalf("expected id=%s in high lane, got %#v", resp.ID, pending)
 	}
 
 	// GET /jobs/{id} должен вернуть priority=2
-	req2 := httptest.NewRequest(http.MethodGet, "/jobs/"+id.String(), nil)
+	req2 := httptest.NewRequest(http.MethodGet, "/jobs/"+resp.ID, nil)
 	rr2 := httptest.NewRecorder()
-	router.ServeHTTP(rr2, req2)
+	env.router.ServeHTTP(rr2, req2)
 
 	if rr2.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d, body=%s", rr2.Code, rr2.Body.String())
@@ -131,53 +125,138 @@ func TestHTTP_CreateJob_201_AndPriorityStored(t *testing.T) {
 }
 
 func TestHTTP_CreateJob_DefaultPriorityIs1(t *testing.T) {
-	id := uuid.MustParse("44444444-4444-4444-4444-444444444444")
-
-	repo := &repoWithJobs{createID: id, jobs: map[uuid.UUID]*entity.Job{}}
-	queue := &queueStub{}
-	router := newTestRouter(repo, queue)
+	env := newTestEnv()
 
 	body := `{"type":"echo","input":{"hello":"world"}}` // без priority
 	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	router.ServeHTTP(rr, req)
+	env.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+
+	if pending := env.queue.Pending(1); len(pending) != 1 {
+		t.Fatalf("expected job in normal lane (default priority=1), got %#v", pending)
+	}
+}
+
+func TestHTTP_CreateJob_DelayedGoesToScheduledSet(t *testing.T) {
+	env := newTestEnv()
+
+	body := `{"type":"echo","input":{},"delay_seconds":60}`
+	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v, body=%s", err, rr.Body.String())
+	}
+
+	if pending := env.queue.Pending(1); len(pending) != 0 {
+		t.Fatalf("expected no immediate enqueue, got %#v", pending)
+	}
+	at, ok := env.queue.Scheduled(resp.ID.String())
+	if !ok {
+		t.Fatalf("expected job in scheduled set")
+	}
+	if d := time.Until(at); d < 55*time.Second || d > 65*time.Second {
+		t.Fatalf("expected run in ~60s, got %s", d)
+	}
+	if runAt := env.job(t, resp.ID).RunAt; runAt == nil || !runAt.Equal(at) {
+		t.Fatalf("expected run_at stored on job, got %v", runAt)
+	}
+
+	// run_at и delay_seconds одновременно — ошибка
+	runAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body = `{"type":"echo","input":{},"delay_seconds":60,"run_at":"` + runAt + `"}`
+	req = httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHTTP_CreateJob_TimeoutSeconds(t *testing.T) {
+	env := newTestEnv()
+
+	body := `{"type":"convert_video","input":{},"timeout_seconds":90,"retry":{"timeout_max_attempts":1}}`
+	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
 	}
+	var resp struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json response: %v, body=%s", err, rr.Body.String())
+	}
+	if j := env.job(t, resp.ID); j.Timeout != 90*time.Second || j.Retry.TimeoutMaxAttempts != 1 {
+		t.Fatalf("expected timeout=90s timeout_max_attempts=1, got %s / %d", j.Timeout, j.Retry.TimeoutMaxAttempts)
+	}
+
+	body = `{"type":"convert_video","input":{},"timeout_seconds":0}`
+	req = httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
 
-	if len(queue.enqueuedPriorities) != 1 || queue.enqueuedPriorities[0] != 1 {
-		t.Fatalf("expected default priority=1, got %#v", queue.enqueuedPriorities)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHTTP_CreateJob_IdempotencyKey(t *testing.T) {
+	env := newTestEnv()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "report-2026-03")
+		req.Header.Set("X-Client-ID", "gateway")
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	body := `{"type":"generate_report","input":{"month":3}}`
+	first := post(body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", first.Code, first.Body.String())
+	}
+	again := post(body)
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Fatalf("expected original response %s, got %d %s", first.Body.String(), again.Code, again.Body.String())
+	}
+	if lane := env.queue.Pending(1); len(lane) != 1 {
+		t.Fatalf("expected one job enqueued, got %v", lane)
+	}
+
+	if rr := post(`{"type":"generate_report","input":{"month":4}}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d, body=%s", rr.Code, rr.Body.String())
 	}
 }
 
 func TestHTTP_GetJobResult_409_WhenNotDone(t *testing.T) {
-	id := uuid.MustParse("55555555-5555-5555-5555-555555555555")
-
-	repo := &repoWithJobs{
-		createID: id,
-		jobs: map[uuid.UUID]*entity.Job{
-			id: {
-				ID:        id,
-				Type:      "echo",
-				Status:    entity.StatusProcessing,
-				Priority:  1,
-				Input:     json.RawMessage(`{"a":1}`),
-				Output:    json.RawMessage(`{"a":1}`),
-				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
-			},
-		},
-	}
-	queue := &queueStub{}
-	router := newTestRouter(repo, queue)
+	env := newTestEnv()
+	id := env.createJob(t, entity.Job{Type: "echo", Priority: 1, Input: json.RawMessage(`{"a":1}`)})
+	env.startJob(t, id) // processing
 
 	req := httptest.NewRequest(http.MethodGet, "/jobs/"+id.String()+"/result", nil)
 	rr := httptest.NewRecorder()
 
-	router.ServeHTTP(rr, req)
+	env.router.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusConflict {
 		t.Fatalf("expected 409, got %d, body=%s", rr.Code, rr.Body.String())
@@ -185,30 +264,14 @@ func TestHTTP_GetJobResult_409_WhenNotDone(t *testing.T) {
 }
 
 func TestHTTP_GetJobResult_200_WhenDone_ReturnsRawJSON(t *testing.T) {
-	id := uuid.MustParse("66666666-6666-6666-6666-666666666666")
-
-	repo := &repoWithJobs{
-		createID: id,
-		jobs: map[uuid.UUID]*entity.Job{
-			id: {
-				ID:        id,
-				Type:      "echo",
-				Status:    entity.StatusDone,
-				Priority:  1,
-				Input:     json.RawMessage(`{"hello":"world"}`),
-				Output:    json.RawMessage(`{"hello":"world"}`),
-				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
-			},
-		},
-	}
-	queue := &queueStub{}
-	router := newTestRouter(repo, queue)
+	env := newTestEnv()
+	id := env.createJob(t, entity.Job{Type: "echo", Priority: 1, Input: json.RawMessage(`{"hello":"world"}`)})
+	_ = env.repo.SetResultDone(context.Background(), id, env.startJob(t, id), json.RawMessage(`{"hello":"world"}`))
 
 	req := httptest.NewRequest(http.MethodGet, "/jobs/"+id.String()+"/result", nil)
 	rr := httptest.NewRecorder()
 
-	router.ServeHTTP(rr, req)
+	env.router.ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d, body=%s", rr.Code, rr.Body.String())
@@ -219,3 +282,828 @@ func TestHTTP_GetJobResult_200_WhenDone_ReturnsRawJSON(t *testing.T) {
 		t.Fatalf("expected raw json output, got %s", got)
 	}
 }
+
+func TestHTTP_GetJob_404_WhenMissing(t *testing.T) {
+	env := newTestEnv()
+
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+uuid.NewString(), nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHTTP_DeadLetters_ListAndRequeue(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv()
+
+	id := env.createJob(t, entity.Job{Type: "echo", Priority: 2, Input: json.RawMessage(`{}`)})
+	_ = env.repo.SetDead(ctx, id, env.startJob(t, id), entity.ErrorClassError, "boom")
+
+	// новые первыми: poison id раньше, dead job — позже
+	_ = env.queue.DeadLetter(ctx, "garbage", "invalid job id")
+	_ = env.queue.DeadLetter(ctx, id.String(), "boom")
+
+	// list: job из БД подтягивается, poison id — без job
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/dead-letters", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	var list struct {
+		Items []struct {
+			JobID  string         `json:"job_id"`
+			Reason string         `json:"reason"`
+			Job    map[string]any `json:"job"`
+		} `json:"items"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid json: %v, body=%s", err, rr.Body.String())
+	}
+	if list.Total != 2 || len(list.Items) != 2 {
+		t.Fatalf("expected 2 dead letters, got %s", rr.Body.String())
+	}
+	if list.Items[0].Job == nil || list.Items[0].Job["status"] != "dead" {
+		t.Fatalf("expected dead job attached, got %#v", list.Items[0].Job)
+	}
+	if list.Items[1].Job != nil {
+		t.Fatalf("expected no job for poison id, got %#v", list.Items[1].Job)
+	}
+
+	// poison id вернуть в очередь нельзя
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/dead-letters/garbage/requeue", nil))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+
+	// requeue: pending, attempts=0, в очередь с исходным priority, из dead-letter удалена
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/dead-letters/"+id.String()+"/requeue", nil))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if j := env.job(t, id); j.Status != entity.StatusPending || j.Attempts != 0 {
+		t.Fatalf("expected job reset to pending, got status=%s attempts=%d", j.Status, j.Attempts)
+	}
+	if pending := env.queue.Pending(2); len(pending) != 1 || pending[0] != id.String() {
+		t.Fatalf("expected job back in high lane, got %#v", pending)
+	}
+	if _, err := env.queue.GetDead(ctx, id.String()); err == nil {
+		t.Fatalf("expected job removed from dead-letter queue")
+	}
+}
+
+func TestHTTP_CancelJob(t *testing.T) {
+	env := newTestEnv()
+	ctx := context.Background()
+
+	cancel := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jobs/"+id+"/cancel", nil)
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// pending: снимается с очереди
+	pending := env.createJob(t, entity.Job{Type: "echo", Priority: 2})
+	_ = env.queue.Enqueue(ctx, pending.String(), 2)
+
+	rr := cancel(pending.String())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"status":"canceled"`) {
+		t.Fatalf("expected canceled job in response, got %s", rr.Body.String())
+	}
+	if lane := env.queue.Pending(2); len(lane) != 0 {
+		t.Fatalf("expected job removed from lane, got %v", lane)
+	}
+
+	// processing: сигнал worker'у
+	running := env.createJob(t, entity.Job{Type: "echo", Priority: 1})
+	env.startJob(t, running)
+
+	if rr := cancel(running.String()); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if ids, _ := env.queue.CancelRequested(ctx, []string{running.String()}); len(ids) != 1 {
+		t.Fatalf("expected cancel signal for running job, got %v", ids)
+	}
+	if st := env.job(t, running).Status; st != entity.StatusCanceled {
+		t.Fatalf("expected status canceled, got %s", st)
+	}
+
+	// уже отменена / нет такой / невалидный id
+	if rr := cancel(running.String()); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if rr := cancel(uuid.NewString()); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if rr := cancel("nope"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_ListJobs_FiltersAndCursor(t *testing.T) {
+	env := newTestEnv()
+	ctx := context.Background()
+
+	var failed []uuid.UUID
+	for i := 0; i < 5; i++ {
+		id := env.createJob(t, entity.Job{Type: "generate_report", Tags: []string{"billing", "nightly"}})
+		_ = env.repo.SetResultError(ctx, id, 0, "boom") // pending: token ещё 0
+		failed = append(failed, id)
+	}
+	env.createJob(t, entity.Job{Type: "generate_report", Tags: []string{"billing"}}) // pending
+	env.createJob(t, entity.Job{Type: "echo", Tags: []string{"billing", "nightly"}}) // другой type
+
+	list := func(query string) (int, struct {
+		Items []struct {
+			ID     string           `json:"id"`
+			Status entity.JobStatus `json:"status"`
+			Tags   []string         `json:"tags"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}) {
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs?"+query, nil))
+		var resp struct {
+			Items []struct {
+				ID     string           `json:"id"`
+				Status entity.JobStatus `json:"status"`
+				Tags   []string         `json:"tags"`
+			} `json:"items"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json response: %v, body=%s", err, rr.Body.String())
+			}
+		}
+		return rr.Code, resp
+	}
+
+	// постранично по 2, новые первыми: 2 + 2 + 1, без дублей
+	var got []string
+	query := "status=error,dead&type=generate_report&tag=billing&tag=nightly&limit=2"
+	for page := 0; ; page++ {
+		if page > 3 {
+			t.Fatal("too many pages")
+		}
+		code, resp := list(query)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		for _, it := range resp.Items {
+			if it.Status != entity.StatusError {
+				t.Fatalf("unexpected status %s", it.Status)
+			}
+			got = append(got, it.ID)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		query = "status=error,dead&type=generate_report&tag=billing&tag=nightly&limit=2&cursor=" + resp.NextCursor
+	}
+	if len(got) != len(failed) {
+		t.Fatalf("expected %d jobs, got %d: %v", len(failed), len(got), got)
+	}
+
+	// asc отдаёт тот же набор в обратном порядке
+	_, asc := list("status=error&type=generate_report&order=asc&limit=10")
+	if len(asc.Items) != len(got) {
+		t.Fatalf("expected %d jobs, got %d", len(got), len(asc.Items))
+	}
+	for i, it := range asc.Items {
+		if it.ID != got[len(got)-1-i] {
+			t.Fatalf("asc order mismatch at %d: %s vs %s", i, it.ID, got[len(got)-1-i])
+		}
+	}
+
+	if _, resp := list("tag=billing"); len(resp.Items) != 7 {
+		t.Fatalf("expected 7 jobs tagged billing, got %d", len(resp.Items))
+	}
+	if _, resp := list("created_from=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); len(resp.Items) != 0 {
+		t.Fatalf("expected no jobs created in the future, got %d", len(resp.Items))
+	}
+
+	for _, bad := range []string{"status=bogus", "order=sideways", "limit=0", "priority=x", "created_to=yesterday", "cursor=garbage"} {
+		if code, _ := list(bad); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", bad, code)
+		}
+	}
+}
+
+func TestHTTP_RetryJob(t *testing.T) {
+	env := newTestEnv()
+	ctx := context.Background()
+
+	retry := func(id, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/jobs/"+id+"/retry", strings.NewReader(body)))
+		return rr
+	}
+
+	// canceled во время выполнения: сигнал отмены снимается, иначе worker сразу отменит job снова
+	id := env.createJob(t, entity.Job{Type: "echo", Priority: 0})
+	env.startJob(t, id)
+	_, _ = env.repo.Cancel(ctx, id)
+	_ = env.queue.SignalCancel(ctx, id.String())
+
+	if rr := retry(id.String(), `{"priority":2}`); rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	j := env.job(t, id)
+	if j.Status != entity.StatusPending || j.Attempts != 0 || j.Priority != 2 {
+		t.Fatalf("expected pending, attempts=0, priority=2, got %s, %d, %d", j.Status, j.Attempts, j.Priority)
+	}
+	if lane := env.queue.Pending(2); len(lane) != 1 || lane[0] != id.String() {
+		t.Fatalf("expected job in high lane, got %v", lane)
+	}
+	if ids, _ := env.queue.CancelRequested(ctx, []string{id.String()}); len(ids) != 0 {
+		t.Fatalf("expected cancel signal cleared, got %v", ids)
+	}
+
+	// dead: без body — исходный priority, запись dead-letter убирается
+	dead := env.createJob(t, entity.Job{Type: "echo", Priority: 1})
+	_ = env.repo.SetDead(ctx, dead, env.startJob(t, dead), entity.ErrorClassError, "boom")
+	_ = env.queue.DeadLetter(ctx, dead.String(), "boom")
+
+	if rr := retry(dead.String(), ""); rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if lane := env.queue.Pending(1); len(lane) != 1 || lane[0] != dead.String() {
+		t.Fatalf("expected job in normal lane, got %v", lane)
+	}
+	if _, err := env.queue.GetDead(ctx, dead.String()); err == nil {
+		t.Fatal("expected dead letter removed")
+	}
+
+	// pending (уже повторена) / нет такой / плохой priority
+	if rr := retry(id.String(), ""); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if rr := retry(uuid.NewString(), ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if rr := retry(dead.String(), `{"priority":7}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_CloneJob_PatchesInput(t *testing.T) {
+	env := newTestEnv()
+
+	src := env.createJob(t, entity.Job{
+		Type:     "generate_report",
+		Priority: 2,
+		Input:    json.RawMessage(`{"report":"sales","range":{"from":"2026-01-01","to":"2026-02-01"},"debug":true}`),
+		Retry:    entity.RetryPolicy{MaxAttempts: 7},
+		Tags:     []string{"billing"},
+	})
+	_ = env.repo.SetResultError(context.Background(), src, 0, "boom")
+
+	body := `{"input":{"range":{"to":"2026-03-01"},"debug":null}}`
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/jobs/"+src.String()+"/clone", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	clone := env.job(t, uuid.MustParse(resp.ID))
+
+	if clone.Status != entity.StatusPending || clone.Type != "generate_report" || clone.Priority != 2 {
+		t.Fatalf("unexpected clone: status=%s type=%s priority=%d", clone.Status, clone.Type, clone.Priority)
+	}
+	if clone.Retry.MaxAttempts != 7 || len(clone.Tags) != 1 || clone.Tags[0] != "billing" {
+		t.Fatalf("expected retry policy and tags copied, got %+v %v", clone.Retry, clone.Tags)
+	}
+	var input map[string]any
+	_ = json.Unmarshal(clone.Input, &input)
+	want := map[string]any{"report": "sales", "range": map[string]any{"from": "2026-01-01", "to": "2026-03-01"}}
+	if got, _ := json.Marshal(input); string(got) != mustJSON(t, want) {
+		t.Fatalf("expected input %s, got %s", mustJSON(t, want), got)
+	}
+	if lane := env.queue.Pending(2); len(lane) != 1 || lane[0] != resp.ID {
+		t.Fatalf("expected clone enqueued, got %v", lane)
+	}
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/jobs/"+uuid.NewString()+"/clone", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/jobs/"+src.String()+"/clone", strings.NewReader(`{"priority":7}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid priority, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_Webhooks_ClientSettingsAndDeliveries(t *testing.T) {
+	env := newTestEnv()
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	acme := map[string]string{"X-Client-ID": "acme"}
+	for _, header := range []map[string]string{nil, {"X-Client-ID": "other"}} {
+		if rr := do(http.MethodPut, "/clients/acme/webhook", `{"url":"https://evil.example/hook"}`, header); rr.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 for X-Client-ID %v, got %d", header, rr.Code)
+		}
+		if rr := do(http.MethodDelete, "/clients/acme/webhook", "", header); rr.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 for X-Client-ID %v, got %d", header, rr.Code)
+		}
+	}
+	if rr := do(http.MethodPut, "/clients/acme/webhook", `{"url":"localhost/hook"}`, acme); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for relative url, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPut, "/clients/acme/webhook", `{"url":"https://acme.example/hook","secret":"s3cret"}`, acme); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodGet, "/clients/acme/webhook", "", map[string]string{"X-Client-ID": "other"}); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for another client, got %d", rr.Code)
+	}
+	rr := do(http.MethodGet, "/clients/acme/webhook", "", acme)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "s3cret") {
+		t.Fatalf("expected settings without secret, got %d %s", rr.Code, rr.Body.String())
+	}
+	var settings struct {
+		URL       string `json:"url"`
+		HasSecret bool   `json:"has_secret"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &settings)
+	if settings.URL != "https://acme.example/hook" || !settings.HasSecret {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+
+	// callback_url job важнее URL клиента
+	create := func(body string) uuid.UUID {
+		rr := do(http.MethodPost, "/jobs", body, acme)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+		return uuid.MustParse(resp.ID)
+	}
+	withCallback := create(`{"type":"echo","input":{},"callback_url":"https://consumer.example/done"}`)
+	withDefault := create(`{"type":"echo","input":{}}`)
+	if rr := do(http.MethodPost, "/jobs", `{"type":"echo","callback_url":"consumer.example"}`, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid callback_url, got %d", rr.Code)
+	}
+	if j := env.job(t, withCallback); j.CallbackURL != "https://consumer.example/done" || j.ClientID != "acme" {
+		t.Fatalf("unexpected job: callback_url=%q client_id=%q", j.CallbackURL, j.ClientID)
+	}
+
+	ctx := context.Background()
+	_ = env.repo.SetResultDone(ctx, withCallback, env.startJob(t, withCallback), json.RawMessage(`{}`))
+	_ = env.repo.SetDead(ctx, withDefault, env.startJob(t, withDefault), entity.ErrorClassError, "boom")
+
+	list := func(id uuid.UUID) []map[string]any {
+		rr := do(http.MethodGet, "/jobs/"+id.String()+"/webhooks", "", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d, body=%s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Items []map[string]any `json:"items"`
+		}
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp.Items
+	}
+	items := list(withCallback)
+	if len(items) != 1 || items[0]["url"] != "https://consumer.example/done" || items[0]["event"] != "job.done" || items[0]["status"] != "pending" {
+		t.Fatalf("unexpected deliveries: %v", items)
+	}
+	items = list(withDefault)
+	if len(items) != 1 || items[0]["url"] != "https://acme.example/hook" || items[0]["event"] != "job.dead" {
+		t.Fatalf("unexpected deliveries: %v", items)
+	}
+
+	if rr := do(http.MethodGet, "/jobs/"+uuid.NewString()+"/webhooks", "", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/clients/acme/webhook", "", acme); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/clients/acme/webhook", "", acme); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", rr.Code)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// sseReader читает события text/event-stream: (event, data); ok=false — поток закрыт.
+type sseReader struct {
+	sc *bufio.Scanner
+}
+
+func (r *sseReader) next(t *testing.T) (event string, data string, ok bool) {
+	t.Helper()
+	for r.sc.Scan() {
+		line := r.sc.Text()
+		switch {
+		case line == "" && event != "":
+			return event, data, true
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return "", "", false
+}
+
+func openSSE(t *testing.T, url string) *sseReader {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected 200 text/event-stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return &sseReader{sc: bufio.NewScanner(resp.Body)}
+}
+
+func TestHTTP_JobEvents_StreamsUntilFinalStatus(t *testing.T) {
+	env := newTestEnv()
+	srv := httptest.NewServer(env.router)
+	t.Cleanup(srv.Close) // после закрытия потоков (Cleanup — в обратном порядке)
+	ctx := context.Background()
+
+	id := env.createJob(t, entity.Job{Type: "convert_video"})
+	other := env.createJob(t, entity.Job{Type: "echo"})
+
+	stream := openSSE(t, srv.URL+"/jobs/"+id.String()+"/events")
+	feed := openSSE(t, srv.URL+"/events?type=convert_video")
+
+	expect := func(r *sseReader, wantEvent string, check func(ev entity.JobEvent)) {
+		t.Helper()
+		event, data, ok := r.next(t)
+		if !ok || event != wantEvent {
+			t.Fatalf("expected %s event, got %q %s (ok=%v)", wantEvent, event, data, ok)
+		}
+		var ev entity.JobEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatalf("invalid event json: %v, data=%s", err, data)
+		}
+		check(ev)
+	}
+
+	// snapshot
+	expect(stream, "status", func(ev entity.JobEvent) {
+		if ev.JobID != id || ev.Status != entity.StatusPending {
+			t.Fatalf("expected pending snapshot, got %+v", ev)
+		}
+	})
+
+	env.startJob(t, other) // другой type — не в feed
+	token := env.startJob(t, id)
+	_ = env.repo.SetProgress(ctx, id, token, entity.JobProgress{Percent: 40, Stage: "transcoding", Message: "pass 1"})
+
+	// прогресс виден и в GET /jobs/{id}
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+id.String(), nil))
+	var got struct {
+		Progress *struct {
+			Percent int    `json:"percent"`
+			Stage   string `json:"stage"`
+		} `json:"progress"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Progress == nil || got.Progress.Percent != 40 || got.Progress.Stage != "transcoding" {
+		t.Fatalf("expected progress in job response, got %s", rr.Body.String())
+	}
+
+	_ = env.repo.SetResultDone(ctx, id, token, json.RawMessage(`{"url":"s3://out.mp4"}`))
+
+	for _, r := range []*sseReader{stream, feed} {
+		expect(r, "status", func(ev entity.JobEvent) {
+			if ev.JobID != id || ev.Status != entity.StatusProcessing || ev.Attempts != 1 {
+				t.Fatalf("expected processing, got %+v", ev)
+			}
+		})
+		expect(r, "progress", func(ev entity.JobEvent) {
+			if ev.Progress == nil || *ev.Progress != 40 || ev.Stage != "transcoding" || ev.Message != "pass 1" {
+				t.Fatalf("expected progress 40, got %+v", ev)
+			}
+		})
+		expect(r, "status", func(ev entity.JobEvent) {
+			if ev.Status != entity.StatusDone || string(ev.Output) != `{"url":"s3://out.mp4"}` {
+				t.Fatalf("expected done with output, got %+v (output=%s)", ev, ev.Output)
+			}
+		})
+	}
+
+	// после финального статуса поток job закрывается
+	if event, _, ok := stream.next(t); ok {
+		t.Fatalf("expected stream closed, got %s", event)
+	}
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+uuid.NewString()+"/events", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_GetJobResult_WaitBlocksUntilDone(t *testing.T) {
+	env := newTestEnv()
+	ctx := context.Background()
+
+	get := func(id uuid.UUID, wait string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+id.String()+"/result?wait="+wait, nil))
+		return rr
+	}
+
+	id := env.createJob(t, entity.Job{Type: "echo"})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		token, _ := env.repo.StartAttempt(ctx, id, time.Minute) // не startJob: t.Fatalf нельзя из другой goroutine
+		_ = env.repo.SetResultDone(ctx, id, token, json.RawMessage(`{"echo":"hi"}`))
+	}()
+
+	start := time.Now()
+	rr := get(id, "5s")
+	if rr.Code != http.StatusOK || rr.Body.String() != `{"echo":"hi"}` {
+		t.Fatalf("expected 200 with output, got %d %s", rr.Code, rr.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected result right after completion, waited %s", elapsed)
+	}
+
+	// wait истёк — как без wait: 409
+	pending := env.createJob(t, entity.Job{Type: "echo"})
+	start = time.Now()
+	if rr := get(pending, "100ms"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected to wait 100ms, returned after %s", elapsed)
+	}
+
+	// финальный, но не done — сразу 409
+	failed := env.createJob(t, entity.Job{Type: "echo"})
+	_ = env.repo.SetResultError(ctx, failed, 0, "boom")
+	if rr := get(failed, "30"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+
+	if rr := get(pending, "soon"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	// больше 60s не обрезается молча
+	if rr := get(pending, "61s"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for wait above the limit, got %d", rr.Code)
+	}
+	if rr := get(uuid.New(), "1s"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_JobLogs_PaginatedAndFiltered(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv()
+	id := env.createJob(t, entity.Job{Type: "echo"})
+
+	var logs []entity.JobLog
+	for i := range 5 {
+		level := entity.LogInfo
+		if i == 3 {
+			level = entity.LogError
+		}
+		logs = append(logs, entity.JobLog{Attempt: 1 + i/3, Level: level, Message: fmt.Sprintf("line %d", i), At: time.Now()})
+	}
+	if err := env.repo.AppendLogs(ctx, id, 0, logs); err != nil {
+		t.Fatalf("append logs: %v", err)
+	}
+
+	type page struct {
+		Items []struct {
+			ID      int64  `json:"id"`
+			Attempt int    `json:"attempt"`
+			Level   string `json:"level"`
+			Message string `json:"message"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	get := func(query string) (int, page) {
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+id.String()+"/logs"+query, nil))
+		var p page
+		_ = json.Unmarshal(rr.Body.Bytes(), &p)
+		return rr.Code, p
+	}
+
+	// постранично по 2, в порядке записи
+	var msgs []string
+	cursor := ""
+	for range 3 {
+		code, p := get("?limit=2" + cursor)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		for _, l := range p.Items {
+			msgs = append(msgs, l.Message)
+		}
+		cursor = "&cursor=" + p.NextCursor
+		if p.NextCursor == "" {
+			break
+		}
+	}
+	if strings.Join(msgs, ",") != "line 0,line 1,line 2,line 3,line 4" {
+		t.Fatalf("unexpected pages: %v", msgs)
+	}
+
+	if _, p := get("?attempt=2&level=error"); len(p.Items) != 1 || p.Items[0].Message != "line 3" || p.Items[0].Level != "error" {
+		t.Fatalf("unexpected filtered logs: %+v", p.Items)
+	}
+	if code, _ := get("?level=debug"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown level, got %d", code)
+	}
+	if code, _ := get("?cursor=abc"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid cursor, got %d", code)
+	}
+
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+uuid.NewString()+"/logs", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown job, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_JobHistory_RecordsTransitionsWithActor(t *testing.T) {
+	env := newTestEnv()
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"type":"echo"}`))
+	req.Header.Set("X-Client-ID", "acme")
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	id := uuid.MustParse(created.ID)
+
+	// первый worker завис, reaper отдал job второму: две попытки подряд без результата первой
+	var token int64
+	for _, w := range []string{"worker:a", "worker:b"} {
+		var err error
+		env.repo.ExpireLease(id) // lease первой попытки истёк
+		if token, err = env.repo.StartAttempt(entity.WithActor(context.Background(), w), id, time.Minute); err != nil {
+			t.Fatalf("start attempt: %v", err)
+		}
+	}
+	if err := env.repo.SetResultError(entity.WithActor(context.Background(), "worker:b"), id, token, "boom"); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+created.ID+"/history", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var resp struct {
+		Items []struct {
+			From    string `json:"from"`
+			To      string `json:"to"`
+			Attempt int    `json:"attempt"`
+			Actor   string `json:"actor"`
+			Detail  string `json:"detail"`
+		} `json:"items"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	var got []string
+	for _, it := range resp.Items {
+		got = append(got, strings.TrimSpace(fmt.Sprintf("%s>%s #%d %s %s", it.From, it.To, it.Attempt, it.Actor, it.Detail)))
+	}
+	want := []string{
+		">pending #0 api:acme",
+		"pending>processing #1 worker:a",
+		"processing>processing #2 worker:b",
+		"processing>error #2 worker:b boom",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected history:\n got %q\nwant %q", got, want)
+	}
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+uuid.NewString()+"/history", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown job, got %d", rr.Code)
+	}
+}
+
+func TestHTTP_CreateJob_FollowUpTemplates(t *testing.T) {
+	env := newTestEnv()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	for _, bad := range []string{
+		`{"type":"convert_video","on_success":{"input":{"a":1}}}`,
+		`{"type":"convert_video","on_failure":{"type":"notify","input":{"url":{"$ref":"output"}}}}`,
+		`{"type":"convert_video","on_success":{"type":"generate_report","on_success":{"type":"notify","timeout_seconds":-1}}}`,
+		`{"type":"convert_video","on_success":{"type":"notify","input":[1]}}`,
+	} {
+		if rr := post(bad); rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", bad, rr.Code)
+		}
+	}
+
+	rr := post(`{"type":"convert_video","input":{"file":"a.mov"},
+		"on_success":{"type":"generate_report","input":{"video":{"$ref":"/output/url"},"limit":12345678901234567890},"delay_seconds":60,
+			"on_failure":{"type":"notify"}}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		ID uuid.UUID `json:"id"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs/"+created.ID.String(), nil))
+	var got struct {
+		OnSuccess struct {
+			Type         string         `json:"type"`
+			Input        map[string]any `json:"input"`
+			DelaySeconds int            `json:"delay_seconds"`
+			OnFailure    struct {
+				Type string `json:"type"`
+			} `json:"on_failure"`
+		} `json:"on_success"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %v, body=%s", err, rr.Body.String())
+	}
+	if s := got.OnSuccess; s.Type != "generate_report" || s.DelaySeconds != 60 || s.OnFailure.Type != "notify" || s.Input["video"] == nil {
+		t.Fatalf("unexpected on_success: %+v", s)
+	}
+	// input шаблона хранится как есть, без округления чисел
+	if !bytes.Contains(rr.Body.Bytes(), []byte(`"limit":12345678901234567890`)) {
+		t.Fatalf("expected template input kept verbatim, body=%s", rr.Body.String())
+	}
+
+	// follow-up job родителя — через GET /jobs?parent_id=
+	child := env.createJob(t, entity.Job{Type: "generate_report", ParentID: &created.ID})
+	env.createJob(t, entity.Job{Type: "generate_report"})
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs?parent_id="+created.ID.String(), nil))
+	var list struct {
+		Items []struct {
+			ID       string `json:"id"`
+			ParentID string `json:"parent_id"`
+		} `json:"items"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Items) != 1 || list.Items[0].ID != child.String() || list.Items[0].ParentID != created.ID.String() {
+		t.Fatalf("expected only the follow-up job, got %+v", list.Items)
+	}
+
+	rr = httptest.NewRecorder()
+	env.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/jobs?parent_id=nope", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid parent_id, got %d", rr.Code)
+	}
+}
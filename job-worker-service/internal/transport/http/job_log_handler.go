@@ -0,0 +1,99 @@
+package httptransport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+type jobLogResp struct {
+	ID      int64           `json:"id"`
+	Attempt int             `json:"attempt"`
+	Level   entity.LogLevel `json:"level"` // info | warn | error
+	Message string          `json:"message"`
+	At      string          `json:"at"`
+}
+
+type jobLogListResp struct {
+	Items      []jobLogResp `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"` // пусто на последней странице
+}
+
+// ListJobLogs godoc
+// @Summary List execution log of job
+// @Description Lines written by the job handler (Reporter.Logf / Warnf / Errorf) and errors of failed attempts,
+// @Description oldest first. Lines are saved in batches about once per second while the job is processing.
+// @Description level accepts several values (repeated or comma-separated).
+// @Tags jobs
+// @Produce json
+// @Param id path string true "job id (uuid)"
+// @Param attempt query int false "only lines of this attempt"
+// @Param level query string false "level filter, e.g. warn,error"
+// @Param limit query int false "limit (default 100, max 1000)"
+// @Param cursor query string false "next_cursor from previous page"
+// @Success 200 {object} jobLogListResp
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/logs [get]
+func (h *Handler) ListJobLogs(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var f entity.JobLogFilter
+	for _, l := range queryList(r, "level") {
+		f.Levels = append(f.Levels, entity.LogLevel(l))
+	}
+	if f.Attempt, err = queryInt(r, "attempt", 0); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid attempt")
+		return
+	}
+	if f.Limit, err = queryInt(r, "limit", service.DefaultLogLimit); err != nil || f.Limit <= 0 {
+		h.writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		if f.After, err = strconv.ParseInt(c, 10, 64); err != nil || f.After <= 0 {
+			h.writeError(w, http.StatusBadRequest, entity.ErrInvalidCursor.Error())
+			return
+		}
+	}
+
+	page, err := h.logSvc.Logs(r.Context(), id, f)
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, service.ErrInvalidFilter):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := jobLogListResp{Items: make([]jobLogResp, 0, len(page.Logs))}
+	for _, l := range page.Logs {
+		resp.Items = append(resp.Items, jobLogResp{
+			ID:      l.ID,
+			Attempt: l.Attempt,
+			Level:   l.Level,
+			Message: l.Message,
+			At:      l.At.Format(time.RFC3339Nano),
+		})
+	}
+	if page.Next != 0 {
+		resp.NextCursor = strconv.FormatInt(page.Next, 10)
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
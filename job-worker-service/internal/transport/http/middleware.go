@@ -5,7 +5,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+
+	"job-worker-service/internal/entity"
 )
 
 type statusWriter struct {
@@ -28,6 +31,34 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap — для http.ResponseController (Flush в SSE).
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Actor помечает изменения job запросом API ("api" или "api:<X-Client-ID>") для истории job.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := entity.ActorAPI
+		if client := r.Header.Get("X-Client-ID"); client != "" {
+			actor += ":" + client
+		}
+		next.ServeHTTP(w, r.WithContext(entity.WithActor(r.Context(), actor)))
+	})
+}
+
+// requireClient пускает к ресурсам клиента ({client_id} в пути) только запросы с тем же X-Client-ID.
+func (h *Handler) requireClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := r.Header.Get("X-Client-ID")
+		if client == "" || client != chi.URLParam(r, "client_id") {
+			h.writeError(w, http.StatusForbidden, "X-Client-ID must match client_id")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sw := &statusWriter{ResponseWriter: w}
@@ -18,6 +18,7 @@ func Routes(h *Handler) http.Handler {
 
 	// наш логгер (после RequestID)
 	r.Use(RequestLogger)
+	r.Use(Actor)
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("ok"))
@@ -25,8 +26,33 @@ func Routes(h *Handler) http.Handler {
 
 	r.Route("/jobs", func(r chi.Router) {
 		r.Post("/", h.CreateJob)
+		r.Get("/", h.ListJobs)
 		r.Get("/{id}", h.GetJob)
 		r.Get("/{id}/result", h.GetJobResult)
+		r.Post("/{id}/cancel", h.CancelJob)
+		r.Post("/{id}/retry", h.RetryJob)
+		r.Post("/{id}/clone", h.CloneJob)
+		r.Get("/{id}/events", h.JobEvents)
+		r.Get("/{id}/webhooks", h.ListJobWebhooks)
+		r.Get("/{id}/logs", h.ListJobLogs)
+		r.Get("/{id}/history", h.JobHistory)
+	})
+
+	r.Get("/events", h.Events)
+
+	r.Route("/clients/{client_id}/webhook", func(r chi.Router) {
+		r.Use(h.requireClient)
+		r.Get("/", h.GetClientWebhook)
+		r.Put("/", h.PutClientWebhook)
+		r.Delete("/", h.DeleteClientWebhook)
+	})
+
+	r.Route("/dead-letters", func(r chi.Router) {
+		r.Get("/", h.ListDeadLetters)
+		r.Delete("/", h.PurgeDeadLetters)
+		r.Get("/{id}", h.GetDeadLetter)
+		r.Delete("/{id}", h.PurgeDeadLetter)
+		r.Post("/{id}/requeue", h.RequeueDeadLetter)
 	})
 
 	r.Get("/swagger/*", httpSwagger.Handler(
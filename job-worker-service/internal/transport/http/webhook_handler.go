@@ -0,0 +1,208 @@
+package httptransport
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/service"
+)
+
+type webhookAttemptResp struct {
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code,omitempty"` // нет — ответа не было (сеть, timeout)
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	At         string `json:"at"`
+}
+
+type webhookDeliveryResp struct {
+	ID            int64                `json:"id"`
+	URL           string               `json:"url"`
+	Event         string               `json:"event"`  // job.done | job.error | job.dead
+	Status        entity.WebhookStatus `json:"status"` // pending | delivered | failed
+	Attempts      int                  `json:"attempts"`
+	NextAttemptAt string               `json:"next_attempt_at,omitempty"` // только pending
+	LastError     string               `json:"last_error,omitempty"`
+	CreatedAt     string               `json:"created_at"`
+	DeliveredAt   string               `json:"delivered_at,omitempty"`
+	AttemptLog    []webhookAttemptResp `json:"attempt_log"`
+}
+
+type webhookDeliveryListResp struct {
+	Items []webhookDeliveryResp `json:"items"`
+}
+
+func toWebhookDeliveryResp(d entity.WebhookDelivery) webhookDeliveryResp {
+	resp := webhookDeliveryResp{
+		ID:         d.ID,
+		URL:        d.URL,
+		Event:      "job." + string(d.JobStatus),
+		Status:     d.Status,
+		Attempts:   d.Attempts,
+		LastError:  d.LastError,
+		CreatedAt:  d.CreatedAt.Format(time.RFC3339),
+		AttemptLog: make([]webhookAttemptResp, 0, len(d.AttemptLog)),
+	}
+	if d.Status == entity.WebhookPending {
+		resp.NextAttemptAt = d.NextAttemptAt.Format(time.RFC3339)
+	}
+	if d.DeliveredAt != nil {
+		resp.DeliveredAt = d.DeliveredAt.Format(time.RFC3339)
+	}
+	for _, a := range d.AttemptLog {
+		resp.AttemptLog = append(resp.AttemptLog, webhookAttemptResp{
+			Attempt:    a.Attempt,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMs: a.Duration.Milliseconds(),
+			At:         a.At.Format(time.RFC3339),
+		})
+	}
+	return resp
+}
+
+type webhookSettingsDTO struct {
+	URL    string `json:"url,omitempty"`    // webhook для job клиента без callback_url
+	Secret string `json:"secret,omitempty"` // секрет подписи; пусто => общий секрет сервиса
+}
+
+type webhookSettingsResp struct {
+	ClientID  string `json:"client_id"`
+	URL       string `json:"url,omitempty"`
+	HasSecret bool   `json:"has_secret"` // сам секрет не возвращается
+	UpdatedAt string `json:"updated_at"`
+}
+
+func toWebhookSettingsResp(s *entity.WebhookSettings) webhookSettingsResp {
+	return webhookSettingsResp{
+		ClientID:  s.ClientID,
+		URL:       s.URL,
+		HasSecret: s.Secret != "",
+		UpdatedAt: s.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ListJobWebhooks godoc
+// @Summary List webhook deliveries of job
+// @Description Every transition of the job to done, error or dead creates a delivery to its callback_url
+// @Description (or the default webhook of its client). Failed attempts are retried with backoff.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "job id (uuid)"
+// @Success 200 {object} webhookDeliveryListResp
+// @Failure 400 {object} apiError
+// @Failure 404 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /jobs/{id}/webhooks [get]
+func (h *Handler) ListJobWebhooks(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	deliveries, err := h.webhookSvc.Deliveries(r.Context(), id)
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		h.writeError(w, http.StatusNotFound, "job not found")
+		return
+	case err != nil:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := webhookDeliveryListResp{Items: make([]webhookDeliveryResp, 0, len(deliveries))}
+	for _, d := range deliveries {
+		resp.Items = append(resp.Items, toWebhookDeliveryResp(d))
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// GetClientWebhook godoc
+// @Summary Get default webhook of client
+// @Tags webhooks
+// @Produce json
+// @Param client_id path string true "client id (X-Client-ID)"
+// @Param X-Client-ID header string true "must be equal to client_id"
+// @Success 200 {object} webhookSettingsResp
+// @Failure 404 {object} apiError
+// @Failure 403 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /clients/{client_id}/webhook [get]
+func (h *Handler) GetClientWebhook(w http.ResponseWriter, r *http.Request) {
+	s, err := h.webhookSvc.Settings(r.Context(), chi.URLParam(r, "client_id"))
+	if err != nil {
+		h.writeWebhookError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toWebhookSettingsResp(s))
+}
+
+// PutClientWebhook godoc
+// @Summary Set default webhook of client
+// @Description url receives webhooks of the client's jobs (created with X-Client-ID) that have no callback_url;
+// @Description secret signs all webhooks of the client (X-Webhook-Signature). Replaces previous settings.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param client_id path string true "client id (X-Client-ID)"
+// @Param X-Client-ID header string true "must be equal to client_id"
+// @Param request body webhookSettingsDTO true "webhook settings"
+// @Success 200 {object} webhookSettingsResp
+// @Failure 400 {object} apiError
+// @Failure 403 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /clients/{client_id}/webhook [put]
+func (h *Handler) PutClientWebhook(w http.ResponseWriter, r *http.Request) {
+	var dto webhookSettingsDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	s, err := h.webhookSvc.PutSettings(r.Context(), entity.WebhookSettings{
+		ClientID: chi.URLParam(r, "client_id"),
+		URL:      dto.URL,
+		Secret:   dto.Secret,
+	})
+	if err != nil {
+		h.writeWebhookError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, toWebhookSettingsResp(s))
+}
+
+// DeleteClientWebhook godoc
+// @Summary Remove default webhook of client
+// @Tags webhooks
+// @Param client_id path string true "client id (X-Client-ID)"
+// @Param X-Client-ID header string true "must be equal to client_id"
+// @Success 204
+// @Failure 404 {object} apiError
+// @Failure 403 {object} apiError
+// @Failure 500 {object} apiError
+// @Router /clients/{client_id}/webhook [delete]
+func (h *Handler) DeleteClientWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.webhookSvc.DeleteSettings(r.Context(), chi.URLParam(r, "client_id")); err != nil {
+		h.writeWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, entity.ErrWebhookSettingsNotFound):
+		h.writeError(w, http.StatusNotFound, "webhook settings not found")
+	case errors.Is(err, service.ErrInvalidWebhook):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
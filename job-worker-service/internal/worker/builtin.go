@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"job-worker-service/internal/entity"
+)
+
+// RegisterBuiltins регистрирует демонстрационные типы: generate_report, convert_video, echo.
+func RegisterBuiltins(r *Registry) {
+	r.RegisterFunc("generate_report", generateReport)
+	r.RegisterFunc("convert_video", convertVideo)
+	r.RegisterFunc("echo", echo)
+}
+
+func generateReport(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error) {
+	if err := sleep(ctx, 2*time.Second); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(`{"report_url":"https://example.local/report/123"}`), nil
+}
+
+func convertVideo(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error) {
+	r.Stage("transcoding")
+	for step := 1; step <= 3; step++ {
+		if err := sleep(ctx, time.Second); err != nil {
+			return nil, err
+		}
+		r.Progress(step*100/3, "")
+	}
+	return json.RawMessage(`{"file_url":"https://example.local/video/converted.mp4"}`), nil
+}
+
+func echo(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error) {
+	if err := sleep(ctx, time.Second); err != nil {
+		return nil, err
+	}
+	// просто вернуть input
+	if len(job.Input) == 0 {
+		return json.RawMessage(`{}`), nil
+	}
+	return job.Input, nil
+}
+
+// sleep имитирует работу, но прерывается при отмене ctx.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
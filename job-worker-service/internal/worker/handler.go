@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"job-worker-service/internal/entity"
+)
+
+// Handler выполняет job одного типа и возвращает её output.
+// ctx отменяется при остановке worker'а — долгие handler'ы должны его слушать.
+// Ошибка = неудачная попытка: job уйдёт на retry или в dead-letter по retry-политике.
+type Handler interface {
+	Handle(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error)
+}
+
+// HandlerFunc позволяет использовать обычную функцию как Handler.
+type HandlerFunc func(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error) {
+	return f(ctx, job, r)
+}
+
+// Reporter — помощники handler'а: прогресс и лог выполняемой job.
+type Reporter interface {
+	// Progress: percent 0..100 (значения вне диапазона обрезаются), message — необязательный комментарий.
+	// Виден в GET /jobs/{id} и SSE; сохраняется не чаще раза в секунду (последнее значение не теряется).
+	Progress(percent int, message string)
+	// Stage — текущий этап ("transcoding", "uploading"); сохраняется сразу, percent остаётся прежним.
+	Stage(stage string)
+	// Logf / Warnf / Errorf пишут строку в лог job (GET /jobs/{id}/logs) с номером попытки,
+	// а также в лог worker'а.
+	Logf(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// Registry сопоставляет job.Type и Handler. Безопасен для конкурентного использования.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]Handler{}}
+}
+
+// Register регистрирует handler для типа; как http.ServeMux, паникует на пустой тип,
+// nil handler и повторную регистрацию — это ошибки программиста, а не runtime.
+func (r *Registry) Register(typ string, h Handler) {
+	if typ == "" {
+		panic("worker: empty job type")
+	}
+	if h == nil {
+		panic("worker: nil handler for job type " + typ)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.handlers[typ]; ok {
+		panic(fmt.Sprintf("worker: handler for job type %q already registered", typ))
+	}
+	r.handlers[typ] = h
+}
+
+func (r *Registry) RegisterFunc(typ string, f func(ctx context.Context, job *entity.Job, r Reporter) (json.RawMessage, error)) {
+	r.Register(typ, HandlerFunc(f))
+}
+
+func (r *Registry) Lookup(typ string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	h, ok := r.handlers[typ]
+	return h, ok
+}
+
+// Types returns registered job types (sorted).
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]string, 0, len(r.handlers))
+	for typ := range r.handlers {
+		out = append(out, typ)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -2,60 +2,99 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"log"
+	"sync"
 	"time"
 
+	"job-worker-service/internal/entity"
 	"job-worker-service/internal/service"
 )
 
+// CancelSource — порт: какие из выполняемых job отменены (реализация: service.CancelSignals).
+type CancelSource interface {
+	CancelRequested(ctx context.Context, jobIDs []string) ([]string, error)
+}
+
 type Pool struct {
 	queue      service.Queue
 	processor  *Processor
+	cancels    CancelSource
 	workers    int
 	claimDelay time.Duration
+	heartbeat  time.Duration
+	cancelPoll time.Duration
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc // job_id -> отмена ctx handler'а
 }
 
-func NewPool(queue service.Queue, processor *Processor, workers int) *Pool {
+// NewPool: heartbeat — как часто продлевать lease job'ы, пока она обрабатывается
+// (должно быть заметно меньше lease TTL очереди).
+// cancels может быть nil — тогда отмена выполняемых job не отслеживается.
+func NewPool(queue service.Queue, processor *Processor, workers int, heartbeat time.Duration, cancels CancelSource) *Pool {
 	if workers <= 0 {
 		workers = 4
 	}
+	if heartbeat <= 0 {
+		heartbeat = entity.DefaultLeaseTTL / 3
+	}
 	return &Pool{
 		queue:      queue,
 		processor:  processor,
+		cancels:    cancels,
 		workers:    workers,
 		claimDelay: 5 * time.Second,
+		heartbeat:  heartbeat,
+		cancelPoll: time.Second,
+		running:    map[string]context.CancelCauseFunc{},
 	}
 }
 
+// Run забирает job из очереди до ctx.Done(), затем ждёт выполняющиеся job: их ctx отменён,
+// и Processor возвращает прерванные попытки в очередь (ack — тоже Processor, после записи результата).
 func (p *Pool) Run(ctx context.Context) {
 	log.Printf("worker pool started: workers=%d", p.workers)
 
 	jobCh := make(chan string)
+	var wg sync.WaitGroup
+
+	if p.cancels != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.watchCancels(ctx)
+		}()
+	}
 
 	// N воркеров
 	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
 		go func(n int) {
+			defer wg.Done()
 			for jobID := range jobCh {
-				err := p.processor.Process(ctx, jobID)
+				jobCtx := p.track(ctx, jobID)
+				stopHeartbeat := p.startHeartbeat(ctx, n, jobID)
+				err := p.processor.Process(jobCtx, jobID)
+				stopHeartbeat()
+				p.untrack(jobID)
 				if err != nil {
 					log.Printf("[worker-%d] process job %s error: %v", n, jobID, err)
 				}
-
-				// В любом случае ACK: job уже переведён в done/error в БД (или упал раньше).
-				// Если Process() упал до обновления статуса — тогда reaper вернёт id обратно.
-				if ackErr := p.queue.Ack(ctx, jobID); ackErr != nil {
-					log.Printf("[worker-%d] ack job %s error: %v", n, jobID, ackErr)
-				}
 			}
 		}(i + 1)
 	}
 
+	defer func() {
+		close(jobCh)
+		wg.Wait()
+		log.Println("worker pool stopped")
+	}()
+
 	// Listener: atomically claim from queue -> processing
 	for {
 		select {
 		case <-ctx.Done():
-			close(jobCh)
-			log.Println("worker pool stopped")
 			return
 		default:
 			jobID, err := p.queue.ClaimBlocking(ctx, p.claimDelay)
@@ -66,9 +105,115 @@ func (p *Pool) Run(ctx context.Context) {
 			select {
 			case jobCh <- jobID:
 			case <-ctx.Done():
-				close(jobCh)
+				// job взята, но не начата: вернёт reaper по истечении lease
+				return
+			}
+		}
+	}
+}
+
+// startHeartbeat продлевает lease job'ы в фоне, пока не вызвана возвращённая функция.
+func (p *Pool) startHeartbeat(ctx context.Context, n int, jobID string) (stop func()) {
+	hbCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(p.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-hbCtx.Done():
 				return
+			case <-ticker.C:
+				err := p.queue.Heartbeat(hbCtx, jobID)
+				if errors.Is(err, entity.ErrLeaseLost) {
+					// job уже вернул в очередь reaper — эту попытку останавливаем, результат не пишется
+					log.Printf("[worker-%d] job %s lease lost (reaped), canceling attempt", n, jobID)
+					p.abort(jobID, entity.ErrLeaseLost)
+					return
+				}
+				if err != nil && hbCtx.Err() == nil {
+					log.Printf("[worker-%d] heartbeat job %s error: %v", n, jobID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// track returns ctx for job's handler; watchCancels cancels it with ErrCanceled, heartbeat — with entity.ErrLeaseLost.
+func (p *Pool) track(ctx context.Context, jobID string) context.Context {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+
+	p.mu.Lock()
+	p.running[jobID] = cancel
+	p.mu.Unlock()
+	return jobCtx
+}
+
+func (p *Pool) untrack(jobID string) {
+	p.mu.Lock()
+	cancel, ok := p.running[jobID]
+	delete(p.running, jobID)
+	p.mu.Unlock()
+
+	if ok {
+		cancel(nil)
+	}
+}
+
+// abort отменяет ctx handler'а выполняющейся job с причиной cause.
+func (p *Pool) abort(jobID string, cause error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.running[jobID]; ok {
+		cancel(cause)
+	}
+}
+
+// watchCancels раз в cancelPoll спрашивает, не отменены ли выполняемые job, и отменяет их ctx.
+func (p *Pool) watchCancels(ctx context.Context) {
+	ticker := time.NewTicker(p.cancelPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			ids := make([]string, 0, len(p.running))
+			for id := range p.running {
+				ids = append(ids, id)
+			}
+			p.mu.Unlock()
+			if len(ids) == 0 {
+				continue
+			}
+
+			canceled, err := p.cancels.CancelRequested(ctx, ids)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[worker] cancel poll error: %v", err)
+				}
+				continue
+			}
+
+			p.mu.Lock()
+			for _, id := range canceled {
+				if cancel, ok := p.running[id]; ok {
+					log.Printf("[worker] job_id=%s cancel requested", id)
+					cancel(ErrCanceled)
+				}
 			}
+			p.mu.Unlock()
 		}
 	}
 }
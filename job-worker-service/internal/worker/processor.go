@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,33 +16,135 @@ import (
 	"job-worker-service/internal/entity"
 )
 
+// JobRepo — порт репозитория для Processor (реализации: postgresql.JobRepository, memory.JobRepository).
+// StartAttempt выдаёт fencing token попытки; записи с ним отклоняются с entity.ErrStaleAttempt,
+// если job уже взята новой попыткой, и с entity.ErrInvalidTransition, если её статус не позволяет переход
+// (завершена, отменена, возвращена reaper'ом в pending).
 type JobRepo interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.Job, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status entity.JobStatus) error
-	SetResultDone(ctx context.Context, id uuid.UUID, output json.RawMessage) error
-	SetResultError(ctx context.Context, id uuid.UUID, errText string) error
+	StartAttempt(ctx context.Context, id uuid.UUID, lease time.Duration) (token int64, err error)
+	// ExtendLease продлевает lease попытки в БД, пока она выполняется (StartAttempt ставит его на lease).
+	ExtendLease(ctx context.Context, id uuid.UUID, token int64, lease time.Duration) error
+	SetRetry(ctx context.Context, id uuid.UUID, token int64, class entity.ErrorClass, errText string, runAt time.Time) error
+	SetDead(ctx context.Context, id uuid.UUID, token int64, class entity.ErrorClass, errText string) error
+	SetResultDone(ctx context.Context, id uuid.UUID, token int64, output json.RawMessage) error
+	SetResultError(ctx context.Context, id uuid.UUID, token int64, errText string) error
+	// ReleaseAttempt возвращает прерванную попытку в pending, не засчитывая её (attempts--).
+	ReleaseAttempt(ctx context.Context, id uuid.UUID, token int64) error
+	// SetProgress сохраняет прогресс handler'а (GET /jobs/{id}) и публикует его подписчикам (SSE).
+	SetProgress(ctx context.Context, id uuid.UUID, token int64, p entity.JobProgress) error
+	// AppendLogs сохраняет строки лога job (GET /jobs/{id}/logs) попытки token;
+	// entity.ErrStaleAttempt — job уже взята новой попыткой.
+	AppendLogs(ctx context.Context, jobID uuid.UUID, token int64, logs []entity.JobLog) error
 }
 
+// Requeuer — порт очереди для Processor (реализация: service.Queue):
+// ack взятой job, отложенный повтор и перенос в dead-letter.
+type Requeuer interface {
+	Ack(ctx context.Context, jobID string) error
+	EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error
+	DeadLetter(ctx context.Context, jobID string, reason string) error
+}
+
+// Scheduler ставит job в очередь повторно — retry по backoff и возврат прерванной попытки
+// (реализации: postgresql.Outbox, postgresql.Queue или сама очередь).
+type Scheduler interface {
+	EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error
+}
+
+// Transactor выполняет fn в одной транзакции БД (реализация: postgresql.TxManager).
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// FollowUps создаёт follow-up job (on_success / on_failure) завершённой job (реализация: service.JobService).
+type FollowUps interface {
+	CreateFollowUp(ctx context.Context, parentID uuid.UUID) (uuid.UUID, error)
+}
+
+// workerActor — кто меняет job в истории (GET /jobs/{id}/history): две попытки одной job разными
+// worker'ами — след RequeueStale.
+var workerActor = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("worker:%s-%d", host, os.Getpid())
+}()
+
+// ErrTimeout — попытка не уложилась в job.Timeout (error class "timeout").
+var ErrTimeout = errors.New("job timed out")
+
+// ErrCanceled — job отменена через API, пока выполнялась (cause ctx handler'а, см. Pool).
+var ErrCanceled = errors.New("job canceled")
+
+// resultWriteTimeout — сколько ждать записи результата попытки и ack, если ctx уже отменён
+// (остановка worker'а, отмена job).
+const resultWriteTimeout = 10 * time.Second
+
 type Processor struct {
-	repo JobRepo
+	repo      JobRepo
+	queue     Requeuer
+	handlers  *Registry
+	followUps FollowUps
+
+	schedule Scheduler
+	tx       Transactor
+	lease    time.Duration // lease попытки в БД: TTL очереди (LeaseTTL), иначе entity.DefaultLeaseTTL
 }
 
-func NewProcessor(repo JobRepo) *Processor {
-	return &Processor{repo: repo}
+// NewProcessor: handlers — обработчики по job.Type; nil = только встроенные (RegisterBuiltins).
+// followUps может быть nil — тогда on_success / on_failure job не создаются.
+// schedule и tx — как у service.JobService: с tx смена статуса (retry, release) и повторная постановка
+// в schedule (outbox / Postgres-очередь) идут одной транзакцией. schedule nil — queue, tx nil — по отдельности.
+func NewProcessor(repo JobRepo, queue Requeuer, handlers *Registry, followUps FollowUps, schedule Scheduler, tx Transactor) *Processor {
+	if handlers == nil {
+		handlers = NewRegistry()
+		RegisterBuiltins(handlers)
+	}
+	if schedule == nil {
+		schedule = queue
+	}
+	lease := entity.DefaultLeaseTTL
+	if q, ok := queue.(interface{ LeaseTTL() time.Duration }); ok && q.LeaseTTL() > 0 {
+		lease = q.LeaseTTL()
+	}
+	return &Processor{repo: repo, queue: queue, handlers: handlers, followUps: followUps, schedule: schedule, tx: tx, lease: lease}
 }
 
+// Process выполняет одну доставку job и снимает её с processing очереди (ack), когда результат записан.
+// Без ack (ошибка БД до записи результата) job вернёт в очередь reaper по истечении lease.
 func (p *Processor) Process(ctx context.Context, jobID string) error {
 	start := time.Now()
+	if entity.ActorFrom(ctx) == "" {
+		ctx = entity.WithActor(ctx, workerActor)
+	}
 
 	id, err := uuid.Parse(jobID)
 	if err != nil {
 		log.Printf("[worker] job_id=%s parse_error=%v", jobID, err)
+		p.deadLetter(ctx, jobID, "invalid job id: "+err.Error())
+		p.ack(ctx, jobID)
 		return err
 	}
 
-	// статус -> processing
-	if err := p.repo.UpdateStatus(ctx, id, entity.StatusProcessing); err != nil {
+	// статус -> processing, attempts++; token — fencing token этой попытки
+	token, err := p.repo.StartAttempt(ctx, id, p.lease)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidTransition) {
+			status := p.status(ctx, id)
+			log.Printf("[worker] job_id=%s status=%s skipped", id.String(), status)
+			// дубль доставки job, которую выполняет другая попытка (lease жив): ack снял бы её доставку,
+			// а наша вернётся reaper'ом, если та попытка умрёт
+			if status != entity.StatusProcessing {
+				// отменили, пока job ждала в очереди, или повторная доставка уже завершённой job
+				p.ack(ctx, jobID)
+			}
+			return nil
+		}
 		log.Printf("[worker] job_id=%s update_status=processing error=%v", id.String(), err)
+		if errors.Is(err, entity.ErrNotFound) {
+			// id в очереди есть, а job в БД нет — повторять бессмысленно
+			p.deadLetter(ctx, jobID, "job not found")
+			p.ack(ctx, jobID)
+		}
 		return err
 	}
 
@@ -48,23 +154,84 @@ func (p *Processor) Process(ctx context.Context, jobID string) error {
 		return err
 	}
 
-	log.Printf("[worker] job_id=%s type=%s status=processing", id.String(), job.Type)
+	log.Printf("[worker] job_id=%s type=%s status=processing attempt=%d/%d timeout=%s",
+		id.String(), job.Type, job.Attempts, job.Retry.MaxAttempts, job.Timeout,
+	)
+
+	ctx, lost := context.WithCancelCause(ctx)
+	defer lost(nil)
+	stopLease := p.keepLease(ctx, lost, job, token)
+	out, procErr := p.run(ctx, job, token)
+	stopLease()
+
+	// результат пишем и после отмены ctx, но не дольше resultWriteTimeout
+	runCtx := ctx
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resultWriteTimeout)
+	defer cancel()
 
-	out, procErr := doWork(job.Type, job.Input)
+	if errors.Is(context.Cause(runCtx), ErrCanceled) {
+		// статус canceled уже записан API; результат отменённой попытки не нужен
+		log.Printf("[worker] job_id=%s type=%s status=canceled duration_ms=%d",
+			id.String(), job.Type, time.Since(start).Milliseconds(),
+		)
+		p.ack(ctx, jobID)
+		return ErrCanceled
+	}
+	// lease истёк (Pool отменил ctx с entity.ErrLeaseLost): доставку уже вернул в очередь reaper,
+	// ack'ать нечего — можно только снять чужую
+	leaseLost := errors.Is(context.Cause(runCtx), entity.ErrLeaseLost)
+	if procErr != nil && leaseLost {
+		log.Printf("[worker] job_id=%s type=%s attempt=%d lease lost, result dropped error=%v",
+			id.String(), job.Type, job.Attempts, procErr,
+		)
+		return entity.ErrLeaseLost
+	}
+	if procErr != nil && runCtx.Err() != nil {
+		// попытку прервали не timeout и не отмена job (остановка worker'а) — она не засчитывается
+		return p.release(ctx, job, token, context.Cause(runCtx))
+	}
 	if procErr != nil {
 		msg := procErr.Error()
-		_ = p.repo.SetResultError(ctx, id, msg)
+		class := entity.ErrorClassError
+		if errors.Is(procErr, ErrTimeout) {
+			class = entity.ErrorClassTimeout
+		}
 
-		log.Printf("[worker] job_id=%s type=%s status=error duration_ms=%d error=%s",
-			id.String(), job.Type, time.Since(start).Milliseconds(), msg,
+		if job.Attempts < job.Retry.AttemptsFor(class) {
+			return p.retry(ctx, job, token, class, msg, time.Since(start), procErr)
+		}
+
+		deadErr := p.finish(ctx, job, job.OnFailure, func(ctx context.Context) error {
+			return p.repo.SetDead(ctx, id, token, class, msg)
+		})
+		if isStale(deadErr) {
+			return p.dropped(job, "dead", deadErr)
+		} else if deadErr != nil {
+			log.Printf("[worker] job_id=%s type=%s set_dead error=%v", id.String(), job.Type, deadErr)
+		}
+		p.deadLetter(ctx, jobID, msg)
+		p.ack(ctx, jobID)
+
+		log.Printf("[worker] job_id=%s type=%s status=dead attempts=%d duration_ms=%d error_class=%s error=%s",
+			id.String(), job.Type, job.Attempts, time.Since(start).Milliseconds(), class, msg,
 		)
 		return procErr
 	}
 
-	if err := p.repo.SetResultDone(ctx, id, out); err != nil {
+	err = p.finish(ctx, job, job.OnSuccess, func(ctx context.Context) error {
+		return p.repo.SetResultDone(ctx, id, token, out)
+	})
+	if err != nil {
+		if isStale(err) {
+			return p.dropped(job, "done", err)
+		}
 		log.Printf("[worker] job_id=%s type=%s set_done error=%v", id.String(), job.Type, err)
 		return err
 	}
+	// handler успел до отмены: результат записан (token ещё наш), но доставка уже не наша
+	if !leaseLost {
+		p.ack(ctx, jobID)
+	}
 
 	log.Printf("[worker] job_id=%s type=%s status=done duration_ms=%d",
 		id.String(), job.Type, time.Since(start).Milliseconds(),
@@ -72,22 +239,394 @@ func (p *Processor) Process(ctx context.Context, jobID string) error {
 	return nil
 }
 
-func doWork(typ string, input json.RawMessage) (json.RawMessage, error) {
-	switch typ {
-	case "generate_report":
-		time.Sleep(2 * time.Second)
-		return json.RawMessage(`{"report_url":"https://example.local/report/123"}`), nil
-	case "convert_video":
-		time.Sleep(3 * time.Second)
-		return json.RawMessage(`{"file_url":"https://example.local/video/converted.mp4"}`), nil
-	case "echo":
-		time.Sleep(1 * time.Second)
-		// просто вернуть input
-		if len(input) == 0 {
-			return json.RawMessage(`{}`), nil
+// keepLease продлевает lease попытки в БД каждые lease/3, пока не вызвана возвращённая функция.
+// Если попытку взяли заново (ErrStaleAttempt), отменяет её ctx с entity.ErrLeaseLost — как heartbeat очереди в Pool.
+func (p *Processor) keepLease(ctx context.Context, lost context.CancelCauseFunc, job *entity.Job, token int64) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(p.lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := p.repo.ExtendLease(ctx, job.ID, token, p.lease)
+				if errors.Is(err, entity.ErrStaleAttempt) {
+					log.Printf("[worker] job_id=%s type=%s attempt=%d lease taken over, canceling attempt", job.ID.String(), job.Type, job.Attempts)
+					lost(entity.ErrLeaseLost)
+					return
+				}
+				if errors.Is(err, entity.ErrInvalidTransition) {
+					// job завершили или отменили без нас — продлевать нечего
+					return
+				}
+				if err != nil && ctx.Err() == nil {
+					log.Printf("[worker] job_id=%s extend_lease error=%v", job.ID.String(), err)
+				}
+			}
 		}
-		return input, nil
-	default:
-		return nil, errors.New("unknown job type: " + typ)
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// retry возвращает job в pending и откладывает следующую попытку по backoff-политике job.
+func (p *Processor) retry(ctx context.Context, job *entity.Job, token int64, class entity.ErrorClass, msg string, took time.Duration, procErr error) error {
+	delay := job.Retry.Backoff(job.Attempts)
+	runAt := time.Now().Add(delay)
+
+	setErr, err := p.reschedule(ctx, job, runAt, func(ctx context.Context) error {
+		return p.repo.SetRetry(ctx, job.ID, token, class, msg, runAt)
+	})
+	if setErr != nil {
+		if isStale(setErr) {
+			return p.dropped(job, "retry", setErr)
+		}
+		log.Printf("[worker] job_id=%s type=%s set_retry error=%v", job.ID.String(), job.Type, setErr)
+		return setErr
+	}
+	if err != nil {
+		// повтор не запланирован — иначе job навсегда зависнет в pending, поэтому фиксируем ошибку
+		log.Printf("[worker] job_id=%s type=%s schedule_retry error=%v", job.ID.String(), job.Type, err)
+		_ = p.finish(ctx, job, job.OnFailure, func(ctx context.Context) error {
+			return p.repo.SetResultError(ctx, job.ID, token, msg)
+		})
+		return err
+	}
+
+	log.Printf("[worker] job_id=%s type=%s status=retry attempt=%d/%d duration_ms=%d retry_in_ms=%d error_class=%s error=%s",
+		job.ID.String(), job.Type, job.Attempts, job.Retry.AttemptsFor(class), took.Milliseconds(), delay.Milliseconds(), class, msg,
+	)
+	return procErr
+}
+
+// release возвращает прерванную попытку в pending (attempts не растёт) и снова ставит job в очередь.
+func (p *Processor) release(ctx context.Context, job *entity.Job, token int64, cause error) error {
+	releaseErr, err := p.reschedule(ctx, job, time.Now(), func(ctx context.Context) error {
+		return p.repo.ReleaseAttempt(ctx, job.ID, token)
+	})
+	if releaseErr != nil {
+		if isStale(releaseErr) {
+			return p.dropped(job, "release", releaseErr)
+		}
+		log.Printf("[worker] job_id=%s type=%s release error=%v", job.ID.String(), job.Type, releaseErr)
+		return releaseErr
+	}
+	if err != nil {
+		// job в pending, но не в очереди — её вернёт reconciler
+		log.Printf("[worker] job_id=%s type=%s requeue error=%v", job.ID.String(), job.Type, err)
+		return err
+	}
+
+	log.Printf("[worker] job_id=%s type=%s status=pending attempt=%d released cause=%v",
+		job.ID.String(), job.Type, job.Attempts, cause,
+	)
+	return cause
+}
+
+// reschedule возвращает job в pending (write) и ставит её в очередь на at.
+// С tx — одной транзакцией: ошибка постановки откатывает и write (вернётся как writeErr, job останется processing
+// до reaper'а), а ack — после commit'а: новую доставку outbox опубликует relay позже.
+// Без tx ack — до EnqueueAt (см. ack); enqueueErr — job уже в pending, но в очередь не попала.
+func (p *Processor) reschedule(ctx context.Context, job *entity.Job, at time.Time, write func(ctx context.Context) error) (writeErr, enqueueErr error) {
+	jobID := job.ID.String()
+	if p.tx != nil {
+		err := p.tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := write(ctx); err != nil {
+				return err
+			}
+			return p.schedule.EnqueueAt(ctx, jobID, job.Priority, at)
+		})
+		if err != nil {
+			return err, nil
+		}
+		p.ack(ctx, jobID)
+		return nil, nil
+	}
+
+	if err := write(ctx); err != nil {
+		return err, nil
+	}
+	p.ack(ctx, jobID)
+	return nil, p.schedule.EnqueueAt(ctx, jobID, job.Priority, at)
+}
+
+// finish записывает финальный статус job (write) и создаёт follow-up job по шаблону tmpl (nil — нет).
+// С tx — одной транзакцией (child и её enqueue через outbox — там же): падение worker'а между записью результата
+// и созданием child не оставит завершённую job без неё. Если follow-up создать не удалось, результат пишется
+// без него, а ошибка только логируется: повторная доставка завершённой job follow-up уже не создаст.
+func (p *Processor) finish(ctx context.Context, job *entity.Job, tmpl *entity.JobTemplate, write func(ctx context.Context) error) error {
+	if tmpl == nil || p.followUps == nil {
+		return write(ctx)
+	}
+	if p.tx == nil {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		childID, err := p.followUps.CreateFollowUp(ctx, job.ID)
+		p.logFollowUp(job, tmpl, childID, err)
+		return nil
+	}
+
+	var (
+		childID   uuid.UUID
+		followErr error
+	)
+	err := p.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		childID, followErr = p.followUps.CreateFollowUp(ctx, job.ID)
+		return followErr
+	})
+	if followErr == nil {
+		if err == nil {
+			p.logFollowUp(job, tmpl, childID, nil)
+		}
+		return err
+	}
+	// транзакция откатилась из-за follow-up — результат job важнее
+	p.logFollowUp(job, tmpl, uuid.Nil, followErr)
+	return write(ctx)
+}
+
+func (p *Processor) logFollowUp(job *entity.Job, tmpl *entity.JobTemplate, childID uuid.UUID, err error) {
+	if err != nil {
+		log.Printf("[worker] job_id=%s type=%s follow_up=%s error=%v", job.ID.String(), job.Type, tmpl.Type, err)
+		return
+	}
+	log.Printf("[worker] job_id=%s type=%s follow_up=%s child_id=%s", job.ID.String(), job.Type, tmpl.Type, childID.String())
+}
+
+// status — текущий статус job для лога ("unknown", если прочитать не удалось).
+func (p *Processor) status(ctx context.Context, id uuid.UUID) entity.JobStatus {
+	job, err := p.repo.GetByID(ctx, id)
+	if err != nil {
+		return "unknown"
+	}
+	return job.Status
+}
+
+// isStale: запись результата отклонена — попытку уже взяли заново или job завершили / отменили без нас.
+func isStale(err error) bool {
+	return errors.Is(err, entity.ErrStaleAttempt) || errors.Is(err, entity.ErrInvalidTransition)
+}
+
+// dropped логирует отброшенный результат устаревшей попытки; job не трогаем — ей владеет новая попытка
+// (или она уже в финальном статусе). Ack тоже нет: доставка в очереди может быть уже новой попытки;
+// если нет — её вернёт reaper, и следующая попытка пропустит завершённую job.
+func (p *Processor) dropped(job *entity.Job, result string, err error) error {
+	log.Printf("[worker] job_id=%s type=%s attempt=%d result=%s dropped error=%v",
+		job.ID.String(), job.Type, job.Attempts, result, err,
+	)
+	return err
+}
+
+// ack снимает job с processing очереди. Перед повторной постановкой в саму очередь — обязательно до неё:
+// иначе ack снял бы уже новую доставку той же job (через outbox её публикует relay уже после commit'а).
+func (p *Processor) ack(ctx context.Context, jobID string) {
+	if err := p.queue.Ack(ctx, jobID); err != nil {
+		log.Printf("[worker] job_id=%s ack error=%v", jobID, err)
+	}
+}
+
+func (p *Processor) deadLetter(ctx context.Context, jobID, reason string) {
+	if err := p.queue.DeadLetter(ctx, jobID, reason); err != nil {
+		log.Printf("[worker] job_id=%s dead_letter error=%v", jobID, err)
+	}
+}
+
+// run dispatches job to its handler by type, limiting the attempt to job.Timeout.
+// Ошибка попытки попадает и в лог job.
+func (p *Processor) run(ctx context.Context, job *entity.Job, token int64) (json.RawMessage, error) {
+	rep := &logReporter{ctx: ctx, repo: p.repo, job: job, token: token}
+	out, err := p.handle(ctx, job, rep)
+	if err != nil && !errors.Is(context.Cause(ctx), ErrCanceled) {
+		rep.write(entity.LogError, "attempt failed: "+err.Error())
+	}
+	// прогресс и лог, отложенные throttling'ом, сохраняются до результата попытки;
+	// handler, брошенный по timeout, дальше в job не пишет
+	rep.close()
+	return out, err
+}
+
+func (p *Processor) handle(ctx context.Context, job *entity.Job, rep *logReporter) (json.RawMessage, error) {
+	h, ok := p.handlers.Lookup(job.Type)
+	if !ok {
+		return nil, errors.New("unknown job type: " + job.Type)
+	}
+	if job.Timeout <= 0 {
+		return h.Handle(ctx, job, rep)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	type result struct {
+		out json.RawMessage
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := h.Handle(runCtx, job, rep)
+		done <- result{out: out, err: err}
+	}()
+
+	// handler может не слушать ctx — тогда не ждём его: worker освобождается по timeout,
+	// а результат "зависшего" handler'а отбрасывается
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w after %s: %v", ErrTimeout, job.Timeout, res.err)
+		}
+		return res.out, res.err
+	case <-runCtx.Done():
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w after %s", ErrTimeout, job.Timeout)
+		}
+		return nil, runCtx.Err()
+	}
+}
+
+// progressInterval — не чаще этого logReporter сохраняет прогресс (смена stage — сразу) и лог job.
+const progressInterval = time.Second
+
+// Лог job копится в logReporter и пишется пачкой: раз в progressInterval или по logBatchSize строк.
+const (
+	logBatchSize  = 100
+	maxLogMessage = 4 << 10 // байт; длиннее — обрезается
+)
+
+// logReporter пишет прогресс и сообщения handler'а в лог worker'а; прогресс и сообщения ещё и сохраняются
+// в job (throttled: handler может звать Progress хоть на каждый кадр).
+type logReporter struct {
+	ctx   context.Context
+	repo  JobRepo
+	job   *entity.Job
+	token int64 // fencing token попытки
+
+	mu      sync.Mutex
+	current entity.JobProgress
+	savedAt time.Time // когда прогресс сохранялся последний раз
+	dirty   bool      // есть несохранённый прогресс
+
+	logs        []entity.JobLog // ещё не сохранённые строки
+	logsSavedAt time.Time
+
+	closed bool // попытка завершена: запись от брошенного handler'а отбрасывается
+}
+
+func (r *logReporter) Progress(percent int, message string) {
+	percent = min(max(percent, 0), 100)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.current.Percent = percent
+	r.current.Message = message
+	r.dirty = true
+	// в лог worker'а — с тем же throttling'ом, что и в job
+	if time.Since(r.savedAt) >= progressInterval {
+		log.Printf("[worker] job_id=%s type=%s progress=%d message=%q", r.job.ID.String(), r.job.Type, percent, message)
+		r.save()
+	}
+}
+
+func (r *logReporter) Stage(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	log.Printf("[worker] job_id=%s type=%s stage=%q", r.job.ID.String(), r.job.Type, stage)
+	if stage == r.current.Stage {
+		return
+	}
+	r.current.Stage = stage
+	r.current.Message = ""
+	r.save()
+}
+
+// close сохраняет последний прогресс, если его отбросил throttling, и накопленный лог;
+// дальнейшие вызовы reporter'а ничего не пишут.
+func (r *logReporter) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	if r.dirty {
+		r.save()
+	}
+	r.saveLogs()
+	r.closed = true
+}
+
+// save; r.mu must be held.
+func (r *logReporter) save() {
+	r.current.UpdatedAt = time.Now().UTC()
+	r.savedAt = time.Now()
+	r.dirty = false
+	if err := r.repo.SetProgress(r.ctx, r.job.ID, r.token, r.current); err != nil {
+		log.Printf("[worker] job_id=%s save progress error=%v", r.job.ID.String(), err)
+	}
+}
+
+func (r *logReporter) Logf(format string, args ...any) {
+	r.write(entity.LogInfo, fmt.Sprintf(format, args...))
+}
+
+func (r *logReporter) Warnf(format string, args ...any) {
+	r.write(entity.LogWarn, fmt.Sprintf(format, args...))
+}
+
+func (r *logReporter) Errorf(format string, args ...any) {
+	r.write(entity.LogError, fmt.Sprintf(format, args...))
+}
+
+func (r *logReporter) write(level entity.LogLevel, msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	log.Printf("[worker] job_id=%s type=%s level=%s log=%q", r.job.ID.String(), r.job.Type, level, msg)
+	if len(msg) > maxLogMessage {
+		msg = strings.ToValidUTF8(msg[:maxLogMessage], "") + "…"
+	}
+	r.logs = append(r.logs, entity.JobLog{Attempt: r.job.Attempts, Level: level, Message: msg, At: time.Now().UTC()})
+	if len(r.logs) >= logBatchSize || time.Since(r.logsSavedAt) >= progressInterval {
+		r.saveLogs()
+	}
+}
+
+// saveLogs; r.mu must be held.
+func (r *logReporter) saveLogs() {
+	if len(r.logs) == 0 {
+		return
+	}
+	r.logsSavedAt = time.Now()
+	// лог пишем и при отменённом ctx: строки перед отменой / shutdown'ом самые интересные;
+	// fencing token не даёт дописать лог попытки, которую уже сменила новая
+	if err := r.repo.AppendLogs(context.WithoutCancel(r.ctx), r.job.ID, r.token, r.logs); err != nil {
+		log.Printf("[worker] job_id=%s save logs=%d error=%v", r.job.ID.String(), len(r.logs), err)
 	}
+	r.logs = nil
 }
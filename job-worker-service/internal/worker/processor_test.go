@@ -0,0 +1,1001 @@
+package worker_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"job-worker-service/internal/entity"
+	"job-worker-service/internal/repository/memory"
+	"job-worker-service/internal/service"
+	"job-worker-service/internal/worker"
+)
+
+// ---- fakes ----
+
+type scheduled struct {
+	jobID    string
+	priority int
+	at       time.Time
+}
+
+type queueStub struct {
+	calls []scheduled
+	dead  map[string]string // job_id -> reason
+	acked []string
+	lease time.Duration // 0 — entity.DefaultLeaseTTL
+}
+
+func (s *queueStub) LeaseTTL() time.Duration {
+	return s.lease
+}
+
+func (s *queueStub) Ack(ctx context.Context, jobID string) error {
+	s.acked = append(s.acked, jobID)
+	return nil
+}
+
+func (s *queueStub) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	s.calls = append(s.calls, scheduled{jobID: jobID, priority: priority, at: at})
+	return nil
+}
+
+func (s *queueStub) DeadLetter(ctx context.Context, jobID string, reason string) error {
+	if s.dead == nil {
+		s.dead = map[string]string{}
+	}
+	s.dead[jobID] = reason
+	return nil
+}
+
+func mustGetJob(t *testing.T, repo *memory.JobRepository, id uuid.UUID) *entity.Job {
+	t.Helper()
+	j, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get job %s: %v", id, err)
+	}
+	return j
+}
+
+// ---- tests ----
+
+func TestProcessor_FailedJobIsRetriedUntilAttemptsExhausted(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{
+		Type:     "no_such_type", // handler'а нет — попытка падает сразу
+		Priority: 2,
+		Retry:    entity.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Second, MaxDelay: time.Minute},
+	})
+	queue := &queueStub{}
+	p := worker.NewProcessor(repo, queue, nil, nil, nil, nil)
+
+	// попытка 1: ошибка => pending + отложенный повтор
+	before := time.Now()
+	if err := p.Process(ctx, id.String()); err == nil {
+		t.Fatalf("expected error from first attempt")
+	}
+	if got := mustGetJob(t, repo, id).Status; got != entity.StatusPending {
+		t.Fatalf("expected status=pending after first attempt, got %s", got)
+	}
+	if len(queue.calls) != 1 || queue.calls[0].jobID != id.String() || queue.calls[0].priority != 2 {
+		t.Fatalf("expected one retry scheduled with priority=2, got %#v", queue.calls)
+	}
+	delay := queue.calls[0].at.Sub(before)
+	if delay < 500*time.Millisecond || delay > 2*time.Second {
+		t.Fatalf("expected backoff around base delay 1s, got %s", delay)
+	}
+
+	// попытка 2 (последняя): ошибка => dead + dead-letter, повтор не планируется
+	if err := p.Process(ctx, id.String()); err == nil {
+		t.Fatalf("expected error from second attempt")
+	}
+	if got := mustGetJob(t, repo, id).Status; got != entity.StatusDead {
+		t.Fatalf("expected status=dead after attempts exhausted, got %s", got)
+	}
+	if _, ok := queue.dead[id.String()]; !ok {
+		t.Fatalf("expected job in dead-letter queue, got %#v", queue.dead)
+	}
+	if mustGetJob(t, repo, id).Attempts != 2 {
+		t.Fatalf("expected attempts=2, got %d", mustGetJob(t, repo, id).Attempts)
+	}
+	if len(queue.calls) != 1 {
+		t.Fatalf("expected no more retries, got %#v", queue.calls)
+	}
+}
+
+type txKey struct{}
+
+// txStub — транзакция без БД: помечает ctx и запоминает, закоммичена ли она.
+type txStub struct {
+	committed bool
+}
+
+func (s *txStub) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		return err
+	}
+	s.committed = true
+	return nil
+}
+
+// outboxStub — EnqueueAt через outbox: запоминает, что вызван в транзакции.
+type outboxStub struct {
+	inTx  []bool
+	queue *queueStub
+	tx    *txStub
+	err   error
+
+	ackedBeforeCommit bool
+}
+
+func (s *outboxStub) EnqueueAt(ctx context.Context, jobID string, priority int, at time.Time) error {
+	s.inTx = append(s.inTx, ctx.Value(txKey{}) != nil)
+	s.ackedBeforeCommit = len(s.queue.acked) > 0 && !s.tx.committed
+	return s.err
+}
+
+func TestProcessor_RetryGoesThroughOutboxInTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{
+		Type:  "no_such_type",
+		Retry: entity.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Second, MaxDelay: time.Second},
+	})
+	queue := &queueStub{}
+	tx := &txStub{}
+	outbox := &outboxStub{queue: queue, tx: tx}
+	p := worker.NewProcessor(repo, queue, nil, nil, outbox, tx)
+
+	if err := p.Process(ctx, id.String()); err == nil {
+		t.Fatalf("expected error from first attempt")
+	}
+	if len(outbox.inTx) != 1 || !outbox.inTx[0] {
+		t.Fatalf("expected retry enqueued through outbox inside transaction, got %v", outbox.inTx)
+	}
+	if len(queue.calls) != 0 {
+		t.Fatalf("expected no direct enqueue into queue, got %#v", queue.calls)
+	}
+	if !tx.committed || outbox.ackedBeforeCommit || len(queue.acked) != 1 {
+		t.Fatalf("expected ack once after commit, committed=%v acked=%v before_commit=%v", tx.committed, queue.acked, outbox.ackedBeforeCommit)
+	}
+	if got := mustGetJob(t, repo, id).Status; got != entity.StatusPending {
+		t.Fatalf("expected status=pending, got %s", got)
+	}
+}
+
+func TestProcessor_FailedOutboxWriteLeavesDeliveryUnacked(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{
+		Type:  "no_such_type",
+		Retry: entity.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Second, MaxDelay: time.Second},
+	})
+	queue := &queueStub{}
+	tx := &txStub{}
+	outboxErr := errors.New("outbox unavailable")
+	p := worker.NewProcessor(repo, queue, nil, nil, &outboxStub{queue: queue, tx: tx, err: outboxErr}, tx)
+
+	// транзакция откатилась: доставку не ack'аем — её вернёт reaper
+	if err := p.Process(ctx, id.String()); !errors.Is(err, outboxErr) {
+		t.Fatalf("expected outbox error, got %v", err)
+	}
+	if tx.committed || len(queue.acked) != 0 {
+		t.Fatalf("expected no commit and no ack, committed=%v acked=%v", tx.committed, queue.acked)
+	}
+}
+
+func TestProcessor_PoisonMessageGoesToDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	queue := &queueStub{}
+	p := worker.NewProcessor(repo, queue, nil, nil, nil, nil)
+
+	if err := p.Process(ctx, "not-a-uuid"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if reason, ok := queue.dead["not-a-uuid"]; !ok || reason == "" {
+		t.Fatalf("expected poison id in dead-letter queue with reason, got %#v", queue.dead)
+	}
+
+	// валидный UUID, но job в БД нет
+	missing := uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	if err := p.Process(ctx, missing.String()); err == nil {
+		t.Fatalf("expected not found error")
+	}
+	if _, ok := queue.dead[missing.String()]; !ok {
+		t.Fatalf("expected missing job in dead-letter queue, got %#v", queue.dead)
+	}
+}
+
+// Полный цикл create -> claim -> process -> ack в одном процессе, как в cmd/standalone.
+func TestPool_FullLifecycleInMemory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "echo", Input: json.RawMessage(`{"hello":"world"}`)})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pool := worker.NewPool(queue, worker.NewProcessor(repo, queue, nil, nil, nil, nil), 2, 0, nil)
+	go pool.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j := mustGetJob(t, repo, id)
+		if j.Status == entity.StatusDone {
+			if string(j.Output) != `{"hello":"world"}` || j.Attempts != 1 {
+				t.Fatalf("unexpected result: output=%s attempts=%d", j.Output, j.Attempts)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job not done in time, status=%s", j.Status)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// ACK снимает lease: heartbeat больше невозможен
+	deadline = time.Now().Add(time.Second)
+	for queue.Heartbeat(ctx, id.String()) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job acked after processing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcessor_DispatchesToRegisteredHandler(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "resize", Input: json.RawMessage(`{"w":100}`), Priority: 2})
+
+	var got *entity.Job
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("resize", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		got = job
+		r.Progress(50, "half way")
+		r.Logf("resizing to %s", job.Input)
+		return json.RawMessage(`{"ok":true}`), nil
+	})
+
+	p := worker.NewProcessor(repo, &queueStub{}, handlers, nil, nil, nil)
+	if err := p.Process(ctx, id.String()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if got == nil || got.ID != id || got.Attempts != 1 || got.Status != entity.StatusProcessing || string(got.Input) != `{"w":100}` {
+		t.Fatalf("handler got unexpected job: %+v", got)
+	}
+	j := mustGetJob(t, repo, id)
+	if j.Status != entity.StatusDone || string(j.Output) != `{"ok":true}` {
+		t.Fatalf("expected done with handler output, got status=%s output=%s", j.Status, j.Output)
+	}
+
+	// встроенные типы в этом registry не зарегистрированы
+	echoID, _ := repo.Create(ctx, &entity.Job{Type: "echo", Retry: entity.RetryPolicy{MaxAttempts: 1}})
+	if err := p.Process(ctx, echoID.String()); err == nil {
+		t.Fatalf("expected unknown job type error")
+	}
+}
+
+func TestProcessor_ProgressIsThrottledAndPersisted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "convert"})
+	events, _ := repo.Subscribe(ctx)
+
+	// лог worker'а тоже throttled
+	var stdout bytes.Buffer
+	log.SetOutput(&stdout)
+	defer log.SetOutput(os.Stderr)
+
+	var during *entity.Job
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("convert", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		for pct := 10; pct <= 90; pct += 10 {
+			r.Progress(pct, "frame") // сохраняется только первый, остальные — throttled
+		}
+		r.Stage("uploading") // смена stage — сразу
+		r.Progress(95, "almost")
+		during = mustGetJob(t, repo, job.ID)
+		return json.RawMessage(`{}`), nil
+	})
+
+	if err := worker.NewProcessor(repo, &queueStub{}, handlers, nil, nil, nil).Process(ctx, id.String()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if p := during.Progress; p == nil || p.Percent != 90 || p.Stage != "uploading" || p.Message != "" {
+		t.Fatalf("expected stage saved with last percent, got %+v", p)
+	}
+	// 95 отброшен throttling'ом, но сохранён после выхода handler'а
+	j := mustGetJob(t, repo, id)
+	if p := j.Progress; j.Status != entity.StatusDone || p == nil || p.Percent != 95 || p.Stage != "uploading" || p.Message != "almost" {
+		t.Fatalf("expected final progress 95 uploading, got status=%s %+v", j.Status, p)
+	}
+
+	var progress []int
+	for len(events) > 0 {
+		if ev := <-events; ev.Kind == entity.EventProgress {
+			progress = append(progress, *ev.Progress)
+		}
+	}
+	if len(progress) != 3 || progress[0] != 10 || progress[1] != 90 || progress[2] != 95 {
+		t.Fatalf("expected progress saved 3 times (10, stage at 90, 95), got %v", progress)
+	}
+	if n := strings.Count(stdout.String(), " progress="); n != 1 {
+		t.Fatalf("expected 1 progress line in worker log, got %d:\n%s", n, stdout.String())
+	}
+
+	// новая попытка (повторная доставка processing job) начинается без прогресса прошлой
+	other, _ := repo.Create(ctx, &entity.Job{Type: "convert"})
+	token, _ := repo.StartAttempt(ctx, other, time.Minute)
+	_ = repo.SetProgress(ctx, other, token, entity.JobProgress{Percent: 40})
+	repo.ExpireLease(other)
+	if _, err := repo.StartAttempt(ctx, other, time.Minute); err != nil {
+		t.Fatalf("start attempt: %v", err)
+	}
+	if mustGetJob(t, repo, other).Progress != nil {
+		t.Fatalf("expected progress reset")
+	}
+}
+
+func TestProcessor_StaleAttemptCannotOverwriteJob(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "render"})
+
+	var (
+		calls    int
+		newToken int64
+	)
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("render", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		calls++
+		// пока handler работает, job забирает другой worker (повторная доставка после потери lease)
+		repo.ExpireLease(job.ID)
+		newToken, _ = repo.StartAttempt(ctx, job.ID, time.Minute)
+		r.Logf("from stale attempt")
+		return json.RawMessage(`{"from":"stale"}`), nil
+	})
+	queue := &queueStub{}
+	p := worker.NewProcessor(repo, queue, handlers, nil, nil, nil)
+
+	if err := p.Process(ctx, id.String()); !errors.Is(err, entity.ErrStaleAttempt) {
+		t.Fatalf("expected ErrStaleAttempt for stale worker, got %v", err)
+	}
+	if j := mustGetJob(t, repo, id); j.Status != entity.StatusProcessing || j.Output != nil {
+		t.Fatalf("stale result must be dropped, got status=%s output=%s", j.Status, j.Output)
+	}
+	if logs, _ := repo.ListLogs(ctx, id, entity.JobLogFilter{Limit: 10}); len(logs) != 0 {
+		t.Fatalf("stale attempt must not append logs, got %+v", logs)
+	}
+	// доставкой теперь владеет новая попытка — устаревшая её не ack'ает
+	if len(queue.acked) != 0 {
+		t.Fatalf("stale attempt must not ack, got %v", queue.acked)
+	}
+
+	// новая попытка завершает job своим token'ом
+	if err := repo.SetResultDone(ctx, id, newToken, json.RawMessage(`{"from":"fresh"}`)); err != nil {
+		t.Fatalf("set done: %v", err)
+	}
+
+	// повторная доставка done job пропускается: handler не вызывается, результат не меняется
+	if err := p.Process(ctx, id.String()); err != nil {
+		t.Fatalf("expected duplicate delivery skipped, got %v", err)
+	}
+	repo.ExpireLease(id)
+	if _, err := repo.StartAttempt(ctx, id, time.Minute); !errors.Is(err, entity.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition for done -> processing, got %v", err)
+	}
+	if j := mustGetJob(t, repo, id); calls != 1 || j.Status != entity.StatusDone || string(j.Output) != `{"from":"fresh"}` {
+		t.Fatalf("expected done job untouched, got calls=%d status=%s output=%s", calls, j.Status, j.Output)
+	}
+}
+
+func TestProcessor_DuplicateDeliveryOfLiveAttemptIsNotAcked(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "render"})
+	if _, err := repo.StartAttempt(ctx, id, time.Minute); err != nil {
+		t.Fatalf("start attempt: %v", err)
+	}
+
+	calls := 0
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("render", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		calls++
+		return nil, nil
+	})
+	queue := &queueStub{}
+
+	// lease первой попытки жив: дубль (повторная публикация outbox, reconciler) её не перехватывает
+	if err := worker.NewProcessor(repo, queue, handlers, nil, nil, nil).Process(ctx, id.String()); err != nil {
+		t.Fatalf("expected duplicate skipped, got %v", err)
+	}
+	if j := mustGetJob(t, repo, id); calls != 0 || j.Attempts != 1 || j.FenceToken != 1 {
+		t.Fatalf("expected live attempt untouched, got calls=%d attempts=%d token=%d", calls, j.Attempts, j.FenceToken)
+	}
+	if len(queue.acked) != 0 {
+		t.Fatalf("duplicate must not ack the live attempt's delivery, got %v", queue.acked)
+	}
+}
+
+func TestProcessor_TakenOverAttemptIsCanceled(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "render"})
+
+	var cause error
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("render", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		// lease истёк, job взяла другая попытка; продление lease это замечает
+		repo.ExpireLease(job.ID)
+		if _, err := repo.StartAttempt(context.Background(), job.ID, time.Minute); err != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			cause = context.Cause(ctx)
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return nil, errors.New("attempt was not canceled")
+		}
+	})
+	queue := &queueStub{lease: 30 * time.Millisecond}
+
+	if err := worker.NewProcessor(repo, queue, handlers, nil, nil, nil).Process(ctx, id.String()); !errors.Is(err, entity.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if !errors.Is(cause, entity.ErrLeaseLost) || len(queue.acked) != 0 {
+		t.Fatalf("expected attempt canceled with ErrLeaseLost and no ack, got cause=%v acked=%v", cause, queue.acked)
+	}
+	if j := mustGetJob(t, repo, id); j.Status != entity.StatusProcessing || j.Attempts != 2 {
+		t.Fatalf("expected new attempt untouched, got status=%s attempts=%d", j.Status, j.Attempts)
+	}
+}
+
+func TestProcessor_FollowUpJobsAreCreatedOnCompletion(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("convert_video", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		return json.RawMessage(`{"url":"s3://out.mp4","frames":[10,20]}`), nil
+	})
+	handlers.RegisterFunc("broken", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		return nil, errors.New("boom")
+	})
+	p := worker.NewProcessor(repo, queue, handlers, svc, nil, nil)
+
+	if _, err := svc.CreateJob(ctx, service.CreateJobRequest{
+		Type:      "convert_video",
+		OnSuccess: &entity.JobTemplate{Type: "generate_report", Input: json.RawMessage(`{"video":{"$ref":"output/url"}}`)},
+	}); err == nil {
+		t.Fatalf("expected error for $ref without leading /")
+	}
+
+	parent, err := svc.CreateJob(ctx, service.CreateJobRequest{
+		Type:     "convert_video",
+		Priority: 2,
+		Input:    json.RawMessage(`{"file":"a.mov"}`),
+		OnSuccess: &entity.JobTemplate{
+			Type:      "generate_report",
+			Input:     json.RawMessage(`{"video":{"$ref":"/output/url"},"frame":{"$ref":"/output/frames/1"},"source":{"$ref":"/input/file"},"format":"pdf"}`),
+			OnSuccess: &entity.JobTemplate{Type: "notify"},
+		},
+		OnFailure: &entity.JobTemplate{Type: "notify"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := p.Process(ctx, parent.String()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	children, _ := repo.List(ctx, entity.JobFilter{ParentID: &parent, Limit: 10})
+	if len(children) != 1 {
+		t.Fatalf("expected one follow-up job, got %d", len(children))
+	}
+	child := children[0]
+	if child.Type != "generate_report" || child.Priority != 2 || child.OnSuccess == nil || child.OnSuccess.Type != "notify" {
+		t.Fatalf("unexpected follow-up job: %+v", child)
+	}
+	if want := `{"format":"pdf","frame":20,"source":"a.mov","video":"s3://out.mp4"}`; string(child.Input) != want {
+		t.Fatalf("expected input %s, got %s", want, child.Input)
+	}
+	if pending := queue.Pending(2); !slices.Contains(pending, child.ID.String()) {
+		t.Fatalf("expected follow-up job enqueued, got %v", pending)
+	}
+	// повторный вызов для того же завершения не создаёт вторую job
+	if again, err := svc.CreateFollowUp(ctx, parent); err != nil || again != child.ID {
+		t.Fatalf("expected same follow-up job, got %s err=%v", again, err)
+	}
+
+	// on_failure: после dead, input берёт текст ошибки родителя
+	failing, _ := svc.CreateJob(ctx, service.CreateJobRequest{
+		Type:      "broken",
+		Retry:     entity.RetryPolicy{MaxAttempts: 1},
+		OnSuccess: &entity.JobTemplate{Type: "generate_report"},
+		OnFailure: &entity.JobTemplate{Type: "notify", Input: json.RawMessage(`{"job":{"$ref":"/id"},"reason":{"$ref":"/error"}}`)},
+	})
+	_ = p.Process(ctx, failing.String())
+
+	children, _ = repo.List(ctx, entity.JobFilter{ParentID: &failing, Limit: 10})
+	if len(children) != 1 || children[0].Type != "notify" {
+		t.Fatalf("expected notify follow-up after dead, got %+v", children)
+	}
+	if want := `{"job":"` + failing.String() + `","reason":"boom"}`; string(children[0].Input) != want {
+		t.Fatalf("expected input %s, got %s", want, children[0].Input)
+	}
+}
+
+// rollbackTx — транзакция без БД: записи txRepo внутри неё применяются только при commit'е.
+type rollbackTx struct {
+	pending []func() error
+	commits int
+}
+
+func (tx *rollbackTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx.pending = nil
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		tx.pending = nil
+		return err
+	}
+	for _, apply := range tx.pending {
+		if err := apply(); err != nil {
+			return err
+		}
+	}
+	tx.commits++
+	return nil
+}
+
+type txRepo struct {
+	*memory.JobRepository
+	tx *rollbackTx
+}
+
+func (r *txRepo) SetResultDone(ctx context.Context, id uuid.UUID, token int64, output json.RawMessage) error {
+	if ctx.Value(txKey{}) == nil {
+		return r.JobRepository.SetResultDone(ctx, id, token, output)
+	}
+	r.tx.pending = append(r.tx.pending, func() error {
+		return r.JobRepository.SetResultDone(context.Background(), id, token, output)
+	})
+	return nil
+}
+
+type followUpStub struct {
+	inTx []bool
+	err  error
+}
+
+func (s *followUpStub) CreateFollowUp(ctx context.Context, parentID uuid.UUID) (uuid.UUID, error) {
+	s.inTx = append(s.inTx, ctx.Value(txKey{}) != nil)
+	if s.err != nil {
+		return uuid.Nil, s.err
+	}
+	return uuid.New(), nil
+}
+
+func parentHandlers() *worker.Registry {
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("parent", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		return json.RawMessage(`{"ok":true}`), nil
+	})
+	return handlers
+}
+
+func TestProcessor_FollowUpCreatedInResultTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	tx := &rollbackTx{}
+	repo := &txRepo{JobRepository: memory.NewJobRepository(), tx: tx}
+	id, _ := repo.Create(ctx, &entity.Job{Type: "parent", OnSuccess: &entity.JobTemplate{Type: "notify"}})
+	queue := &queueStub{}
+	followUps := &followUpStub{}
+
+	if err := worker.NewProcessor(repo, queue, parentHandlers(), followUps, queue, tx).Process(ctx, id.String()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(followUps.inTx) != 1 || !followUps.inTx[0] || tx.commits != 1 {
+		t.Fatalf("expected follow-up inside the result transaction, got in_tx=%v commits=%d", followUps.inTx, tx.commits)
+	}
+	if got := mustGetJob(t, repo.JobRepository, id).Status; got != entity.StatusDone || len(queue.acked) != 1 {
+		t.Fatalf("expected done and acked, got status=%s acked=%v", got, queue.acked)
+	}
+}
+
+func TestProcessor_FailedFollowUpDoesNotLoseResult(t *testing.T) {
+	ctx := context.Background()
+
+	tx := &rollbackTx{}
+	repo := &txRepo{JobRepository: memory.NewJobRepository(), tx: tx}
+	id, _ := repo.Create(ctx, &entity.Job{Type: "parent", OnSuccess: &entity.JobTemplate{Type: "notify"}})
+	queue := &queueStub{}
+	followUps := &followUpStub{err: errors.New("follow-up input: no key")}
+
+	// транзакция откатилась вместе с результатом — он пишется повторно, уже без follow-up
+	if err := worker.NewProcessor(repo, queue, parentHandlers(), followUps, queue, tx).Process(ctx, id.String()); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if tx.commits != 0 || len(followUps.inTx) != 1 {
+		t.Fatalf("expected rolled back follow-up tx, got commits=%d calls=%d", tx.commits, len(followUps.inTx))
+	}
+	if got := mustGetJob(t, repo.JobRepository, id).Status; got != entity.StatusDone || len(queue.acked) != 1 {
+		t.Fatalf("expected done and acked, got status=%s acked=%v", got, queue.acked)
+	}
+}
+
+func TestProcessor_HandlerLogsArePersistedPerAttempt(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "import", Retry: entity.RetryPolicy{MaxAttempts: 2}})
+
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("import", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		r.Logf("rows=%d", 10*job.Attempts)
+		if job.Attempts == 1 {
+			r.Warnf("upstream slow")
+			return nil, errors.New("upstream 503")
+		}
+		return json.RawMessage(`{}`), nil
+	})
+	p := worker.NewProcessor(repo, &queueStub{}, handlers, nil, nil, nil)
+
+	for range 2 {
+		_ = p.Process(ctx, id.String())
+	}
+	if j := mustGetJob(t, repo, id); j.Status != entity.StatusDone {
+		t.Fatalf("expected done on 2nd attempt, got %s", j.Status)
+	}
+
+	logs, err := repo.ListLogs(ctx, id, entity.JobLogFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	type line struct {
+		attempt int
+		level   entity.LogLevel
+		msg     string
+	}
+	want := []line{
+		{1, entity.LogInfo, "rows=10"},
+		{1, entity.LogWarn, "upstream slow"},
+		{1, entity.LogError, "attempt failed: upstream 503"}, // ошибку попытки пишет Processor
+		{2, entity.LogInfo, "rows=20"},
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), logs)
+	}
+	for i, l := range logs {
+		if got := (line{l.Attempt, l.Level, l.Message}); got != want[i] || l.At.IsZero() {
+			t.Fatalf("line %d: got %+v want %+v", i, l, want[i])
+		}
+	}
+
+	errs, _ := repo.ListLogs(ctx, id, entity.JobLogFilter{Levels: []entity.LogLevel{entity.LogError}, Limit: 10})
+	if len(errs) != 1 || errs[0].Attempt != 1 {
+		t.Fatalf("expected 1 error line of attempt 1, got %+v", errs)
+	}
+}
+
+func TestProcessor_TimeoutIsDistinctErrorClass(t *testing.T) {
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	handlers := worker.NewRegistry()
+	// слушает ctx
+	handlers.RegisterFunc("slow", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	// "завис" и ctx не слушает
+	handlers.RegisterFunc("hung", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		<-release
+		return json.RawMessage(`{}`), nil
+	})
+
+	repo := memory.NewJobRepository()
+	queue := &queueStub{}
+	p := worker.NewProcessor(repo, queue, handlers, nil, nil, nil)
+
+	// timeout => retry с error_class=timeout
+	slow, _ := repo.Create(ctx, &entity.Job{
+		Type:    "slow",
+		Timeout: 50 * time.Millisecond,
+		Retry:   entity.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second},
+	})
+	if err := p.Process(ctx, slow.String()); !errors.Is(err, worker.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if j := mustGetJob(t, repo, slow); j.Status != entity.StatusPending || j.ErrorClass != entity.ErrorClassTimeout {
+		t.Fatalf("expected pending with error_class=timeout, got status=%s class=%q", j.Status, j.ErrorClass)
+	}
+
+	// TimeoutMaxAttempts=1: timeout не повторяется, хотя max_attempts=3; worker не ждёт зависший handler
+	hung, _ := repo.Create(ctx, &entity.Job{
+		Type:    "hung",
+		Timeout: 50 * time.Millisecond,
+		Retry:   entity.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, TimeoutMaxAttempts: 1},
+	})
+	start := time.Now()
+	if err := p.Process(ctx, hung.String()); !errors.Is(err, worker.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("expected worker released after timeout, took %s", took)
+	}
+	if j := mustGetJob(t, repo, hung); j.Status != entity.StatusDead || j.ErrorClass != entity.ErrorClassTimeout {
+		t.Fatalf("expected dead with error_class=timeout, got status=%s class=%q", j.Status, j.ErrorClass)
+	}
+	if len(queue.calls) != 1 {
+		t.Fatalf("expected only the 'slow' retry scheduled, got %#v", queue.calls)
+	}
+}
+
+func TestProcessor_AbandonedHandlerWritesNothing(t *testing.T) {
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	handlers := worker.NewRegistry()
+	// ctx не слушает и пишет в reporter уже после timeout
+	handlers.RegisterFunc("hung", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		defer close(finished)
+		<-release
+		r.Stage("late")
+		r.Progress(90, "late")
+		r.Logf("late line")
+		return json.RawMessage(`{}`), nil
+	})
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "hung", Timeout: 20 * time.Millisecond})
+	p := worker.NewProcessor(repo, &queueStub{}, handlers, nil, nil, nil)
+
+	if err := p.Process(ctx, id.String()); !errors.Is(err, worker.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	close(release)
+	<-finished
+
+	logs, _ := repo.ListLogs(ctx, id, entity.JobLogFilter{Limit: 10})
+	if len(logs) != 1 || !strings.HasPrefix(logs[0].Message, "attempt failed:") {
+		t.Fatalf("expected only the attempt error line, got %+v", logs)
+	}
+	if j := mustGetJob(t, repo, id); j.Progress != nil {
+		t.Fatalf("expected no progress from abandoned handler, got %+v", j.Progress)
+	}
+}
+
+func TestRegistry_RegisterPanicsOnDuplicate(t *testing.T) {
+	r := worker.NewRegistry()
+	worker.RegisterBuiltins(r)
+
+	if types := r.Types(); len(types) != 3 || types[0] != "convert_video" || types[2] != "generate_report" {
+		t.Fatalf("unexpected builtin types: %v", types)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic on duplicate registration")
+		}
+	}()
+	r.RegisterFunc("echo", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		return nil, nil
+	})
+}
+
+// Отмена через API доходит до handler'а, который выполняет job в пуле.
+func TestPool_CancelReachesRunningHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("long", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- context.Cause(ctx)
+		return nil, ctx.Err()
+	})
+
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "long"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pool := worker.NewPool(queue, worker.NewProcessor(repo, queue, handlers, nil, nil, nil), 1, 0, queue)
+	go pool.Run(ctx)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handler not started")
+	}
+
+	if _, err := service.NewCancelService(repo, queue).Cancel(ctx, id); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	select {
+	case cause := <-stopped:
+		if !errors.Is(cause, worker.ErrCanceled) {
+			t.Fatalf("expected ErrCanceled cause, got %v", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handler ctx not cancelled")
+	}
+
+	// ACK: lease снят; статус остаётся canceled, retry не планируется
+	deadline := time.Now().Add(time.Second)
+	for queue.Heartbeat(ctx, id.String()) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected canceled job acked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if j := mustGetJob(t, repo, id); j.Status != entity.StatusCanceled || j.RunAt != nil {
+		t.Fatalf("expected canceled without retry, got status=%s run_at=%v", j.Status, j.RunAt)
+	}
+}
+
+func TestProcessor_SkipsJobCanceledInQueue(t *testing.T) {
+	ctx := context.Background()
+
+	repo := memory.NewJobRepository()
+	id, _ := repo.Create(ctx, &entity.Job{Type: "echo"})
+	if _, err := repo.Cancel(ctx, id); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	queue := &queueStub{}
+	if err := worker.NewProcessor(repo, queue, nil, nil, nil, nil).Process(ctx, id.String()); err != nil {
+		t.Fatalf("expected canceled job skipped without error, got %v", err)
+	}
+	if j := mustGetJob(t, repo, id); j.Status != entity.StatusCanceled || j.Attempts != 0 {
+		t.Fatalf("expected canceled job untouched, got status=%s attempts=%d", j.Status, j.Attempts)
+	}
+	if len(queue.dead) != 0 {
+		t.Fatalf("expected no dead letters, got %v", queue.dead)
+	}
+}
+
+func TestPool_ShutdownReleasesRunningAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("long", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond) // handler заканчивает не сразу — Run должен его дождаться
+		return nil, ctx.Err()
+	})
+
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "long", Priority: 2})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pool := worker.NewPool(queue, worker.NewProcessor(repo, queue, handlers, nil, nil, nil), 1, 0, queue)
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handler not started")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pool did not stop")
+	}
+
+	// Run вернулся — попытка уже возвращена: pending, не засчитана, снова в очереди
+	j := mustGetJob(t, repo, id)
+	if j.Status != entity.StatusPending || j.Attempts != 0 {
+		t.Fatalf("expected released attempt (pending, attempts=0), got status=%s attempts=%d", j.Status, j.Attempts)
+	}
+	if _, err := queue.PromoteDue(context.Background(), 10); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if pending := queue.Pending(2); len(pending) != 1 || pending[0] != id.String() {
+		t.Fatalf("expected job back in high lane, got %v", pending)
+	}
+}
+
+func TestPool_LeaseLostCancelsAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	causes := make(chan error, 1)
+	handlers := worker.NewRegistry()
+	handlers.RegisterFunc("long", func(ctx context.Context, job *entity.Job, r worker.Reporter) (json.RawMessage, error) {
+		close(started)
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil, ctx.Err()
+	})
+
+	repo := memory.NewJobRepository()
+	queue := memory.NewQueue(time.Minute)
+	svc := service.NewJobService(repo, queue, nil, service.RetryPolicies{}, service.TimeoutPolicies{})
+	id, err := svc.CreateJob(ctx, service.CreateJobRequest{Type: "long"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pool := worker.NewPool(queue, worker.NewProcessor(repo, queue, handlers, nil, nil, nil), 1, 20*time.Millisecond, nil)
+	go pool.Run(ctx)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handler not started")
+	}
+	// lease пропал (как после reaper'а): следующий heartbeat получает ErrLeaseLost
+	_ = queue.Ack(ctx, id.String())
+
+	select {
+	case cause := <-causes:
+		if !errors.Is(cause, entity.ErrLeaseLost) {
+			t.Fatalf("expected ErrLeaseLost cause, got %v", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("attempt not canceled after lease lost")
+	}
+
+	// результат прерванной попытки не пишется: job ждёт следующей доставки в processing
+	time.Sleep(50 * time.Millisecond)
+	if j := mustGetJob(t, repo, id); j.Status != entity.StatusProcessing || j.Attempts != 1 || j.Error != nil {
+		t.Fatalf("expected job untouched, got status=%s attempts=%d error=%v", j.Status, j.Attempts, j.Error)
+	}
+}
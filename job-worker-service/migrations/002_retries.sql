@@ -0,0 +1,11 @@
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS max_attempts INT NOT NULL DEFAULT 3;
+
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS backoff_base_ms BIGINT NOT NULL DEFAULT 1000;
+
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS backoff_max_ms BIGINT NOT NULL DEFAULT 60000;
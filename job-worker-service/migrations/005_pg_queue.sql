@@ -0,0 +1,21 @@
+-- Postgres-only queue backend (QUEUE_BACKEND=postgres): очередь = строки jobs со status='pending'.
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS lease_until timestamptz;
+
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS lease_token uuid;
+
+-- dispatch: WHERE status='pending' ORDER BY priority DESC, created_at (FIFO внутри priority)
+DROP INDEX IF EXISTS idx_jobs_priority_created_at;
+CREATE INDEX IF NOT EXISTS idx_jobs_priority_created_at ON jobs(priority DESC, created_at) WHERE status = 'pending';
+
+-- reaper: истёкшие lease
+CREATE INDEX IF NOT EXISTS idx_jobs_lease_until ON jobs(lease_until) WHERE lease_until IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    job_id text PRIMARY KEY,
+    reason text NOT NULL,
+    dead_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_dead_at ON dead_letters(dead_at DESC);
@@ -0,0 +1,14 @@
+-- Transactional outbox: строка пишется в той же транзакции, что и insert в jobs;
+-- relay публикует её в Redis и удаляет.
+CREATE TABLE IF NOT EXISTS job_outbox (
+    id bigserial PRIMARY KEY,
+    job_id uuid NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    priority int NOT NULL,
+    run_at timestamptz,
+    attempts int NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_outbox_next_attempt_at ON job_outbox(next_attempt_at, id);
@@ -0,0 +1,14 @@
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS timeout_ms BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS timeout_max_attempts INT NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS error_class TEXT;
+
+ALTER TABLE jobs
+    DROP CONSTRAINT IF EXISTS jobs_error_class_check;
+
+ALTER TABLE jobs
+    ADD CONSTRAINT jobs_error_class_check CHECK (error_class IN ('error','timeout'));
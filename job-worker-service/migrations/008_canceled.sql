@@ -0,0 +1,5 @@
+ALTER TABLE jobs
+    DROP CONSTRAINT IF EXISTS jobs_status_check;
+
+ALTER TABLE jobs
+    ADD CONSTRAINT jobs_status_check CHECK (status IN ('pending','processing','done','error','dead','canceled'));
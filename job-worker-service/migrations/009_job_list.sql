@@ -0,0 +1,10 @@
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_jobs_tags ON jobs USING GIN (tags);
+
+-- keyset pagination GET /jobs: ORDER BY created_at, id
+CREATE INDEX IF NOT EXISTS idx_jobs_created_at_id ON jobs (created_at DESC, id DESC);
+
+-- "что упало за последний час": status + created_at range
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs (status, created_at DESC, id DESC);
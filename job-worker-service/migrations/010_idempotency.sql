@@ -0,0 +1,9 @@
+-- Idempotency-Key на POST /jobs: повтор запроса с тем же ключом (в рамках client_id) возвращает ту же job.
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS client_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS idempotency_key TEXT,
+    ADD COLUMN IF NOT EXISTS request_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_jobs_idempotency_key
+    ON jobs (client_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Unique jobs: пока есть pending/processing job того же type с тем же unique_key
+-- (hash канонизированного input или ключ клиента), новая не создаётся.
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS unique_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_jobs_unique_key
+    ON jobs (type, unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'processing');
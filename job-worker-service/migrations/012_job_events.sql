@@ -0,0 +1,24 @@
+-- События job для SSE (GET /jobs/{id}/events, GET /events): каждая смена статуса — NOTIFY job_events.
+-- Payload маленький (лимит NOTIFY — 8000 байт): результат API дочитывает из jobs.
+CREATE OR REPLACE FUNCTION notify_job_event()
+RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status THEN
+        PERFORM pg_notify('job_events', json_build_object(
+            'kind', 'status',
+            'job_id', NEW.id,
+            'type', NEW.type,
+            'status', NEW.status,
+            'attempts', NEW.attempts,
+            'at', NEW.updated_at
+        )::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS jobs_notify_event ON jobs;
+CREATE TRIGGER jobs_notify_event
+    AFTER INSERT OR UPDATE OF status ON jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_job_event();
@@ -0,0 +1,71 @@
+-- Webhook о завершении job: POST на callback_url job (или URL клиента по умолчанию) с подписью HMAC-SHA256.
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS callback_url TEXT;
+
+-- Настройки клиента (X-Client-ID): URL для job без callback_url и секрет подписи (NULL — общий WEBHOOK_SECRET).
+CREATE TABLE IF NOT EXISTS webhook_settings (
+    client_id  TEXT PRIMARY KEY,
+    url        TEXT,
+    secret     TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Доставка: одна на каждый переход job в done / error / dead; dispatcher (cmd/worker) шлёт её с ретраями.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    job_id          UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    job_status      TEXT NOT NULL,
+    url             TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at, id)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_job
+    ON webhook_deliveries (job_id, id);
+
+-- Попытки доставки (GET /jobs/{id}/webhooks).
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt     INT NOT NULL,
+    status_code INT, -- NULL: ответа нет (сетевая ошибка, timeout)
+    error       TEXT,
+    duration_ms BIGINT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery
+    ON webhook_attempts (delivery_id, attempt);
+
+-- Доставка создаётся в той же транзакции, что и финальный статус (SetResultDone / SetResultError / SetDead):
+-- commit без webhook'а или webhook без commit'а невозможны.
+CREATE OR REPLACE FUNCTION enqueue_job_webhook()
+RETURNS trigger AS $$
+DECLARE
+    target TEXT := NEW.callback_url;
+BEGIN
+    IF NEW.status IN ('done', 'error', 'dead') AND NEW.status IS DISTINCT FROM OLD.status THEN
+        IF target IS NULL AND NEW.client_id <> '' THEN
+            SELECT url INTO target FROM webhook_settings WHERE client_id = NEW.client_id;
+        END IF;
+        IF target IS NOT NULL AND target <> '' THEN
+            INSERT INTO webhook_deliveries (job_id, job_status, url) VALUES (NEW.id, NEW.status, target);
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS jobs_enqueue_webhook ON jobs;
+CREATE TRIGGER jobs_enqueue_webhook
+    AFTER UPDATE OF status ON jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION enqueue_job_webhook();
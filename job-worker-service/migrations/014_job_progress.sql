@@ -0,0 +1,4 @@
+-- Прогресс handler'а (Reporter.Progress / Reporter.Stage), последний сохранённый: {"percent","stage","message","updated_at"}.
+-- Сбрасывается в NULL с началом новой попытки.
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS progress JSONB;
@@ -0,0 +1,13 @@
+-- Лог выполнения job (GET /jobs/{id}/logs): строки Reporter.Logf / Warnf / Errorf handler'а и ошибки попыток.
+CREATE TABLE IF NOT EXISTS job_logs (
+    id         BIGSERIAL PRIMARY KEY,
+    job_id     UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    attempt    INT NOT NULL,
+    level      TEXT NOT NULL CHECK (level IN ('info', 'warn', 'error')),
+    message    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- keyset pagination по job: ORDER BY id
+CREATE INDEX IF NOT EXISTS idx_job_logs_job
+    ON job_logs (job_id, id);
@@ -0,0 +1,16 @@
+-- История job (GET /jobs/{id}/history): каждая смена статуса и каждая новая попытка — с тем, кто их сделал.
+-- Пишут методы postgresql.JobRepository (и Queue.RequeueStale) той же командой, что меняет jobs.
+-- Не путать с каналом NOTIFY job_events (migrations/012_job_events.sql): тот — живые события для SSE, эта таблица — аудит.
+CREATE TABLE IF NOT EXISTS job_events (
+    id          BIGSERIAL PRIMARY KEY,
+    job_id      UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    from_status TEXT, -- NULL: job создана
+    to_status   TEXT NOT NULL,
+    attempt     INT NOT NULL,
+    actor       TEXT, -- api:<client_id> | worker:<host>-<pid> | reaper | reconciler; NULL — неизвестно
+    detail      TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_events_job
+    ON job_events (job_id, id);
@@ -0,0 +1,5 @@
+-- Fencing token попытки: StartAttempt увеличивает его и отдаёт worker'у, запись результата
+-- (SetRetry / SetDead / SetResultDone / SetResultError / SetProgress) проходит только с текущим token'ом.
+-- В отличие от attempts, не сбрасывается при повторе job (ResetToPending).
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS fence_token BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Цепочки job: шаблоны follow-up job (entity.JobTemplate) для done и для error / dead.
+-- Дочернюю job создаёт worker через JobService.CreateFollowUp; parent_id связывает её с родителем (GET /jobs?parent_id=).
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS on_success JSONB,
+    ADD COLUMN IF NOT EXISTS on_failure JSONB,
+    ADD COLUMN IF NOT EXISTS parent_id  UUID REFERENCES jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_parent_id
+    ON jobs (parent_id)
+    WHERE parent_id IS NOT NULL;